@@ -2,18 +2,27 @@ package app
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"net"
 	"net/http"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
+	"github.com/luizaranda/go-core/pkg/health"
 	"github.com/luizaranda/go-core/pkg/internal/infra"
 	"github.com/luizaranda/go-core/pkg/log"
 	"github.com/luizaranda/go-core/pkg/otel"
 	"github.com/luizaranda/go-core/pkg/telemetry"
 	"github.com/luizaranda/go-core/pkg/web"
+	"google.golang.org/grpc"
 )
 
 const (
@@ -22,6 +31,8 @@ const (
 
 	_otelAgentEnabledEnv  = "OTEL_AGENT_ENABLED"
 	_otelAgentDisabledEnv = "OTEL_AGENT_DISABLED"
+
+	_telemetrySampleRateEnv = "TELEMETRY_SAMPLE_RATE"
 )
 
 // Application is a container struct that contains all required base components for building web applications.
@@ -39,9 +50,28 @@ type Application struct {
 	cancel  context.CancelFunc
 
 	// Fields that contains information for running the application.
-	network        string
-	address        string
-	serverTimeouts web.Timeouts
+	network         string
+	address         string
+	serverTimeouts  web.Timeouts
+	serverOptions   []web.ServerOption
+	baseContextFunc func(net.Listener) context.Context
+	contextValues   []ContextValue
+	listeners       []net.Listener
+	unixSockets     []string
+
+	health      *health.Registry
+	maintenance *maintenanceSwitch
+	scheduler   scheduler
+	consumers   consumers
+	lifecycle   lifecycle
+	registry    registry
+	grpcServer  *grpc.Server
+	inflight    atomic.Int64
+	reloaders   *reloaders
+	signals     *signals
+	warmup      *warmup
+	migrations  *migrations
+	gauges      infra.Gauges
 
 	otelShutdownFunc otel.ShutdownFunc
 }
@@ -53,6 +83,41 @@ type Scope struct {
 	Metadata    string
 }
 
+// String returns the scope in its {environment}-{app role}-{metadata} format,
+// omitting the empty parts.
+func (s Scope) String() string {
+	parts := []string{s.Environment}
+	if s.Role != "" {
+		parts = append(parts, s.Role)
+	}
+	if s.Metadata != "" {
+		parts = append(parts, s.Metadata)
+	}
+	return strings.Join(parts, "-")
+}
+
+// IsProduction reports whether the application runs in the production
+// environment.
+func (s Scope) IsProduction() bool {
+	return s.Environment == "production"
+}
+
+// IsTest reports whether the application runs in the test environment.
+func (s Scope) IsTest() bool {
+	return s.Environment == "test"
+}
+
+// IsLocal reports whether the application runs locally, which is the case when
+// SCOPE is not set.
+func (s Scope) IsLocal() bool {
+	return s.Environment == _defaultScopeEnvironment
+}
+
+// Tag returns the scope as a telemetry tag, such as "scope:production-indexer".
+func (s Scope) Tag() string {
+	return "scope:" + s.String()
+}
+
 // NewWebApplication instantiates an Application using the given configuration.
 // Sane defaults are provided.
 func NewWebApplication(opts ...AppOptFunc) (*Application, error) {
@@ -61,10 +126,6 @@ func NewWebApplication(opts ...AppOptFunc) (*Application, error) {
 		opt(&config)
 	}
 
-	if config.LogLevel == 0 {
-		config.LogLevel = log.InfoLevel
-	}
-
 	if config.ServerTimeouts == (web.Timeouts{}) {
 		config.ServerTimeouts = web.Timeouts{
 			IdleTimeout:     75 * time.Second,
@@ -72,47 +133,42 @@ func NewWebApplication(opts ...AppOptFunc) (*Application, error) {
 		}
 	}
 
-	// We must start OTel before any other dependency since
-	// there are components that require the global provider to be set.
-	otelShutdownFunc, err := startOTel()
-	if err != nil {
-		return nil, err
+	if config.DrainDelay > 0 {
+		config.ServerTimeouts.DrainDelay = config.DrainDelay
 	}
 
-	scopeFromEnv := getScopeFromEnv()
-
-	scope, err := infra.ParseScope(scopeFromEnv)
+	rt, err := bootstrap(config)
 	if err != nil {
 		return nil, err
 	}
 
-	tracer, err := newTracer(scope)
-	if err != nil {
-		return nil, err
-	}
-
-	logger, level := newLogger(config)
-
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = _defaultWebApplicationPort
-	}
-
 	cfg := infra.Config{
 		ErrorHandler:       config.ErrorHandler,
 		ErrorEncoder:       config.ErrorEncoder,
 		NotFoundHandler:    config.NotFoundHandler,
-		Logger:             logger,
-		Tracer:             tracer,
+		Logger:             rt.logger,
+		Tracer:             rt.tracer,
 		EnableProfiling:    config.EnableProfiling,
 		DisableCompression: config.DisableCompression,
 		ServerTimeouts:     config.ServerTimeouts,
+		RouterOptions:      config.RouterOptions,
+	}
+
+	healthRegistry := health.NewRegistry()
+
+	var warmupGate *warmup
+	if config.Warmup != nil {
+		warmupGate = &warmup{fn: config.Warmup, timeout: config.WarmupTimeout}
+		healthRegistry.Register("warmup", warmupGate.check, health.WithCacheTTL(0))
 	}
 
 	cfg.HealthCheckRegisterer = func(r *web.Router) {
+		// Kept for the probes of existing deployments, it's the same as /live.
 		r.Get("/ping", func(w http.ResponseWriter, r *http.Request) error {
 			return web.EncodeJSON(w, "pong", 200)
 		})
+		r.Get("/live", wrapF(healthRegistry.LiveHandler()))
+		r.Get("/ready", wrapF(healthRegistry.ReadyHandler()))
 	}
 
 	app, err := infra.NewWebApplication(cfg)
@@ -120,45 +176,267 @@ func NewWebApplication(opts ...AppOptFunc) (*Application, error) {
 		return nil, err
 	}
 
-	// Register logger handler for changing log level dynamically
-	app.Router.Any("/debug/log/level", wrapF(level.ServeHTTP))
+	// Register logger handler for changing log level dynamically, of the
+	// whole application or of the named loggers given by the logger parameter.
+	levels := &levelHandler{level: rt.level}
+	app.Router.Any("/debug/log/level", wrapF(levels.ServeHTTP))
+
+	// Register reload handler for re-evaluating reloadable components.
+	reloaders := &reloaders{tracer: rt.tracer, logger: rt.logger}
+	app.Router.Any("/debug/reload", wrapF(reloaders.ServeHTTP))
+
+	// The maintenance switch applies to the routes registered from now on, so
+	// the health checks and the /debug endpoints are never rejected.
+	maintenance := newMaintenanceSwitch(rt.logger)
+	app.Router.Any("/debug/maintenance", wrapF(maintenance.ServeHTTP))
+	app.Router.Use(maintenance.middleware)
+
+	var serverOptions []web.ServerOption
+	if config.EnableH2C {
+		serverOptions = append(serverOptions, web.WithH2C())
+	}
+
+	tlsConfig, err := newTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		serverOptions = append(serverOptions, web.WithTLSConfig(tlsConfig))
+	}
+
+	var grpcServer *grpc.Server
+	if config.GRPCRegister != nil {
+		grpcServer = infra.NewGRPCServer(rt.tracer, rt.logger, config.GRPCOptions...)
+		config.GRPCRegister(grpcServer)
+
+		// gRPC requires HTTP/2, which is only enabled by default with TLS.
+		if tlsConfig == nil && !config.EnableH2C {
+			serverOptions = append(serverOptions, web.WithH2C())
+		}
+	}
+
+	// Components are reloaded on SIGHUP, logging their own failures.
+	reloadOnHUP := SignalHandler{Signal: syscall.SIGHUP, Handle: func(ctx context.Context) error {
+		_ = reloaders.reload(ctx)
+		return nil
+	}}
+	sigs := &signals{
+		shutdown: config.ShutdownSignals,
+		handlers: append([]SignalHandler{reloadOnHUP}, config.SignalHandlers...),
+		logger:   rt.logger,
+	}
 
 	// Context that will be canceled when calling Shutdown.
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &Application{
-		Scope:  Scope(scope),
+		Scope:  Scope(rt.scope),
 		Router: app.Router,
 		Tracer: app.Tracer,
 		Logger: app.Logger,
 
 		network:          "tcp",
-		address:          ":" + port,
+		address:          listenAddress(config),
 		running:          make(chan struct{}),
 		ctx:              ctx,
 		cancel:           cancel,
 		serverTimeouts:   cfg.ServerTimeouts,
-		otelShutdownFunc: otelShutdownFunc,
+		serverOptions:    serverOptions,
+		baseContextFunc:  config.BaseContext,
+		contextValues:    config.ContextValues,
+		listeners:        config.Listeners,
+		unixSockets:      config.UnixSockets,
+		health:           healthRegistry,
+		maintenance:      maintenance,
+		lifecycle:        lifecycle{timeout: config.HookTimeout},
+		grpcServer:       grpcServer,
+		reloaders:        reloaders,
+		signals:          sigs,
+		warmup:           warmupGate,
+		migrations:       newMigrations(config.Migrations, config.MigrationOptions),
+		otelShutdownFunc: rt.otelShutdownFunc,
 	}, nil
 }
 
-// Run starts your Application using a predefined network and address.
-// It blocks until SIGTERM o SIGINT is received by the running process or Shutdown is called, whichever happens first.
-func (a *Application) Run() error {
+// Run starts your Application using a predefined network and address, along with
+// the listeners and Unix sockets given with WithListener and WithUnixSocket,
+// running the migrations given with WithMigrations and calling the OnStart
+// hooks before serving requests, and the OnStop ones after.
+// It blocks until SIGTERM or SIGINT, unless set with WithShutdownSignals, is received by the running process or Shutdown
+// is called, whichever happens first.
+func (a *Application) Run() (err error) {
 	defer func() { _ = a.otelShutdownFunc() }()
+	defer syncLogger(a.Logger)
+	// The tracer is closed last, so that the metrics of the shutdown, such as
+	// the ones of the closers, are sent.
+	defer func() {
+		err = errors.Join(err, a.lifecycle.stop(), a.lifecycle.close(a.Tracer), a.Tracer.Close())
+	}()
+
+	if err := a.migrations.run(a.ctx, a.Tracer, a.Logger); err != nil {
+		return err
+	}
 
-	ln, err := net.Listen(a.network, a.address)
+	if err := a.lifecycle.start(a.ctx); err != nil {
+		return err
+	}
+
+	lns, err := a.listen()
 	if err != nil {
 		return err
 	}
 
 	a.mutex.Lock()
 	// Once assigned, the application is ready to enqueue SYN messages.
-	a.port = ln.Addr().(*net.TCPAddr).Port
+	a.port = lns[0].Addr().(*net.TCPAddr).Port
 	a.mutex.Unlock()
 
+	// Gauges are published until the application stops, its drain included.
+	pollCtx, stopPolling := context.WithCancel(context.Background())
+	defer stopPolling()
+	go infra.PollExportedVars(pollCtx, a.Tracer, &a.gauges)
+
+	jobsCtx, stopJobs := context.WithCancel(a.ctx)
+	waitJobs := a.scheduler.start(jobsCtx, a.Tracer, a.Logger)
+	waitConsumers := a.consumers.start(jobsCtx, a.Logger)
+	defer func() {
+		stopJobs()
+		waitJobs()
+		waitConsumers()
+	}()
+
+	// The application drains as soon as it's asked to stop, while the server
+	// keeps serving requests for the drain delay.
+	stopCtx, stopNotify := a.signals.notifyShutdown(a.ctx)
+	defer stopNotify()
+
+	done := make(chan struct{})
+	defer close(done)
+	go a.drain(stopCtx, done)
+	a.signals.listen(done)
+
+	// Serving stops when the application is asked to stop or its warmup fails.
+	serveCtx, stopServing := context.WithCancel(stopCtx)
+	defer stopServing()
+
+	var warmupErr error
+	if a.warmup != nil {
+		warmupDone := make(chan struct{})
+		defer func() {
+			stopServing()
+			<-warmupDone
+
+			// Warmups interrupted by the application stopping didn't fail.
+			if !errors.Is(warmupErr, context.Canceled) {
+				err = errors.Join(err, warmupErr)
+			}
+		}()
+
+		go func() {
+			defer close(warmupDone)
+			if warmupErr = a.warmup.run(serveCtx, a.Logger); warmupErr != nil {
+				stopServing()
+			}
+		}()
+	}
+
+	// Requests inherit the application and its context values from the base
+	// context of the server.
+	opts := append(slices.Clone(a.serverOptions), web.WithBaseContext(a.baseContext))
+
 	close(a.running)
-	return infra.RunListener(a.ctx, ln, a.Tracer, a.Logger, a.serverTimeouts, a.Router)
+	return infra.RunListeners(serveCtx, lns, a.Logger, a.serverTimeouts, a.handler(), opts...)
+}
+
+// drain makes the readiness endpoint fail once ctx is done, and records the
+// requests in flight until the application stops, signaled by done.
+func (a *Application) drain(ctx context.Context, done chan struct{}) {
+	select {
+	case <-ctx.Done():
+	case <-done:
+		return
+	}
+
+	a.health.Drain()
+	a.Logger.Info("draining", log.Duration("delay", a.serverTimeouts.DrainDelay))
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		a.Tracer.Gauge("toolkit.http.server.inflight_requests", float64(a.inflight.Load()), nil)
+
+		select {
+		case <-ticker.C:
+		case <-done:
+			return
+		}
+	}
+}
+
+// handler returns the handler serving the requests of the application, which
+// counts the requests in flight and dispatches gRPC ones to the gRPC server, if
+// any.
+func (a *Application) handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.inflight.Add(1)
+		defer a.inflight.Add(-1)
+
+		if a.grpcServer != nil && r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			a.grpcServer.ServeHTTP(w, r)
+			return
+		}
+		a.Router.ServeHTTP(w, r)
+	})
+}
+
+// listen opens the listeners of the application, the first one being the one of
+// its network and address.
+func (a *Application) listen() (lns []net.Listener, err error) {
+	defer func() {
+		if err != nil {
+			for _, ln := range lns {
+				_ = ln.Close()
+			}
+		}
+	}()
+
+	ln, err := net.Listen(a.network, a.address)
+	if err != nil {
+		return nil, err
+	}
+	lns = append(lns, ln)
+
+	for _, path := range a.unixSockets {
+		ln, err := listenUnix(path)
+		if err != nil {
+			return lns, err
+		}
+		lns = append(lns, ln)
+	}
+
+	return append(lns, a.listeners...), nil
+}
+
+// listenUnix listens on a Unix socket at path, replacing any stale socket left
+// there by a previous run.
+func listenUnix(path string) (net.Listener, error) {
+	if fi, err := os.Stat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("removing stale unix socket: %w", err)
+		}
+	}
+
+	return net.Listen("unix", path)
+}
+
+// RegisterHealthCheck adds a check of a dependency of the application to the
+// readiness endpoint, /ready, which answers with HTTP 503 and the status of
+// every dependency while any of them is down. The liveness endpoint, /live, is
+// not affected by checks. Checks are given health.DefaultTimeout and their
+// results are cached for health.DefaultCacheTTL, unless changed with opts.
+func (a *Application) RegisterHealthCheck(name string, check func(ctx context.Context) error, opts ...health.CheckOption) {
+	a.health.Register(name, check, opts...)
 }
 
 // Running returns a channel to signal a caller that the Application is ready to receive a SYN packet.
@@ -167,8 +445,7 @@ func (a *Application) Run() error {
 //
 //	func Test_App(t *testing.T) {
 //		// Given
-//		t.Setenv("PORT", "0")
-//		app, err := app.NewWebApplication()
+//		app, err := app.NewWebApplication(app.WithPort(0))
 //		if err != nil {
 //			t.Fatal(err)
 //		}
@@ -201,6 +478,51 @@ func (a *Application) Shutdown() {
 	a.cancel()
 }
 
+// runtime holds the components shared by every kind of application.
+type runtime struct {
+	scope            infra.Scope
+	tracer           telemetry.Client
+	logger           log.Logger
+	level            *log.AtomicLevel
+	otelShutdownFunc otel.ShutdownFunc
+}
+
+// bootstrap starts the components shared by every kind of application from the
+// given configuration and the environment.
+func bootstrap(config Config) (runtime, error) {
+	// We must start OTel before any other dependency since
+	// there are components that require the global provider to be set.
+	otelShutdownFunc, err := startOTel()
+	if err != nil {
+		return runtime{}, err
+	}
+
+	rawScope := config.Scope
+	if rawScope == "" {
+		rawScope = getScopeFromEnv()
+	}
+
+	scope, err := infra.ParseScope(rawScope)
+	if err != nil {
+		return runtime{}, err
+	}
+
+	tracer, err := newTracer(scope)
+	if err != nil {
+		return runtime{}, err
+	}
+
+	logger, level := newLogger(config, scope)
+
+	return runtime{
+		scope:            scope,
+		tracer:           tracer,
+		logger:           logger,
+		level:            level,
+		otelShutdownFunc: otelShutdownFunc,
+	}, nil
+}
+
 func getScopeFromEnv() string {
 	scope := os.Getenv("SCOPE")
 	if scope == "" {
@@ -210,9 +532,21 @@ func getScopeFromEnv() string {
 	return scope
 }
 
-func newLogger(cfg Config) (log.Logger, *log.AtomicLevel) {
-	l := log.NewAtomicLevelAt(cfg.LogLevel)
-	return log.NewProductionLogger(&l, cfg.LogOptions...), &l
+// newLogger returns the application logger, which logs at Debug level with
+// colored console encoding in the local environment, unless overridden.
+func newLogger(cfg Config, scope infra.Scope) (log.Logger, *log.AtomicLevel) {
+	level := cfg.LogLevel
+	var opts []log.Option
+
+	if strings.EqualFold(scope.Environment, _defaultScopeEnvironment) {
+		if !cfg.logLevelSet {
+			level = log.DebugLevel
+		}
+		opts = append(opts, log.WithConsoleEncoding(), log.WithColor())
+	}
+
+	l := log.NewAtomicLevelAt(level)
+	return log.NewProductionLogger(&l, append(opts, cfg.LogOptions...)...), &l
 }
 
 func newTracer(scope infra.Scope) (telemetry.Client, error) {
@@ -234,7 +568,53 @@ func newTelemetryConfig() telemetry.Config {
 		NewRelicLicense:      os.Getenv("NEW_RELIC_LICENSE_KEY"),
 		DatadogAddress:       "datadog:8125",
 		NewRelicHighSecurity: false,
+		SampleRate:           telemetrySampleRate(),
+	}
+}
+
+// telemetrySampleRate returns the metrics sample rate set in the environment,
+// or zero if unset or invalid, which makes the client use its default.
+func telemetrySampleRate() float64 {
+	rate, err := strconv.ParseFloat(os.Getenv(_telemetrySampleRateEnv), 64)
+	if err != nil {
+		return 0
+	}
+	return rate
+}
+
+// listenAddress returns the TCP address set with WithAddress, or the one of the
+// PORT environment variable.
+func listenAddress(cfg Config) string {
+	if cfg.Address != "" {
+		return cfg.Address
+	}
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = _defaultWebApplicationPort
+	}
+	return ":" + port
+}
+
+// newTLSConfig returns the TLS configuration of the server, or nil if it must
+// serve plain HTTP.
+func newTLSConfig(cfg Config) (*tls.Config, error) {
+	if cfg.TLSCertFile == "" && cfg.TLSKeyFile == "" {
+		return cfg.TLSConfig, nil
 	}
+
+	cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading TLS certificate: %w", err)
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.TLSConfig != nil {
+		tlsConfig = cfg.TLSConfig.Clone()
+	}
+	tlsConfig.Certificates = append(tlsConfig.Certificates, cert)
+
+	return tlsConfig, nil
 }
 
 func wrapF(h http.HandlerFunc) web.Handler {
@@ -244,6 +624,14 @@ func wrapF(h http.HandlerFunc) web.Handler {
 	}
 }
 
+// syncLogger flushes the entries buffered by logger, such as the ones exported
+// with log.WithOTLPExport.
+func syncLogger(logger log.Logger) {
+	if s, ok := logger.(interface{ Sync() error }); ok {
+		_ = s.Sync()
+	}
+}
+
 func startOTel() (otel.ShutdownFunc, error) {
 	if isOpenTelemetryEnabled() {
 		shutdown, err := otel.Start(context.Background())
@@ -0,0 +1,207 @@
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// _healthcheckTimeout is the time the healthcheck command waits for the
+// readiness endpoint to answer.
+const _healthcheckTimeout = 5 * time.Second
+
+// TaskFunc is the function of a CLI command, which is given the positional
+// arguments following the command name, and a WorkerApplication with the same
+// scope, logger and telemetry the web application has. The context is done
+// when the application is asked to stop, as with WorkerApplication.Run.
+type TaskFunc func(ctx context.Context, w *WorkerApplication, args []string) error
+
+// command is a subcommand of a CLI.
+type command struct {
+	name        string
+	description string
+	run         func(args []string) error
+}
+
+// CLI runs one of the commands of a binary, such as serving the application or
+// migrating its database, so that operational tasks share the configuration,
+// logger and telemetry setup of the application.
+type CLI struct {
+	opts     []AppOptFunc
+	commands []*command
+	output   io.Writer
+}
+
+// NewCLI returns a CLI whose commands build their applications with the given
+// options, having the healthcheck command, which requests the readiness
+// endpoint of the application running at the address set with WithAddress or
+// the PORT environment variable, failing unless it's ready. It's meant for the
+// health checks of container images which lack tools such as curl.
+//
+//	cli := app.NewCLI(app.WithLogLevel(log.DebugLevel))
+//
+//	cli.Serve(func(a *app.Application) error {
+//		a.Get("/users/{id}", getUser)
+//		return nil
+//	})
+//
+//	cli.Migrate(func(ctx context.Context, w *app.WorkerApplication, args []string) error {
+//		return migrations.Up(ctx, db)
+//	})
+//
+//	if err := cli.Run(os.Args[1:]); err != nil {
+//		os.Exit(1)
+//	}
+func NewCLI(opts ...AppOptFunc) *CLI {
+	c := &CLI{opts: opts, output: os.Stderr}
+	c.add("healthcheck", "Check that the running application is ready.", c.healthcheck)
+	return c
+}
+
+// Serve adds the serve command, which runs a web application built with
+// NewWebApplication, calling setup for registering its routes, health checks
+// and hooks before running it. It's the command run when no command is given.
+func (c *CLI) Serve(setup func(a *Application) error) {
+	c.add("serve", "Serve the application.", func([]string) error {
+		a, err := NewWebApplication(c.opts...)
+		if err != nil {
+			return err
+		}
+
+		if err := setup(a); err != nil {
+			return err
+		}
+
+		return a.Run()
+	})
+}
+
+// Migrate adds the migrate command, which runs fn. See Command.
+func (c *CLI) Migrate(fn TaskFunc) {
+	c.Command("migrate", "Migrate the database.", fn)
+}
+
+// Seed adds the seed command, which runs fn. See Command.
+func (c *CLI) Seed(fn TaskFunc) {
+	c.Command("seed", "Seed the database.", fn)
+}
+
+// Command adds a command with the given name and description, shown by the
+// help command, which runs fn with a WorkerApplication built with
+// NewWorkerApplication. The OnStart and OnStop hooks and the closers registered
+// on the WorkerApplication by fn are honored as with WorkerApplication.Run.
+func (c *CLI) Command(name, description string, fn TaskFunc) {
+	c.add(name, description, func(args []string) error {
+		w, err := NewWorkerApplication(c.opts...)
+		if err != nil {
+			return err
+		}
+
+		return w.Run(func(ctx context.Context) error {
+			return fn(ctx, w, args)
+		})
+	})
+}
+
+func (c *CLI) add(name, description string, run func(args []string) error) {
+	cmd := &command{name: name, description: description, run: run}
+
+	// Commands added again replace the previous ones, keeping their position.
+	for i, existing := range c.commands {
+		if existing.name == name {
+			c.commands[i] = cmd
+			return
+		}
+	}
+	c.commands = append(c.commands, cmd)
+}
+
+// Run runs the command named by the first of the given arguments, usually
+// os.Args[1:], with the rest of them, returning its error, which is also
+// written to the standard error. The serve command is run when no arguments
+// are given, and the available commands are listed by the help command.
+func (c *CLI) Run(args []string) error {
+	name := "serve"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+
+	if name == "help" || name == "-h" || name == "--help" {
+		c.usage()
+		return nil
+	}
+
+	for _, cmd := range c.commands {
+		if cmd.name == name {
+			err := cmd.run(args)
+			if err != nil {
+				_, _ = fmt.Fprintf(c.output, "%s: %v\n", name, err)
+			}
+			return err
+		}
+	}
+
+	c.usage()
+	return fmt.Errorf("unknown command %q", name)
+}
+
+// usage writes the available commands.
+func (c *CLI) usage() {
+	_, _ = fmt.Fprintf(c.output, "Usage: %s <command> [arguments]\n\nCommands:\n", os.Args[0])
+
+	tw := tabwriter.NewWriter(c.output, 0, 0, 2, ' ', 0)
+	for _, cmd := range c.commands {
+		_, _ = fmt.Fprintf(tw, "  %s\t%s\n", cmd.name, cmd.description)
+	}
+	_, _ = fmt.Fprintf(tw, "  help\tShow this help.\n")
+	_ = tw.Flush()
+}
+
+// healthcheck requests the readiness endpoint of the application listening on
+// the configured address, failing unless it answers with HTTP 200.
+func (c *CLI) healthcheck([]string) error {
+	var config Config
+	for _, opt := range c.opts {
+		opt(&config)
+	}
+
+	host, port, err := net.SplitHostPort(listenAddress(config))
+	if err != nil {
+		return err
+	}
+	if host == "" {
+		host = "localhost"
+	}
+
+	client := &http.Client{Timeout: _healthcheckTimeout}
+
+	scheme := "http"
+	if config.TLSConfig != nil || config.TLSCertFile != "" {
+		// The certificate is not verified, since it's not issued for localhost.
+		// Autocert only completes handshakes for its hosts, so one of them is
+		// sent as the server name.
+		scheme = "https"
+		client.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{ServerName: config.tlsServerName, InsecureSkipVerify: true}, //nolint:gosec
+		}
+	}
+
+	resp, err := client.Get(fmt.Sprintf("%s://%s/ready", scheme, net.JoinHostPort(host, port)))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("not ready: " + string(body))
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/luizaranda/go-core/pkg/telemetry"
+)
+
+// closer is a resource of an application closed when it stops.
+type closer struct {
+	name string
+	c    io.Closer
+}
+
+// close closes every registered closer in reverse registration order, each
+// bounded by the hook timeout, returning their errors joined.
+func (l *lifecycle) close(tracer telemetry.Client) error {
+	l.mu.Lock()
+	closers := slices.Clone(l.closers)
+	l.mu.Unlock()
+
+	var errs []error
+	for _, c := range slices.Backward(closers) {
+		start := time.Now()
+		err := l.call(context.Background(), func(context.Context) error {
+			return c.c.Close()
+		})
+
+		status := "success"
+		if err != nil {
+			status = "failure"
+			errs = append(errs, fmt.Errorf("close %s: %w", c.name, err))
+		}
+		tracer.Timing("toolkit.app.closer.shutdown.time", time.Since(start), []string{"closer:" + telemetry.SanitizeMetricTagValue(c.name), "status:" + status})
+	}
+	return errors.Join(errs...)
+}
+
+// RegisterCloser registers a resource of the application, such as a database
+// pool or an httpclient.Cache, which is closed by Run once the server stopped
+// and the OnStop hooks were called. Resources are closed in reverse
+// registration order, so that they can rely on the ones registered before, and
+// their errors are returned by Run.
+//
+// Every Close is given the timeout set with WithHookTimeout, 15 seconds by
+// default, and its duration is recorded in the toolkit.app.closer.shutdown.time
+// metric, tagged by closer and status.
+func (a *Application) RegisterCloser(name string, c io.Closer) {
+	a.lifecycle.mu.Lock()
+	defer a.lifecycle.mu.Unlock()
+
+	a.lifecycle.closers = append(a.lifecycle.closers, closer{name: name, c: c})
+}
+
+// RegisterCloser registers a resource of the application which is closed by
+// Run once the worker stopped. See Application.RegisterCloser.
+func (a *WorkerApplication) RegisterCloser(name string, c io.Closer) {
+	a.lifecycle.mu.Lock()
+	defer a.lifecycle.mu.Unlock()
+
+	a.lifecycle.closers = append(a.lifecycle.closers, closer{name: name, c: c})
+}
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"context"
+	"sync"
+
+	"github.com/luizaranda/go-core/pkg/consumer"
+	"github.com/luizaranda/go-core/pkg/log"
+)
+
+// consumers runs the message processors registered on an application while it
+// runs.
+type consumers struct {
+	mu         sync.Mutex // guards processors
+	processors []*consumer.Processor
+}
+
+func (c *consumers) add(p *consumer.Processor) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.processors = append(c.processors, p)
+}
+
+// start runs the processors until ctx is done. The returned function waits for
+// them to drain the messages being handled.
+func (c *consumers) start(ctx context.Context, logger log.Logger) (wait func()) {
+	c.mu.Lock()
+	processors := c.processors
+	c.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, p := range processors {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := p.Run(ctx); err != nil {
+				logger.Error("consumer stopped", log.String("consumer", p.Name()), log.Err(err))
+			}
+		}()
+	}
+
+	return wg.Wait
+}
+
+// RegisterConsumer adds a message processor, built with consumer.New, which
+// receives and handles messages while the application runs:
+//
+//	application.RegisterConsumer(consumer.New(queue, consumer.HandlerFunc(handleOrder), consumer.Config{
+//		Name:        "orders",
+//		Concurrency: 10,
+//	}))
+//
+// Processors start once the application is listening and stop receiving when
+// it shuts down, draining the messages being handled within their drain
+// timeout before Run returns.
+func (a *Application) RegisterConsumer(p *consumer.Processor) {
+	a.consumers.add(p)
+}
+
+// RegisterConsumer adds a message processor which receives and handles messages
+// while the application runs. See Application.RegisterConsumer.
+func (a *WorkerApplication) RegisterConsumer(p *consumer.Processor) {
+	a.consumers.add(p)
+}
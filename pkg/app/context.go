@@ -0,0 +1,43 @@
+package app
+
+import (
+	"context"
+	"net"
+)
+
+// ContextValue is a value attached to the context of every request of an
+// application under Key. See WithContextValue.
+type ContextValue struct {
+	Key   any
+	Value any
+}
+
+type applicationKey struct{}
+
+// baseContext returns the base context of the requests accepted on ln, which
+// holds the application and the values given with WithContextValue.
+func (a *Application) baseContext(ln net.Listener) context.Context {
+	ctx := context.Background()
+	if a.baseContextFunc != nil {
+		ctx = a.baseContextFunc(ln)
+	}
+
+	for _, v := range a.contextValues {
+		ctx = context.WithValue(ctx, v.Key, v.Value)
+	}
+
+	return context.WithValue(ctx, applicationKey{}, a)
+}
+
+// FromContext returns the Application serving the request of the given
+// context, so that handlers outside of the main package can resolve the
+// dependencies provided to it, or nil if there is none.
+//
+//	func getUser(w http.ResponseWriter, r *http.Request) error {
+//		users, err := app.Resolve[*UserRepository](app.FromContext(r.Context()))
+//		...
+//	}
+func FromContext(ctx context.Context) *Application {
+	a, _ := ctx.Value(applicationKey{}).(*Application)
+	return a
+}
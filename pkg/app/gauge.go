@@ -0,0 +1,24 @@
+package app
+
+import "github.com/luizaranda/go-core/pkg/internal/infra"
+
+// RegisterGauge adds a gauge metric with the given name and tags, such as the
+// depth of a queue or the stats of a pool, whose value is returned by fn. It's
+// polled every 10 seconds until the application stops, while it drains
+// included, along with the toolkit gauges:
+//
+//	application.RegisterGauge("orders.queue.depth", func() float64 {
+//		return float64(queue.Len())
+//	}, "queue:orders")
+//
+// Since fn is called from the polling goroutine, it must be safe for
+// concurrent use and return quickly.
+func (a *Application) RegisterGauge(name string, fn func() float64, tags ...string) {
+	a.gauges.Register(infra.Gauge{Name: name, Fn: fn, Tags: tags})
+}
+
+// RegisterGauge adds a gauge metric polled while the application runs. See
+// Application.RegisterGauge.
+func (a *WorkerApplication) RegisterGauge(name string, fn func() float64, tags ...string) {
+	a.gauges.Register(infra.Gauge{Name: name, Fn: fn, Tags: tags})
+}
@@ -0,0 +1,121 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// _defaultHookTimeout is the time every lifecycle hook is given to return,
+// unless set with WithHookTimeout.
+const _defaultHookTimeout = 15 * time.Second
+
+// lifecycle keeps the hooks called when an application starts and stops, and
+// the resources closed once it stopped.
+type lifecycle struct {
+	mu      sync.Mutex // guards the hooks and closers
+	onStart []func(ctx context.Context) error
+	onStop  []func(ctx context.Context) error
+	closers []closer
+	timeout time.Duration
+}
+
+// start calls the start hooks in registration order, stopping at the first one
+// failing.
+func (l *lifecycle) start(ctx context.Context) error {
+	l.mu.Lock()
+	hooks := slices.Clone(l.onStart)
+	l.mu.Unlock()
+
+	for i, hook := range hooks {
+		if err := l.call(ctx, hook); err != nil {
+			return fmt.Errorf("start hook %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// stop calls every stop hook in reverse registration order, returning their
+// errors joined.
+func (l *lifecycle) stop() error {
+	l.mu.Lock()
+	hooks := slices.Clone(l.onStop)
+	l.mu.Unlock()
+
+	var errs []error
+	for i, hook := range slices.Backward(hooks) {
+		// The application context is already canceled when stopping.
+		if err := l.call(context.Background(), hook); err != nil {
+			errs = append(errs, fmt.Errorf("stop hook %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// call calls hook with a context bounded by the hook timeout, not waiting for
+// it past the timeout.
+func (l *lifecycle) call(ctx context.Context, hook func(ctx context.Context) error) error {
+	timeout := l.timeout
+	if timeout <= 0 {
+		timeout = _defaultHookTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- hook(ctx)
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("hook did not return within %s: %w", timeout, ctx.Err())
+	}
+}
+
+// OnStart registers a hook which is called by Run before serving requests, such
+// as one warming caches. Hooks are called in registration order, and if any of
+// them fails Run stops and returns its error. Every hook is given a context
+// with the timeout set with WithHookTimeout, 15 seconds by default.
+func (a *Application) OnStart(hook func(ctx context.Context) error) {
+	a.lifecycle.mu.Lock()
+	defer a.lifecycle.mu.Unlock()
+
+	a.lifecycle.onStart = append(a.lifecycle.onStart, hook)
+}
+
+// OnStop registers a hook which is called by Run once the server stopped, such
+// as one closing a database pool. Hooks are called in reverse registration
+// order, also when Run fails, and their errors are returned by Run. Every hook
+// is given a context with the timeout set with WithHookTimeout, 15 seconds by
+// default.
+func (a *Application) OnStop(hook func(ctx context.Context) error) {
+	a.lifecycle.mu.Lock()
+	defer a.lifecycle.mu.Unlock()
+
+	a.lifecycle.onStop = append(a.lifecycle.onStop, hook)
+}
+
+// OnStart registers a hook which is called by Run before running the worker.
+// See Application.OnStart.
+func (a *WorkerApplication) OnStart(hook func(ctx context.Context) error) {
+	a.lifecycle.mu.Lock()
+	defer a.lifecycle.mu.Unlock()
+
+	a.lifecycle.onStart = append(a.lifecycle.onStart, hook)
+}
+
+// OnStop registers a hook which is called by Run once the worker stopped. See
+// Application.OnStop.
+func (a *WorkerApplication) OnStop(hook func(ctx context.Context) error) {
+	a.lifecycle.mu.Lock()
+	defer a.lifecycle.mu.Unlock()
+
+	a.lifecycle.onStop = append(a.lifecycle.onStop, hook)
+}
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/luizaranda/go-core/pkg/log"
+	"github.com/luizaranda/go-core/pkg/web"
+)
+
+// loggerLevel is the level of the loggers with a given name, as served by the
+// /debug/log/level endpoint.
+type loggerLevel struct {
+	Logger string    `json:"logger"`
+	Level  log.Level `json:"level"`
+}
+
+// levelHandler serves the /debug/log/level endpoint, which changes the level of
+// the application logger, or the one of the named loggers given by the logger
+// query parameter, as in:
+//
+//	curl -X PUT 'localhost:8080/debug/log/level?logger=pkg/transport' -d '{"level":"debug"}'
+//
+// GET requests answer with the current level, and DELETE requests remove the
+// level set for the named loggers, which log again at the application level.
+type levelHandler struct {
+	level *log.AtomicLevel
+}
+
+func (h *levelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("logger")
+	if name == "" {
+		h.level.ServeHTTP(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req struct {
+			Level *log.Level `json:"level"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			_ = web.EncodeJSON(w, web.BadRequestErrorf("invalid level: %v", err), http.StatusBadRequest)
+			return
+		}
+		if req.Level == nil {
+			_ = web.EncodeJSON(w, web.BadRequestErrorf("level is required"), http.StatusBadRequest)
+			return
+		}
+		log.SetLevel(name, *req.Level)
+	case http.MethodDelete:
+		log.UnsetLevel(name)
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		_ = web.EncodeJSON(w, web.NewErrorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+
+	lvl, ok := log.LevelFor(name)
+	if !ok {
+		lvl = h.level.Level()
+	}
+	_ = web.EncodeJSON(w, loggerLevel{Logger: name, Level: lvl}, http.StatusOK)
+}
@@ -0,0 +1,101 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/luizaranda/go-core/pkg/log"
+	"github.com/luizaranda/go-core/pkg/web"
+)
+
+// _maintenanceRetryAfter is the time clients are told to wait before retrying
+// requests rejected during maintenance.
+const _maintenanceRetryAfter = 5 * time.Minute
+
+// maintenanceState is the maintenance mode of an Application, replaced as a
+// whole on every change.
+type maintenanceState struct {
+	Enabled   bool     `json:"enabled"`
+	Allowlist []string `json:"allowlist,omitempty"`
+}
+
+// maintenanceSwitch rejects requests with HTTP 503 while maintenance mode is on.
+type maintenanceSwitch struct {
+	state  atomic.Pointer[maintenanceState]
+	logger log.Logger
+}
+
+func newMaintenanceSwitch(logger log.Logger) *maintenanceSwitch {
+	m := &maintenanceSwitch{logger: logger}
+	m.state.Store(&maintenanceState{})
+	return m
+}
+
+func (m *maintenanceSwitch) set(on bool, allowlist []string) {
+	m.state.Store(&maintenanceState{Enabled: on, Allowlist: slices.Clone(allowlist)})
+	m.logger.Info("maintenance mode changed", log.Bool("enabled", on), log.Strings("allowlist", allowlist))
+}
+
+func (m *maintenanceSwitch) middleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		state := m.state.Load()
+		if !state.Enabled || maintenanceAllowed(state.Allowlist, r.URL.Path) {
+			next(w, r)
+			return
+		}
+
+		w.Header().Set("Retry-After", strconv.Itoa(int(_maintenanceRetryAfter.Seconds())))
+		_ = web.EncodeJSON(w, web.NewErrorf(http.StatusServiceUnavailable, "service under maintenance"), http.StatusServiceUnavailable)
+	}
+}
+
+// maintenanceAllowed tells whether path is in the allowlist, either as is or
+// under one of its entries ending in "/*".
+func maintenanceAllowed(allowlist []string, path string) bool {
+	return slices.ContainsFunc(allowlist, func(allowed string) bool {
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok {
+			return path == prefix || strings.HasPrefix(path, prefix+"/")
+		}
+		return path == allowed
+	})
+}
+
+// ServeHTTP answers GET requests with the maintenance state as JSON, and changes
+// it with the one given as JSON to PUT requests.
+func (m *maintenanceSwitch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var state maintenanceState
+		if err := json.NewDecoder(r.Body).Decode(&state); err != nil {
+			_ = web.EncodeJSON(w, web.BadRequestErrorf("invalid maintenance state: %v", err), http.StatusBadRequest)
+			return
+		}
+		m.set(state.Enabled, state.Allowlist)
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		_ = web.EncodeJSON(w, web.NewErrorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+
+	_ = web.EncodeJSON(w, m.state.Load(), http.StatusOK)
+}
+
+// SetMaintenance turns the maintenance mode of the application on or off. While
+// on, every route answers HTTP 503 with a Retry-After header, except for the
+// health checks, the /debug endpoints and the paths of the allowlist, which are
+// either exact paths or prefixes ending in "/*", such as "/admin/*".
+//
+// The maintenance mode can also be changed at runtime through the
+// /debug/maintenance endpoint, which answers GET requests with the current
+// state and changes it with the one given to PUT requests, as in:
+//
+//	curl -X PUT localhost:8080/debug/maintenance -d '{"enabled":true,"allowlist":["/admin/*"]}'
+func (a *Application) SetMaintenance(on bool, allowlist ...string) {
+	a.maintenance.set(on, allowlist)
+}
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/luizaranda/go-core/pkg/log"
+	"github.com/luizaranda/go-core/pkg/telemetry"
+)
+
+// MigrationOption configures how the migrations given with WithMigrations run.
+type MigrationOption func(*migrations)
+
+// MigrateIf makes the migrations run only if enabled, such as the value of a
+// command line flag.
+func MigrateIf(enabled bool) MigrationOption {
+	return func(m *migrations) {
+		m.enabled = m.enabled && enabled
+	}
+}
+
+// MigrateWhenEnv makes the migrations run only if the environment variable of
+// the given name is set to a true value, as parsed by strconv.ParseBool.
+func MigrateWhenEnv(name string) MigrationOption {
+	return func(m *migrations) {
+		enabled, _ := strconv.ParseBool(os.Getenv(name))
+		m.enabled = m.enabled && enabled
+	}
+}
+
+// MigrateFailFast makes Run return the error of the migrations, instead of
+// logging it and running the application anyway.
+func MigrateFailFast() MigrationOption {
+	return func(m *migrations) {
+		m.failFast = true
+	}
+}
+
+// migrations runs the schema migrations of an application before it starts.
+type migrations struct {
+	fn       func(ctx context.Context) error
+	enabled  bool
+	failFast bool
+}
+
+func newMigrations(fn func(ctx context.Context) error, opts []MigrationOption) *migrations {
+	if fn == nil {
+		return nil
+	}
+
+	m := &migrations{fn: fn, enabled: true}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// run runs the migrations if enabled, returning their error only in fail fast
+// mode.
+func (m *migrations) run(ctx context.Context, tracer telemetry.Client, logger log.Logger) error {
+	if m == nil || !m.enabled {
+		return nil
+	}
+
+	logger.Info("running migrations")
+
+	start := time.Now()
+	err := m.fn(ctx)
+
+	status := "success"
+	if err != nil {
+		status = "failure"
+	}
+	tracer.Timing("toolkit.app.migrations.time", time.Since(start), []string{"status:" + status})
+
+	switch {
+	case err == nil:
+		logger.Info("migrations done", log.Duration("duration", time.Since(start)))
+		return nil
+	case m.failFast:
+		return fmt.Errorf("migrations: %w", err)
+	default:
+		logger.Error("migrations failed", log.Err(err))
+		return nil
+	}
+}
@@ -1,10 +1,18 @@
 package app
 
 import (
+	"context"
+	"crypto/tls"
+	"net"
 	"net/http"
+	"os"
+	"strconv"
+	"time"
 
 	"github.com/luizaranda/go-core/pkg/log"
 	"github.com/luizaranda/go-core/pkg/web"
+	"golang.org/x/crypto/acme/autocert"
+	"google.golang.org/grpc"
 )
 
 type Config struct {
@@ -17,6 +25,35 @@ type Config struct {
 	LogOptions         []log.Option
 	ServerTimeouts     web.Timeouts
 	EnableProfiling    bool
+	EnableH2C          bool
+	TLSCertFile        string
+	TLSKeyFile         string
+	TLSConfig          *tls.Config
+	Address            string
+	Scope              string
+	BaseContext        func(net.Listener) context.Context
+	ContextValues      []ContextValue
+	ShutdownSignals    []os.Signal
+	SignalHandlers     []SignalHandler
+	Listeners          []net.Listener
+	UnixSockets        []string
+	RouterOptions      []web.RouterOption
+	HookTimeout        time.Duration
+	GRPCRegister       func(*grpc.Server)
+	GRPCOptions        []grpc.ServerOption
+	DrainDelay         time.Duration
+	Warmup             func(ctx context.Context) error
+	WarmupTimeout      time.Duration
+	Migrations         func(ctx context.Context) error
+	MigrationOptions   []MigrationOption
+
+	// logLevelSet tells whether LogLevel was set, since its zero value is
+	// log.InfoLevel.
+	logLevelSet bool
+
+	// tlsServerName is the host sent as SNI by the healthcheck command, which
+	// must be one of the hosts allowed by WithAutocert.
+	tlsServerName string
 }
 
 // AppOptFunc allows defining custom functions for configuring an Application.
@@ -48,21 +85,35 @@ func WithNotFoundHandler(h http.Handler) AppOptFunc {
 
 // WithLogLevel sets the level at which the application logger will log.
 //
-// Default behavior is to log at Info level in production, and log level in
-// local and test environments.
+// Default behavior is to log at Debug level in the local environment, and at
+// Info level in every other one.
 func WithLogLevel(level log.Level) AppOptFunc {
 	return func(config *Config) {
 		config.LogLevel = level
+		config.logLevelSet = true
 	}
 }
 
 // WithLogOptions sets the options to the application logger.
+//
+// Default behavior is to log with colored console encoding in the local
+// environment, and with key value encoding in every other one. Options
+// setting the encoding, such as log.WithKeyValueEncoding, take precedence.
 func WithLogOptions(opts ...log.Option) AppOptFunc {
 	return func(config *Config) {
 		config.LogOptions = opts
 	}
 }
 
+// WithScope sets the scope in which the application runs, such as
+// "production-indexer-foo", overriding the SCOPE environment variable. See
+// Scope.
+func WithScope(scope string) AppOptFunc {
+	return func(config *Config) {
+		config.Scope = scope
+	}
+}
+
 // WithTimeouts sets the different timeouts that the web server uses.
 //
 // Default behavior is to not have timeouts for incoming requests.
@@ -85,3 +136,218 @@ func WithDisableCompression() AppOptFunc {
 		config.DisableCompression = true
 	}
 }
+
+// WithH2C enables HTTP/2 without TLS, known as h2c, on the application
+// listener along with HTTP/1, for gRPC-gateway and service mesh traffic.
+// Clients must use HTTP/2 with prior knowledge. See web.WithH2C.
+func WithH2C() AppOptFunc {
+	return func(config *Config) {
+		config.EnableH2C = true
+	}
+}
+
+// WithTLS makes the application serve HTTPS, with HTTP/2 enabled, using the
+// certificate and key of the given PEM encoded files, which are loaded when
+// creating the application.
+func WithTLS(certFile, keyFile string) AppOptFunc {
+	return func(config *Config) {
+		config.TLSCertFile = certFile
+		config.TLSKeyFile = keyFile
+	}
+}
+
+// WithTLSConfig makes the application serve HTTPS, with HTTP/2 enabled, using
+// the given TLS configuration, which must provide the server certificates
+// through its Certificates or GetCertificate fields.
+func WithTLSConfig(cfg *tls.Config) AppOptFunc {
+	return func(config *Config) {
+		config.TLSConfig = cfg
+		config.tlsServerName = ""
+	}
+}
+
+// WithAutocert makes the application serve HTTPS using certificates obtained
+// from Let's Encrypt through ACME for the given hosts, accepting its terms of
+// service. Certificates are cached in cacheDir, which should be persistent to
+// avoid hitting the rate limits of Let's Encrypt on restarts.
+//
+// Challenges are answered using the TLS-ALPN-01 method, so the application
+// must be reachable on port 443 of every host.
+func WithAutocert(cacheDir string, hosts ...string) AppOptFunc {
+	return func(config *Config) {
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(hosts...),
+			Cache:      autocert.DirCache(cacheDir),
+		}
+		config.TLSConfig = m.TLSConfig()
+		if len(hosts) > 0 {
+			config.tlsServerName = hosts[0]
+		}
+	}
+}
+
+// WithAddress sets the TCP address the application listens on, such as
+// "127.0.0.1:8080", or ":0" for a port chosen by the system, which is then
+// returned by Application.Port. It takes precedence over the PORT environment
+// variable. Default is ":8080", unless PORT is set.
+func WithAddress(addr string) AppOptFunc {
+	return func(config *Config) {
+		config.Address = addr
+	}
+}
+
+// WithPort sets the port the application listens on, on every interface, or 0
+// for a port chosen by the system. See WithAddress.
+func WithPort(port int) AppOptFunc {
+	return func(config *Config) {
+		config.Address = ":" + strconv.Itoa(port)
+	}
+}
+
+// WithListener makes the application also serve on the given listener, besides
+// the TCP one set with WithAddress or the PORT environment variable. Every
+// listener serves the same router, with the same timeouts and TLS
+// configuration, and is closed when the application stops.
+func WithListener(ln net.Listener) AppOptFunc {
+	return func(config *Config) {
+		config.Listeners = append(config.Listeners, ln)
+	}
+}
+
+// WithUnixSocket makes the application also serve on a Unix socket at the given
+// path, such as one shared with a sidecar for health probes. A stale socket
+// left at path by a previous run is replaced, and the socket is removed when
+// the application stops. See WithListener.
+func WithUnixSocket(path string) AppOptFunc {
+	return func(config *Config) {
+		config.UnixSockets = append(config.UnixSockets, path)
+	}
+}
+
+// WithBaseContext sets the function returning the base context of the requests
+// accepted on each listener of the application, mirroring
+// http.Server.BaseContext. Default is context.Background.
+func WithBaseContext(fn func(net.Listener) context.Context) AppOptFunc {
+	return func(config *Config) {
+		config.BaseContext = fn
+	}
+}
+
+// WithContextValue attaches an application scoped value, such as its
+// configuration, to the context of every request under the given key, before
+// any middleware runs. Keys follow the rules of context.WithValue.
+func WithContextValue(key, value any) AppOptFunc {
+	return func(config *Config) {
+		config.ContextValues = append(config.ContextValues, ContextValue{Key: key, Value: value})
+	}
+}
+
+// WithShutdownSignals sets the signals which stop the application when received
+// by the running process. Default is SIGINT and SIGTERM.
+func WithShutdownSignals(sigs ...os.Signal) AppOptFunc {
+	return func(config *Config) {
+		config.ShutdownSignals = sigs
+	}
+}
+
+// WithSignal registers a handler which is called every time sig is received by
+// the running process while the application runs, such as one dumping the
+// stacks of every goroutine:
+//
+//	app.WithSignal(syscall.SIGUSR1, func(ctx context.Context) error {
+//		return pprof.Lookup("goroutine").WriteTo(os.Stderr, 2)
+//	})
+//
+// Handlers are called one at a time, in registration order, and their errors
+// are logged. Web applications reload their components on SIGHUP besides
+// calling its handlers. See Application.RegisterReloader.
+func WithSignal(sig os.Signal, handler func(ctx context.Context) error) AppOptFunc {
+	return func(config *Config) {
+		config.SignalHandlers = append(config.SignalHandlers, SignalHandler{Signal: sig, Handle: handler})
+	}
+}
+
+// WithRouterOptions sets the options of the application router, which control
+// how requests are matched to routes. See web.RouterOption.
+func WithRouterOptions(opts ...web.RouterOption) AppOptFunc {
+	return func(config *Config) {
+		config.RouterOptions = append(config.RouterOptions, opts...)
+	}
+}
+
+// WithHookTimeout sets the time every OnStart and OnStop hook is given to
+// return. Default is 15 seconds.
+func WithHookTimeout(timeout time.Duration) AppOptFunc {
+	return func(config *Config) {
+		config.HookTimeout = timeout
+	}
+}
+
+// WithGRPC makes the application also serve gRPC, on the same listeners as
+// HTTP, with the services registered by register on a server created with the
+// given options. Requests are told apart by their application/grpc content
+// type, and HTTP/2 without TLS is enabled, as with WithH2C, unless the
+// application serves HTTPS.
+//
+// The gRPC server has interceptors providing the same logging, telemetry,
+// panic recovery and OpenTelemetry tracing as the web middlewares. Its
+// requests are recorded in the toolkit.grpc.server.request metrics, tagged by
+// method and status code.
+//
+//	app.WithGRPC(func(s *grpc.Server) {
+//		pb.RegisterUsersServer(s, usersServer)
+//	})
+func WithGRPC(register func(*grpc.Server), opts ...grpc.ServerOption) AppOptFunc {
+	return func(config *Config) {
+		config.GRPCRegister = register
+		config.GRPCOptions = opts
+	}
+}
+
+// WithDrainDelay sets the time the application keeps serving requests once
+// asked to stop, before closing its listeners and shutting down, for
+// zero-downtime rollouts behind load balancers. The readiness endpoint fails
+// during this time, so that load balancers stop routing requests to the
+// application, and the requests in flight are recorded in the
+// toolkit.http.server.inflight_requests gauge until it stops.
+//
+// Default is to shut down right away. The delay should be longer than the
+// interval of the readiness probes.
+func WithDrainDelay(delay time.Duration) AppOptFunc {
+	return func(config *Config) {
+		config.DrainDelay = delay
+	}
+}
+
+// WithWarmup sets a function which primes the application, such as its caches
+// and connection pools, run by Run with the given timeout once the listeners
+// are bound. The readiness endpoint fails until it returns, so that load
+// balancers keep traffic away meanwhile, through the warmup check. If it fails
+// or times out, the application stops and Run returns its error. A zero timeout
+// means one minute.
+func WithWarmup(fn func(ctx context.Context) error, timeout time.Duration) AppOptFunc {
+	return func(config *Config) {
+		config.Warmup = fn
+		config.WarmupTimeout = timeout
+	}
+}
+
+// WithMigrations sets a function which runs the schema migrations of the
+// application, such as ones embedded in the binary, called by Run before the
+// OnStart hooks and before listening, so that no traffic is served meanwhile:
+//
+//	app.WithMigrations(func(ctx context.Context) error {
+//		return migrate.Up(ctx, db, migrationsFS)
+//	}, app.MigrateWhenEnv("RUN_MIGRATIONS"), app.MigrateFailFast())
+//
+// Migrations run unless disabled with MigrateIf or MigrateWhenEnv, and their
+// failures are logged, unless MigrateFailFast makes Run return them. Their
+// duration is recorded in the toolkit.app.migrations.time metric, tagged by
+// status.
+func WithMigrations(fn func(ctx context.Context) error, opts ...MigrationOption) AppOptFunc {
+	return func(config *Config) {
+		config.Migrations = fn
+		config.MigrationOptions = opts
+	}
+}
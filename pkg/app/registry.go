@@ -0,0 +1,214 @@
+package app
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// registry keeps the dependencies shared by the modules of an application.
+type registry struct {
+	mu        sync.Mutex // guards providers, and the providers and resolutions state
+	providers map[reflect.Type]*provider
+}
+
+// provider builds a dependency once, on its first resolution.
+type provider struct {
+	construct func(d *Dependencies) (any, error)
+	owner     *resolution   // resolution constructing the dependency
+	done      chan struct{} // closed once constructed, nil until resolving
+	value     any
+	err       error
+}
+
+// resolution is a chain of dependencies being constructed, started by a
+// Resolve call with an Application and followed by the Resolve calls of the
+// constructors with the Dependencies they are given.
+type resolution struct {
+	// waiting is the provider the resolution waits for, constructed by
+	// another resolution, so that resolutions waiting in a cycle are detected.
+	waiting *provider
+}
+
+// Resolver resolves the dependencies of an application. It's either the
+// *Application, or the *Dependencies given to the constructors of
+// dependencies.
+type Resolver interface {
+	resolve(t reflect.Type) (any, error)
+}
+
+// Dependencies is the Application given to the constructors of dependencies,
+// which must resolve the dependencies they require with it, rather than with
+// the Application, so that cycles are detected.
+type Dependencies struct {
+	*Application
+
+	res   *resolution
+	stack []reflect.Type // types under construction, outermost first
+}
+
+func (a *Application) resolve(t reflect.Type) (any, error) {
+	return a.registry.resolve(a, t, nil, nil)
+}
+
+func (d *Dependencies) resolve(t reflect.Type) (any, error) {
+	return d.registry.resolve(d.Application, t, d.res, d.stack)
+}
+
+func (r *registry) provide(t reflect.Type, construct func(d *Dependencies) (any, error)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.providers[t]; ok {
+		panic(fmt.Sprintf("app: dependency %s already provided", t))
+	}
+
+	if r.providers == nil {
+		r.providers = make(map[reflect.Type]*provider)
+	}
+	r.providers[t] = &provider{construct: construct}
+}
+
+// resolve returns the dependency of type t, constructing it as part of res, nil
+// for a new resolution, while the types of stack are being constructed.
+func (r *registry) resolve(a *Application, t reflect.Type, res *resolution, stack []reflect.Type) (any, error) {
+	if slices.Contains(stack, t) {
+		return nil, fmt.Errorf("app: dependency %s requires itself: %s", t, formatCycle(append(stack, t)))
+	}
+
+	r.mu.Lock()
+	p, ok := r.providers[t]
+	if !ok {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("app: dependency %s not provided", t)
+	}
+
+	if p.done != nil {
+		select {
+		case <-p.done:
+			r.mu.Unlock()
+			return p.value, p.err
+		default:
+		}
+
+		// The dependency is being constructed by another resolution, which
+		// is waited for unless it waits for this one.
+		if res != nil {
+			for owner := p.owner; owner != nil; owner = owner.waiting.owner {
+				if owner == res {
+					r.mu.Unlock()
+					return nil, fmt.Errorf("app: dependency %s requires itself, through a concurrent resolution", t)
+				}
+				if owner.waiting == nil {
+					break
+				}
+			}
+			res.waiting = p
+		}
+		r.mu.Unlock()
+
+		<-p.done
+
+		if res != nil {
+			r.mu.Lock()
+			res.waiting = nil
+			r.mu.Unlock()
+		}
+		return p.value, p.err
+	}
+
+	if res == nil {
+		res = &resolution{}
+	}
+	p.owner = res
+	p.done = make(chan struct{})
+	r.mu.Unlock()
+
+	// Resolutions waiting for the dependency are released if the constructor
+	// panics.
+	defer func() {
+		if rvr := recover(); rvr != nil {
+			r.mu.Lock()
+			p.err = fmt.Errorf("app: constructing dependency %s: panic: %v", t, rvr)
+			close(p.done)
+			r.mu.Unlock()
+			panic(rvr)
+		}
+	}()
+
+	// The constructor is called without holding the lock, since it usually
+	// resolves the dependencies it requires.
+	value, err := p.construct(&Dependencies{
+		Application: a,
+		res:         res,
+		stack:       append(stack[:len(stack):len(stack)], t),
+	})
+	if err != nil {
+		err = fmt.Errorf("app: constructing dependency %s: %w", t, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p.value, p.err = value, err
+	close(p.done)
+	return value, err
+}
+
+// formatCycle formats the types of a cycle of dependencies, as in
+// "*A -> *B -> *A".
+func formatCycle(types []reflect.Type) string {
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = t.String()
+	}
+	return strings.Join(names, " -> ")
+}
+
+// Provide registers the constructor of the dependency of type T shared by the
+// modules of the application, such as a database pool or a client, so that
+// they don't have to rely on global variables. The constructor is called once,
+// on the first Resolve of T, and can resolve the dependencies it requires with
+// the Dependencies it's given:
+//
+//	app.Provide(application, func(d *app.Dependencies) (*sql.DB, error) {
+//		return sql.Open("mysql", dsn)
+//	})
+//
+//	app.Provide(application, func(d *app.Dependencies) (*UserRepository, error) {
+//		db, err := app.Resolve[*sql.DB](d)
+//		if err != nil {
+//			return nil, err
+//		}
+//		return NewUserRepository(db, d.Logger), nil
+//	})
+//
+// Dependencies should be provided while setting the application up, before
+// calling Run. This function will panic if T was already provided.
+func Provide[T any](a *Application, constructor func(d *Dependencies) (T, error)) {
+	a.registry.provide(reflect.TypeFor[T](), func(d *Dependencies) (any, error) {
+		return constructor(d)
+	})
+}
+
+// Resolve returns the dependency of type T of the application, given as the
+// *Application or, within constructors, as their *Dependencies. It's
+// constructed on the first call with the constructor given to Provide. The
+// result of the constructor, including its error, is kept for the next calls.
+//
+// It fails if T was not provided, or its constructor resolves T itself,
+// directly or through other dependencies. Calls made while T is being
+// constructed by another goroutine wait for it.
+func Resolve[T any](r Resolver) (T, error) {
+	value, err := r.resolve(reflect.TypeFor[T]())
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	// The value is nil if T is an interface and the constructor returned nil.
+	v, _ := value.(T)
+	return v, nil
+}
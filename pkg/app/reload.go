@@ -0,0 +1,89 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/luizaranda/go-core/pkg/log"
+	"github.com/luizaranda/go-core/pkg/telemetry"
+	"github.com/luizaranda/go-core/pkg/web"
+)
+
+// reloader is a component of an application which can be re-evaluated at
+// runtime.
+type reloader struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// reloaders re-evaluates the reloadable components of an application.
+type reloaders struct {
+	mu     sync.Mutex // guards list, serializing reloads
+	list   []reloader
+	tracer telemetry.Client
+	logger log.Logger
+}
+
+func (r *reloaders) add(name string, fn func(ctx context.Context) error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.list = append(r.list, reloader{name: name, fn: fn})
+}
+
+// reload calls every reloader in registration order, returning the errors of
+// the failing ones joined.
+func (r *reloaders) reload(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var errs []error
+	for _, rl := range r.list {
+		status := "success"
+		if err := rl.fn(ctx); err != nil {
+			status = "failure"
+			errs = append(errs, fmt.Errorf("%s: %w", rl.name, err))
+			r.logger.Error("reload failed", log.String("component", rl.name), log.Err(err))
+		}
+		r.tracer.Incr("toolkit.app.reload", []string{"component:" + telemetry.SanitizeMetricTagValue(rl.name), "status:" + status})
+	}
+
+	r.logger.Info("reloaded", log.Int("components", len(r.list)), log.Int("failed", len(errs)))
+	return errors.Join(errs...)
+}
+
+// ServeHTTP reloads on POST requests, answering with HTTP 500 and the errors of
+// the components which failed, if any.
+func (r *reloaders) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		_ = web.EncodeJSON(w, web.NewErrorf(http.StatusMethodNotAllowed, "method %s not allowed", req.Method), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.reload(req.Context()); err != nil {
+		_ = web.EncodeJSON(w, web.NewErrorf(http.StatusInternalServerError, "reload failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	_ = web.EncodeJSON(w, map[string]string{"status": "reloaded"}, http.StatusOK)
+}
+
+// RegisterReloader adds a component of the application, such as feature flags
+// or a config file, which is re-evaluated by calling fn while the application
+// runs, every time SIGHUP is received by the running process or a POST request
+// is made to the /debug/reload endpoint:
+//
+//	application.RegisterReloader("feature flags", func(ctx context.Context) error {
+//		return flags.Load("/etc/app/flags.json")
+//	})
+//
+// Components are reloaded in registration order, one reload at a time. Failures
+// are logged, and every reload is recorded in the toolkit.app.reload metric,
+// tagged by component and status.
+func (a *Application) RegisterReloader(name string, fn func(ctx context.Context) error) {
+	a.reloaders.add(name, fn)
+}
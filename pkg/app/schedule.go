@@ -0,0 +1,211 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/luizaranda/go-core/pkg/log"
+	"github.com/luizaranda/go-core/pkg/telemetry"
+	"github.com/robfig/cron/v3"
+)
+
+// OverlapPolicy defines what happens when a scheduled job is due while its
+// previous run is still running.
+type OverlapPolicy int
+
+const (
+	// OverlapSkip skips the runs due while the previous one is running, which
+	// is the default.
+	OverlapSkip OverlapPolicy = iota
+	// OverlapQueue starts the run due while the previous one is running right
+	// after it finishes. Further runs due meanwhile are skipped.
+	OverlapQueue
+)
+
+// ScheduleOption configures a job scheduled with Schedule.
+type ScheduleOption func(*scheduledJob)
+
+// WithOverlapPolicy sets what happens when the job is due while its previous run
+// is still running. Default is OverlapSkip.
+func WithOverlapPolicy(policy OverlapPolicy) ScheduleOption {
+	return func(j *scheduledJob) {
+		j.overlap = policy
+	}
+}
+
+// WithJitter delays every run of the job by a random duration up to max, so
+// that the instances of the application don't run it at the same time.
+func WithJitter(max time.Duration) ScheduleOption {
+	return func(j *scheduledJob) {
+		j.jitter = max
+	}
+}
+
+type scheduledJob struct {
+	name     string
+	schedule cron.Schedule
+	fn       func(ctx context.Context) error
+	overlap  OverlapPolicy
+	jitter   time.Duration
+
+	running atomic.Bool
+}
+
+// scheduler runs the jobs scheduled on an application while it runs.
+type scheduler struct {
+	mu   sync.Mutex // guards jobs
+	jobs []*scheduledJob
+}
+
+func (s *scheduler) add(name, spec string, fn func(ctx context.Context) error, opts []ScheduleOption) {
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		panic(fmt.Sprintf("app: invalid schedule %q of job %s: %v", spec, name, err))
+	}
+
+	job := &scheduledJob{name: name, schedule: schedule, fn: fn}
+	for _, opt := range opts {
+		opt(job)
+	}
+
+	s.mu.Lock()
+	s.jobs = append(s.jobs, job)
+	s.mu.Unlock()
+}
+
+// start runs the scheduled jobs until ctx is done. The returned function waits
+// for the runs in progress to finish.
+func (s *scheduler) start(ctx context.Context, tracer telemetry.Client, logger log.Logger) (wait func()) {
+	s.mu.Lock()
+	jobs := s.jobs
+	s.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			job.loop(ctx, tracer, logger)
+		}()
+	}
+
+	return wg.Wait
+}
+
+// loop triggers the runs of the job when due until ctx is done.
+func (j *scheduledJob) loop(ctx context.Context, tracer telemetry.Client, logger log.Logger) {
+	// Triggers buffered while a run is in progress are queued runs.
+	triggers := make(chan struct{}, 1)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range triggers {
+			j.run(ctx, tracer, logger)
+		}
+	}()
+
+	defer func() {
+		close(triggers)
+		wg.Wait()
+	}()
+
+	for {
+		delay := time.Until(j.schedule.Next(time.Now()))
+		if j.jitter > 0 {
+			delay += rand.N(j.jitter)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if j.overlap == OverlapSkip && j.running.Load() {
+			j.skip(tracer, logger)
+			continue
+		}
+
+		select {
+		case triggers <- struct{}{}:
+		default:
+			j.skip(tracer, logger)
+		}
+	}
+}
+
+func (j *scheduledJob) skip(tracer telemetry.Client, logger log.Logger) {
+	logger.Warn("skipping scheduled job, previous run in progress", log.String("job", j.name))
+	tracer.Incr("toolkit.app.job.skipped", []string{"job:" + telemetry.SanitizeMetricTagValue(j.name)})
+}
+
+func (j *scheduledJob) run(ctx context.Context, tracer telemetry.Client, logger log.Logger) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	j.running.Store(true)
+	defer j.running.Store(false)
+
+	ctx, span := telemetry.StartAsyncSpan(ctx, "job "+j.name)
+	defer span.Finish()
+
+	start := time.Now()
+	err := j.call(ctx)
+	duration := time.Since(start)
+
+	status := "success"
+	if err != nil {
+		status = "failure"
+		span.NoticeError(err)
+		logger.Error("scheduled job failed", log.String("job", j.name), log.Duration("duration", duration), log.Err(err))
+	} else {
+		logger.Debug("scheduled job finished", log.String("job", j.name), log.Duration("duration", duration))
+	}
+
+	tags := []string{"job:" + telemetry.SanitizeMetricTagValue(j.name), "status:" + status}
+	tracer.Incr("toolkit.app.job.run", tags)
+	tracer.Timing("toolkit.app.job.run.time", duration, tags)
+}
+
+// call calls the job function, turning panics into errors.
+func (j *scheduledJob) call(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return j.fn(ctx)
+}
+
+// Schedule adds a job which runs fn periodically while the application runs,
+// according to spec, a standard cron expression such as "*/5 * * * *" or a
+// descriptor such as "@hourly" or "@every 10m". Jobs must be scheduled before
+// calling Run, and are given a context canceled when the application stops,
+// which waits for the runs in progress to finish.
+//
+// Every run has its own span, has its panics recovered as errors, and is
+// recorded in the toolkit.app.job.run metrics, tagged by job and status,
+// while skipped runs are recorded in the toolkit.app.job.skipped one. Runs
+// due while the previous one is still running are skipped, unless changed
+// with WithOverlapPolicy.
+//
+// This method will panic if spec is not valid.
+func (a *Application) Schedule(name, spec string, fn func(ctx context.Context) error, opts ...ScheduleOption) {
+	a.scheduler.add(name, spec, fn, opts)
+}
+
+// Schedule adds a job which runs fn periodically while the application runs.
+// See Application.Schedule.
+func (a *WorkerApplication) Schedule(name, spec string, fn func(ctx context.Context) error, opts ...ScheduleOption) {
+	a.scheduler.add(name, spec, fn, opts)
+}
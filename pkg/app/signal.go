@@ -0,0 +1,83 @@
+package app
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+
+	"github.com/luizaranda/go-core/pkg/log"
+)
+
+// _defaultShutdownSignals are the signals which stop an application, unless set
+// with WithShutdownSignals.
+var _defaultShutdownSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+
+// SignalHandler is a function called every time Signal is received by the
+// running process. See WithSignal.
+type SignalHandler struct {
+	Signal os.Signal
+	Handle func(ctx context.Context) error
+}
+
+// signals dispatches the signals received by the running process of an
+// application.
+type signals struct {
+	shutdown []os.Signal
+	handlers []SignalHandler
+	logger   log.Logger
+}
+
+// notifyShutdown returns a copy of ctx which is done when any of the shutdown
+// signals is received.
+func (s *signals) notifyShutdown(ctx context.Context) (context.Context, context.CancelFunc) {
+	sigs := s.shutdown
+	if len(sigs) == 0 {
+		sigs = _defaultShutdownSignals
+	}
+	return signal.NotifyContext(ctx, sigs...)
+}
+
+// listen calls the handlers of the signals received until done is closed, one
+// at a time in registration order. Their errors are logged.
+func (s *signals) listen(done <-chan struct{}) {
+	var sigs []os.Signal
+	for _, h := range s.handlers {
+		if !slices.Contains(sigs, h.Signal) {
+			sigs = append(sigs, h.Signal)
+		}
+	}
+
+	if len(sigs) == 0 {
+		return
+	}
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, sigs...)
+
+	go func() {
+		defer signal.Stop(c)
+
+		for {
+			select {
+			case sig := <-c:
+				s.handle(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+func (s *signals) handle(sig os.Signal) {
+	for _, h := range s.handlers {
+		if h.Signal != sig {
+			continue
+		}
+
+		if err := h.Handle(context.Background()); err != nil {
+			s.logger.Error("signal handler failed", log.String("signal", sig.String()), log.Err(err))
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/luizaranda/go-core/pkg/log"
+)
+
+// _defaultWarmupTimeout is the time the warmup function is given to return,
+// unless set with WithWarmup.
+const _defaultWarmupTimeout = time.Minute
+
+var errWarmingUp = errors.New("warming up")
+
+// warmup gates the readiness of an application until its warmup function
+// returns.
+type warmup struct {
+	fn      func(ctx context.Context) error
+	timeout time.Duration
+	done    atomic.Bool
+}
+
+// check is the readiness check of the warmup, which fails until it's done.
+func (w *warmup) check(context.Context) error {
+	if !w.done.Load() {
+		return errWarmingUp
+	}
+	return nil
+}
+
+// run runs the warmup function with the warmup timeout, marking the warmup as
+// done if it succeeds. It returns once ctx is done or the timeout expires, even
+// if the warmup function doesn't honor its context.
+func (w *warmup) run(ctx context.Context, logger log.Logger) error {
+	timeout := w.timeout
+	if timeout <= 0 {
+		timeout = _defaultWarmupTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- w.fn(ctx)
+	}()
+
+	select {
+	case err := <-errs:
+		if err != nil {
+			return fmt.Errorf("warmup: %w", err)
+		}
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("warmup did not return within %s: %w", timeout, ctx.Err())
+		}
+		return fmt.Errorf("warmup: %w", ctx.Err())
+	}
+
+	w.done.Store(true)
+	logger.Info("warmed up", log.Duration("duration", time.Since(start)))
+	return nil
+}
@@ -0,0 +1,152 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/luizaranda/go-core/pkg/internal/infra"
+	"github.com/luizaranda/go-core/pkg/log"
+	"github.com/luizaranda/go-core/pkg/otel"
+	"github.com/luizaranda/go-core/pkg/telemetry"
+)
+
+// _defaultWorkerShutdownTimeout is the time a WorkerApplication gives its
+// function to return once stopped, unless set with WithTimeouts.
+const _defaultWorkerShutdownTimeout = 5 * time.Second
+
+// WorkerApplication is a container struct that contains all required base
+// components for building applications without an HTTP router, such as daemons
+// and message consumers.
+type WorkerApplication struct {
+	Scope  Scope
+	Tracer telemetry.Client
+	Logger log.Logger
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	scheduler        scheduler
+	consumers        consumers
+	lifecycle        lifecycle
+	signals          *signals
+	migrations       *migrations
+	gauges           infra.Gauges
+	shutdownTimeout  time.Duration
+	otelShutdownFunc otel.ShutdownFunc
+}
+
+// NewWorkerApplication instantiates a WorkerApplication using the given
+// configuration, with the same scope, logger, telemetry and OpenTelemetry
+// setup as NewWebApplication. Options which only apply to the web server are
+// ignored, while the ShutdownTimeout given with WithTimeouts bounds how long
+// Run waits for its function to return.
+func NewWorkerApplication(opts ...AppOptFunc) (*WorkerApplication, error) {
+	var config Config
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	shutdownTimeout := config.ServerTimeouts.ShutdownTimeout
+	if shutdownTimeout == 0 {
+		shutdownTimeout = _defaultWorkerShutdownTimeout
+	}
+
+	rt, err := bootstrap(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set telemetry and logger package level defaults, as web applications do.
+	log.DefaultLogger = rt.logger
+	telemetry.DefaultTracer = rt.tracer
+
+	// Context that will be canceled when calling Shutdown.
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &WorkerApplication{
+		Scope:  Scope(rt.scope),
+		Tracer: rt.tracer,
+		Logger: rt.logger,
+
+		ctx:              ctx,
+		cancel:           cancel,
+		shutdownTimeout:  shutdownTimeout,
+		lifecycle:        lifecycle{timeout: config.HookTimeout},
+		signals:          &signals{shutdown: config.ShutdownSignals, handlers: config.SignalHandlers, logger: rt.logger},
+		migrations:       newMigrations(config.Migrations, config.MigrationOptions),
+		otelShutdownFunc: rt.otelShutdownFunc,
+	}, nil
+}
+
+// Run runs fn, which should block doing the work of the application until the
+// given context is done, which happens when SIGTERM or SIGINT, unless set with
+// WithShutdownSignals, is received by the running process or Shutdown is
+// called, whichever happens first. The jobs
+// added with Schedule and the consumers added with RegisterConsumer run
+// meanwhile, and the OnStart and OnStop hooks are called before and after.
+// Applications which only run scheduled jobs or consumers can give a nil fn.
+//
+// It returns the error returned by fn, or an error if fn does not return
+// within the shutdown timeout once the context is done. Returning the context
+// error is not considered an error. The jobs and consumers are then waited
+// for, consumers draining the messages being handled within their drain
+// timeout, before the OnStop hooks and closers are called.
+//
+//	err := worker.Run(func(ctx context.Context) error {
+//		return consumer.Consume(ctx)
+//	})
+func (a *WorkerApplication) Run(fn func(ctx context.Context) error) (err error) {
+	defer func() { _ = a.otelShutdownFunc() }()
+	defer syncLogger(a.Logger)
+	// The tracer is closed last, so that the metrics of the shutdown, such as
+	// the ones of the closers, are sent.
+	defer func() {
+		err = errors.Join(err, a.lifecycle.stop(), a.lifecycle.close(a.Tracer), a.Tracer.Close())
+	}()
+
+	if err := a.migrations.run(a.ctx, a.Tracer, a.Logger); err != nil {
+		return err
+	}
+
+	if err := a.lifecycle.start(a.ctx); err != nil {
+		return err
+	}
+
+	stopCtx, stopNotify := a.signals.notifyShutdown(a.ctx)
+	defer stopNotify()
+
+	done := make(chan struct{})
+	defer close(done)
+	a.signals.listen(done)
+
+	// Gauges are published until the application stops, its shutdown included.
+	pollCtx, stopPolling := context.WithCancel(context.Background())
+	defer stopPolling()
+	go infra.PollExportedVars(pollCtx, a.Tracer, &a.gauges)
+
+	// Jobs and consumers are waited for before the deferred OnStop hooks and
+	// closers run, so that they don't release resources still in use.
+	jobsCtx, stopJobs := context.WithCancel(stopCtx)
+	waitJobs := a.scheduler.start(jobsCtx, a.Tracer, a.Logger)
+	waitConsumers := a.consumers.start(jobsCtx, a.Logger)
+	defer func() {
+		stopJobs()
+		waitJobs()
+		waitConsumers()
+	}()
+
+	return infra.RunWorker(stopCtx, a.Logger, a.shutdownTimeout, func(ctx context.Context) error {
+		if fn == nil {
+			<-ctx.Done()
+			return nil
+		}
+		return fn(ctx)
+	})
+}
+
+// Shutdown stops the application, canceling the context given to the function
+// passed to Run.
+func (a *WorkerApplication) Shutdown() {
+	a.cancel()
+}
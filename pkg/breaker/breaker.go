@@ -0,0 +1,354 @@
+/*
+Package breaker provides a circuit breaker implementation which can be used by
+the httpclient package through the httpclient.WithCircuitBreaker option.
+*/
+package breaker
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/luizaranda/go-core/pkg/telemetry"
+)
+
+const (
+	_stateMetric       = "toolkit.circuit_breaker.state"
+	_stateChangeMetric = "toolkit.circuit_breaker.state_change"
+)
+
+// State is the state of a circuit.
+type State int
+
+const (
+	// Closed circuits allow every call.
+	Closed State = iota
+
+	// Open circuits reject every call until Config.OpenTimeout elapses.
+	Open
+
+	// HalfOpen circuits allow a limited number of probe calls, which decide
+	// whether the circuit is closed or opened again.
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half_open"
+	default:
+		return "unknown"
+	}
+}
+
+// Config contains the attributes required by New for building a
+// BucketBreaker. Zero values are replaced by their defaults.
+type Config struct {
+	// Name identifies the breaker in metrics.
+	Name string
+
+	// Window is the period of time over which calls are accounted for when
+	// computing failure and slow call rates. Default is 10 seconds.
+	Window time.Duration
+
+	// WindowBuckets is the number of buckets the window is split into. Calls
+	// leave the window one bucket at a time. Default is 10.
+	WindowBuckets int
+
+	// MinCalls is the minimum number of calls within the window required to
+	// open a circuit. Default is 20.
+	MinCalls int
+
+	// FailureRateThreshold is the failure rate, between 0 and 1, at which a
+	// circuit is opened. Default is 0.5.
+	FailureRateThreshold float64
+
+	// SlowCallDuration is the duration above which a call is considered slow.
+	// Zero disables slow call tracking.
+	SlowCallDuration time.Duration
+
+	// SlowCallRateThreshold is the slow call rate, between 0 and 1, at which a
+	// circuit is opened. Zero disables opening circuits because of slow calls.
+	SlowCallRateThreshold float64
+
+	// OpenTimeout is the time an open circuit waits before becoming half-open.
+	// Default is 30 seconds.
+	OpenTimeout time.Duration
+
+	// HalfOpenCalls is the number of probe calls allowed while a circuit is
+	// half-open. Once all of them finish the circuit is closed, unless their
+	// failure or slow call rate exceed the thresholds, in which case it is
+	// opened again. Default is 5.
+	HalfOpenCalls int
+
+	// Telemetry is the client used for recording metrics. Default is
+	// telemetry.DefaultTracer.
+	Telemetry telemetry.Client
+}
+
+// BucketBreaker is a circuit breaker which keeps an independent circuit per
+// bucket. It implements the transport.CircuitBreaker interface, so it can be
+// given to httpclient.WithCircuitBreaker.
+//
+// Each circuit accounts for the calls made in a sliding window. When the number
+// of calls reaches MinCalls and either the failure or the slow call rate
+// reach their threshold, the circuit is opened and calls are rejected. After
+// OpenTimeout the circuit becomes half-open and lets HalfOpenCalls calls
+// probe whether the dependency has recovered.
+//
+// Every state transition records the toolkit.circuit_breaker.state_change
+// metric, tagged with the breaker name, the bucket and the new state, and the
+// toolkit.circuit_breaker.state gauge, whose value is the new State.
+type BucketBreaker struct {
+	config   Config
+	circuits sync.Map // map[string]*circuit
+}
+
+// New returns a BucketBreaker configured with the given config.
+func New(config Config) *BucketBreaker {
+	if config.Window <= 0 {
+		config.Window = 10 * time.Second
+	}
+
+	if config.WindowBuckets <= 0 {
+		config.WindowBuckets = 10
+	}
+
+	if config.MinCalls <= 0 {
+		config.MinCalls = 20
+	}
+
+	if config.FailureRateThreshold <= 0 {
+		config.FailureRateThreshold = 0.5
+	}
+
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = 30 * time.Second
+	}
+
+	if config.HalfOpenCalls <= 0 {
+		config.HalfOpenCalls = 5
+	}
+
+	return &BucketBreaker{config: config}
+}
+
+// Allow tells whether a call for the given bucket is allowed. When allowed,
+// exactly one of success or failure must be called once the call finishes.
+func (b *BucketBreaker) Allow(bucket string) (allowed bool, success, failure func()) {
+	c := b.circuit(bucket)
+
+	now := time.Now()
+	generation, ok := c.allow(now)
+	if !ok {
+		return false, nil, nil
+	}
+
+	var done atomic.Bool
+	finish := func(failed bool) {
+		if done.CompareAndSwap(false, true) {
+			c.done(generation, now, failed)
+		}
+	}
+
+	return true, func() { finish(false) }, func() { finish(true) }
+}
+
+// State returns the current state of the circuit for the given bucket.
+func (b *BucketBreaker) State(bucket string) State {
+	c := b.circuit(bucket)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.state
+}
+
+func (b *BucketBreaker) circuit(bucket string) *circuit {
+	if c, ok := b.circuits.Load(bucket); ok {
+		return c.(*circuit)
+	}
+
+	c, _ := b.circuits.LoadOrStore(bucket, newCircuit(b, bucket))
+	return c.(*circuit)
+}
+
+func (b *BucketBreaker) telemetry() telemetry.Client {
+	if b.config.Telemetry != nil {
+		return b.config.Telemetry
+	}
+	return telemetry.DefaultTracer
+}
+
+// circuit keeps the state of a single bucket.
+type circuit struct {
+	breaker *BucketBreaker
+	bucket  string
+
+	mu         sync.Mutex // guards the fields below
+	state      State
+	generation uint64
+	openedAt   time.Time
+	window     []windowBucket
+
+	// Half-open probes accounting.
+	probes        int
+	probesDone    int
+	probeFailures int
+	probeSlow     int
+}
+
+// windowBucket accounts for the calls finished within a window slot.
+type windowBucket struct {
+	slot     int64
+	calls    int
+	failures int
+	slow     int
+}
+
+func newCircuit(b *BucketBreaker, bucket string) *circuit {
+	return &circuit{
+		breaker: b,
+		bucket:  bucket,
+		window:  make([]windowBucket, b.config.WindowBuckets),
+	}
+}
+
+// allow returns the generation of the circuit state the call was allowed in,
+// so that results of calls started in a previous state are discarded.
+func (c *circuit) allow(now time.Time) (uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.state == Open {
+		if now.Sub(c.openedAt) < c.breaker.config.OpenTimeout {
+			return 0, false
+		}
+		c.transition(HalfOpen, now)
+	}
+
+	if c.state == HalfOpen {
+		if c.probes >= c.breaker.config.HalfOpenCalls {
+			return 0, false
+		}
+		c.probes++
+	}
+
+	return c.generation, true
+}
+
+func (c *circuit) done(generation uint64, start time.Time, failed bool) {
+	config := c.breaker.config
+
+	now := time.Now()
+	slow := config.SlowCallDuration > 0 && now.Sub(start) >= config.SlowCallDuration
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	switch c.state {
+	case Closed:
+		calls, failures, slowCalls := c.record(now, failed, slow)
+		if calls >= config.MinCalls && c.exceeded(calls, failures, slowCalls) {
+			c.transition(Open, now)
+		}
+
+	case HalfOpen:
+		c.probesDone++
+		if failed {
+			c.probeFailures++
+		}
+		if slow {
+			c.probeSlow++
+		}
+
+		if c.probesDone < config.HalfOpenCalls {
+			return
+		}
+
+		if c.exceeded(c.probesDone, c.probeFailures, c.probeSlow) {
+			c.transition(Open, now)
+		} else {
+			c.transition(Closed, now)
+		}
+	}
+}
+
+// record accounts for a finished call in the sliding window, returning the
+// totals within it.
+func (c *circuit) record(now time.Time, failed, slow bool) (calls, failures, slowCalls int) {
+	slotSize := c.breaker.config.Window / time.Duration(len(c.window))
+	if slotSize <= 0 {
+		slotSize = 1
+	}
+	slot := now.UnixNano() / int64(slotSize)
+
+	b := &c.window[slot%int64(len(c.window))]
+	if b.slot != slot {
+		*b = windowBucket{slot: slot}
+	}
+
+	b.calls++
+	if failed {
+		b.failures++
+	}
+	if slow {
+		b.slow++
+	}
+
+	oldest := slot - int64(len(c.window)) + 1
+	for _, w := range c.window {
+		if w.slot >= oldest {
+			calls += w.calls
+			failures += w.failures
+			slowCalls += w.slow
+		}
+	}
+
+	return calls, failures, slowCalls
+}
+
+func (c *circuit) exceeded(calls, failures, slowCalls int) bool {
+	if calls == 0 {
+		return false
+	}
+
+	config := c.breaker.config
+	if float64(failures)/float64(calls) >= config.FailureRateThreshold {
+		return true
+	}
+
+	return config.SlowCallRateThreshold > 0 && float64(slowCalls)/float64(calls) >= config.SlowCallRateThreshold
+}
+
+// transition moves the circuit into the given state. It must be called with
+// c.mu held.
+func (c *circuit) transition(state State, now time.Time) {
+	c.state = state
+	c.generation++
+	c.probes, c.probesDone, c.probeFailures, c.probeSlow = 0, 0, 0, 0
+
+	switch state {
+	case Open:
+		c.openedAt = now
+	case Closed:
+		clear(c.window)
+	}
+
+	tags := telemetry.Tags(
+		"name", c.breaker.config.Name,
+		"bucket", telemetry.SanitizeMetricTagValue(c.bucket),
+	)
+
+	client := c.breaker.telemetry()
+	client.Incr(_stateChangeMetric, append(tags, "state:"+state.String()))
+	client.Gauge(_stateMetric, float64(state), tags)
+}
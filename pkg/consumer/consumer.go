@@ -0,0 +1,365 @@
+/*
+Package consumer provides the processing of messages consumed from brokers such
+as Kafka or SQS, with concurrency control, per-message spans, retries with
+backoff, dead lettering and graceful drain on shutdown.
+*/
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/luizaranda/go-core/pkg/log"
+	"github.com/luizaranda/go-core/pkg/telemetry"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	_messageMetric      = "toolkit.consumer.message"
+	_messageTimeMetric  = "toolkit.consumer.message.time"
+	_receiveErrorMetric = "toolkit.consumer.receive.error"
+
+	_tracerName = "github.com/luizaranda/go-core/pkg/consumer"
+
+	// _receiveErrorDelay is the time waited before receiving again after
+	// receiving failed.
+	_receiveErrorDelay = time.Second
+
+	// _cancelGracePeriod is the time the handlers are given to return once
+	// their context is canceled after draining timed out.
+	_cancelGracePeriod = time.Second
+)
+
+// Message is a message consumed from a broker.
+type Message struct {
+	// ID identifies the message in the broker.
+	ID string
+
+	// Body is the payload of the message.
+	Body []byte
+
+	// Attributes are the headers or attributes of the message.
+	Attributes map[string]string
+
+	// Attempt is the number of the current attempt at handling the message,
+	// starting at 1. Consumers of brokers which count deliveries, such as
+	// SQS, may set it to the delivery count.
+	Attempt int
+
+	// Raw is the message as received from the broker client, for handlers
+	// requiring broker specific data.
+	Raw any
+}
+
+// Consumer receives messages from a broker. Implementations wrap the client of
+// a broker, such as a Kafka consumer group or an SQS queue.
+type Consumer interface {
+	// Receive blocks until messages are available, returning them, or until
+	// ctx is done.
+	Receive(ctx context.Context) ([]*Message, error)
+
+	// Ack acknowledges that msg was handled, so that it's not delivered again.
+	Ack(ctx context.Context, msg *Message) error
+
+	// Nack tells the broker that msg couldn't be handled, so that it's
+	// delivered again, as the broker sees fit.
+	Nack(ctx context.Context, msg *Message) error
+}
+
+// Handler handles the messages received by a Consumer.
+type Handler interface {
+	Handle(ctx context.Context, msg *Message) error
+}
+
+// HandlerFunc is an adapter to allow the use of ordinary functions as Handlers.
+type HandlerFunc func(ctx context.Context, msg *Message) error
+
+// Handle calls f(ctx, msg).
+func (f HandlerFunc) Handle(ctx context.Context, msg *Message) error {
+	return f(ctx, msg)
+}
+
+// BackoffFunc returns the time to wait before retrying a message after the
+// given attempt failed. The backoffs of the httpclient package can be converted
+// to it.
+type BackoffFunc func(attempt int) time.Duration
+
+// ExponentialBackoff waits min after the first attempt, doubling the wait after
+// every attempt up to max.
+func ExponentialBackoff(min, max time.Duration) BackoffFunc {
+	return func(attempt int) time.Duration {
+		wait := float64(min) * math.Pow(2, float64(attempt-1))
+		if wait > float64(max) {
+			return max
+		}
+		return time.Duration(wait)
+	}
+}
+
+// DeadLetterFunc receives the messages which failed every attempt, along with
+// the error of the last one, in order to publish them to a dead letter queue
+// or store them for later inspection.
+type DeadLetterFunc func(ctx context.Context, msg *Message, err error) error
+
+// Config contains the attributes required by New for building a Processor.
+// Zero values are replaced by their defaults.
+type Config struct {
+	// Name identifies the processor in metrics, spans and logs.
+	Name string
+
+	// Concurrency is the maximum number of messages handled at once. Default
+	// is 1.
+	Concurrency int
+
+	// MaxAttempts is the number of times a message is handled before giving
+	// up on it. Default is 3.
+	MaxAttempts int
+
+	// Backoff is the time waited between attempts. Default is an
+	// ExponentialBackoff from 100 milliseconds to 10 seconds.
+	Backoff BackoffFunc
+
+	// DeadLetter receives the messages which failed every attempt, which are
+	// then acknowledged. If nil or failing, they are nacked instead.
+	DeadLetter DeadLetterFunc
+
+	// DrainTimeout is the time the messages being handled are given to
+	// finish once the processor is stopped. Default is 30 seconds.
+	DrainTimeout time.Duration
+
+	// Telemetry is the client used for recording metrics. Default is
+	// telemetry.DefaultTracer.
+	Telemetry telemetry.Client
+}
+
+// Processor receives messages from a Consumer and handles them with a Handler.
+//
+// Every attempt at handling a message has its own span, has its panics
+// recovered as errors, and failed attempts are retried with backoff up to
+// MaxAttempts. Messages are acknowledged once handled, and given to the
+// DeadLetter function once they fail every attempt.
+//
+// Handled messages are recorded in the toolkit.consumer.message metrics,
+// tagged with the processor name and a status which is one of success,
+// dead_letter or failure, while receive errors are recorded in the
+// toolkit.consumer.receive.error metric.
+type Processor struct {
+	config   Config
+	consumer Consumer
+	handler  Handler
+}
+
+// New returns a Processor handling the messages received by c with h,
+// configured with the given config.
+func New(c Consumer, h Handler, config Config) *Processor {
+	if config.Concurrency <= 0 {
+		config.Concurrency = 1
+	}
+
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 3
+	}
+
+	if config.Backoff == nil {
+		config.Backoff = ExponentialBackoff(100*time.Millisecond, 10*time.Second)
+	}
+
+	if config.DrainTimeout <= 0 {
+		config.DrainTimeout = 30 * time.Second
+	}
+
+	return &Processor{
+		config:   config,
+		consumer: c,
+		handler:  h,
+	}
+}
+
+// Name returns the name of the processor.
+func (p *Processor) Name() string {
+	return p.config.Name
+}
+
+// Run receives and handles messages until ctx is done. Then it stops receiving
+// and waits for the messages being handled to finish, returning an error if
+// they don't within the drain timeout, in which case their context is
+// canceled. Handlers ignoring their context are not waited for any longer
+// than a short grace period.
+func (p *Processor) Run(ctx context.Context) error {
+	// Messages are handled with a context which outlives ctx, so that they
+	// can finish while draining.
+	handleCtx, cancelHandling := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelHandling()
+
+	sem := make(chan struct{}, p.config.Concurrency)
+	var wg sync.WaitGroup
+
+	for ctx.Err() == nil {
+		msgs, err := p.consumer.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+
+			log.Error(ctx, "consumer receive failed", log.String("consumer", p.config.Name), log.Err(err))
+			p.telemetry().Incr(_receiveErrorMetric, []string{"consumer:" + telemetry.SanitizeMetricTagValue(p.config.Name)})
+
+			select {
+			case <-time.After(_receiveErrorDelay):
+			case <-ctx.Done():
+			}
+			continue
+		}
+
+		for _, msg := range msgs {
+			// Received messages are handled even if ctx is done meanwhile,
+			// since they would be delivered again otherwise.
+			sem <- struct{}{}
+			wg.Add(1)
+			go func() {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				p.process(handleCtx, msg)
+			}()
+		}
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-time.After(p.config.DrainTimeout):
+	}
+
+	cancelHandling()
+	select {
+	case <-drained:
+		return fmt.Errorf("consumer %s: messages not handled within %s", p.config.Name, p.config.DrainTimeout)
+	case <-time.After(_cancelGracePeriod):
+		return fmt.Errorf("consumer %s: handlers did not return within %s, even after being canceled", p.config.Name, p.config.DrainTimeout+_cancelGracePeriod)
+	}
+}
+
+// process handles msg until it succeeds or fails every attempt, and then
+// acknowledges it or gives it to the dead letter function.
+func (p *Processor) process(ctx context.Context, msg *Message) {
+	if msg.Attempt <= 0 {
+		msg.Attempt = 1
+	}
+
+	start := time.Now()
+
+	var err error
+	for {
+		if err = p.handle(ctx, msg); err == nil {
+			p.settle(ctx, msg, "success", p.consumer.Ack, start)
+			return
+		}
+
+		if msg.Attempt >= p.config.MaxAttempts {
+			break
+		}
+
+		log.Warn(ctx, "message handling failed, retrying", p.logFields(msg, err)...)
+
+		select {
+		case <-time.After(p.config.Backoff(msg.Attempt)):
+		case <-ctx.Done():
+			// Draining timed out, the message is given back to the broker.
+			p.settle(ctx, msg, "failure", p.consumer.Nack, start)
+			return
+		}
+		msg.Attempt++
+	}
+
+	if p.config.DeadLetter == nil {
+		log.Error(ctx, "message handling failed", p.logFields(msg, err)...)
+		p.settle(ctx, msg, "failure", p.consumer.Nack, start)
+		return
+	}
+
+	if dlErr := p.config.DeadLetter(ctx, msg, err); dlErr != nil {
+		log.Error(ctx, "message dead lettering failed", p.logFields(msg, errors.Join(err, dlErr))...)
+		p.settle(ctx, msg, "failure", p.consumer.Nack, start)
+		return
+	}
+
+	log.Error(ctx, "message handling failed, dead lettered", p.logFields(msg, err)...)
+	p.settle(ctx, msg, "dead_letter", p.consumer.Ack, start)
+}
+
+// handle makes an attempt at handling msg, within its own span.
+func (p *Processor) handle(ctx context.Context, msg *Message) (err error) {
+	ctx, otelSpan := otel.Tracer(_tracerName).Start(ctx, p.config.Name+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.consumer.name", p.config.Name),
+			attribute.String("messaging.message.id", msg.ID),
+			attribute.Int("messaging.message.attempt", msg.Attempt),
+		),
+	)
+	defer otelSpan.End()
+
+	ctx, span := p.telemetry().StartSpan(ctx, "consume "+p.config.Name)
+	defer span.Finish()
+	span.SetLabel("message_id", msg.ID)
+	span.SetLabel("attempt", msg.Attempt)
+
+	defer func() {
+		if rvr := recover(); rvr != nil {
+			err = fmt.Errorf("panic: %v", rvr)
+		}
+
+		if err != nil {
+			span.NoticeError(err)
+			otelSpan.RecordError(err)
+			otelSpan.SetStatus(codes.Error, err.Error())
+		}
+	}()
+
+	return p.handler.Handle(ctx, msg)
+}
+
+// settle acknowledges or nacks msg with fn, recording the result of handling it.
+// The message is settled even if ctx is canceled, as when draining times out,
+// so that it's given back to the broker.
+func (p *Processor) settle(ctx context.Context, msg *Message, status string, fn func(context.Context, *Message) error, start time.Time) {
+	ctx = context.WithoutCancel(ctx)
+	if err := fn(ctx, msg); err != nil {
+		log.Error(ctx, "message settling failed", p.logFields(msg, err)...)
+	}
+
+	tags := []string{"consumer:" + telemetry.SanitizeMetricTagValue(p.config.Name), "status:" + status}
+	p.telemetry().Incr(_messageMetric, tags)
+	p.telemetry().Timing(_messageTimeMetric, time.Since(start), tags)
+}
+
+func (p *Processor) logFields(msg *Message, err error) []log.Field {
+	return []log.Field{
+		log.String("consumer", p.config.Name),
+		log.String("message_id", msg.ID),
+		log.Int("attempt", msg.Attempt),
+		log.Err(err),
+	}
+}
+
+func (p *Processor) telemetry() telemetry.Client {
+	if p.config.Telemetry != nil {
+		return p.config.Telemetry
+	}
+	return telemetry.DefaultTracer
+}
@@ -0,0 +1,201 @@
+// Package health implements the liveness and readiness checks of an
+// application. Readiness depends on the checks registered for the application
+// dependencies, such as databases or downstream APIs, while liveness only
+// reports that the process is able to serve requests.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultTimeout is the time a check is given before it's considered failed.
+	DefaultTimeout = time.Second
+
+	// DefaultCacheTTL is the time the result of a check is reused for.
+	DefaultCacheTTL = 5 * time.Second
+)
+
+// Status is the status of a check or of a whole Report.
+type Status string
+
+const (
+	StatusUp   Status = "up"
+	StatusDown Status = "down"
+)
+
+// CheckFunc checks the health of a dependency, returning an error if it's
+// unhealthy. It must return once ctx is done.
+type CheckFunc func(ctx context.Context) error
+
+// CheckOption configures a registered check.
+type CheckOption func(*check)
+
+// WithTimeout sets the time the check is given before it's considered failed,
+// which is DefaultTimeout by default.
+func WithTimeout(d time.Duration) CheckOption {
+	return func(c *check) {
+		c.timeout = d
+	}
+}
+
+// WithCacheTTL sets the time the result of the check is reused for, so that
+// frequent readiness probes don't overload the dependency. It is
+// DefaultCacheTTL by default, and zero disables caching.
+func WithCacheTTL(d time.Duration) CheckOption {
+	return func(c *check) {
+		c.cacheTTL = d
+	}
+}
+
+// Result is the result of a single check.
+type Result struct {
+	Status    Status        `json:"status"`
+	Error     string        `json:"error,omitempty"`
+	Duration  time.Duration `json:"duration_ns"`
+	CheckedAt time.Time     `json:"checked_at"`
+}
+
+// Report is the result of all the checks of a Registry. Its Status is up only
+// if every check is up and the application is not draining.
+type Report struct {
+	Status   Status            `json:"status"`
+	Draining bool              `json:"draining,omitempty"`
+	Checks   map[string]Result `json:"checks,omitempty"`
+}
+
+type check struct {
+	fn       CheckFunc
+	timeout  time.Duration
+	cacheTTL time.Duration
+
+	mu     sync.Mutex // guards result, serializing executions of fn
+	result Result
+}
+
+// Registry holds the checks of an application. It is safe to use concurrently
+// by multiple goroutines.
+type Registry struct {
+	mu       sync.RWMutex // guards checks
+	checks   map[string]*check
+	draining atomic.Bool
+}
+
+// NewRegistry returns a new empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{checks: make(map[string]*check)}
+}
+
+// Register adds a check with the given name, replacing any check previously
+// registered with it.
+func (r *Registry) Register(name string, fn CheckFunc, opts ...CheckOption) {
+	c := &check{fn: fn, timeout: DefaultTimeout, cacheTTL: DefaultCacheTTL}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	r.mu.Lock()
+	r.checks[name] = c
+	r.mu.Unlock()
+}
+
+// Check runs every registered check concurrently, reusing the cached results
+// which didn't expire, and returns their Report.
+func (r *Registry) Check(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := make(map[string]*check, len(r.checks))
+	for name, c := range r.checks {
+		checks[name] = c
+	}
+	r.mu.RUnlock()
+
+	report := Report{Status: StatusUp, Checks: make(map[string]Result, len(checks))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := c.run(ctx)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = res
+			if res.Status != StatusUp {
+				report.Status = StatusDown
+			}
+		}()
+	}
+	wg.Wait()
+
+	return report
+}
+
+func (c *check) run(ctx context.Context) Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.result.CheckedAt.IsZero() && time.Since(c.result.CheckedAt) < c.cacheTTL {
+		return c.result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.fn(ctx)
+
+	c.result = Result{Status: StatusUp, Duration: time.Since(start), CheckedAt: start}
+	if err != nil {
+		c.result.Status, c.result.Error = StatusDown, err.Error()
+	}
+
+	return c.result
+}
+
+// LiveHandler returns the handler of the liveness endpoint, which always
+// reports the application as up without running any check. Dependencies being
+// down must not make the application be restarted.
+func (r *Registry) LiveHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		writeReport(w, Report{Status: StatusUp})
+	}
+}
+
+// Drain marks the application as draining, which makes the readiness endpoint
+// report it as down without running any check, so that load balancers stop
+// routing requests to it while it's shutting down.
+func (r *Registry) Drain() {
+	r.draining.Store(true)
+}
+
+// ReadyHandler returns the handler of the readiness endpoint, which runs every
+// check and writes their Report as JSON, with HTTP 200 if all of them are up
+// or HTTP 503 otherwise, or if the application is draining.
+func (r *Registry) ReadyHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if r.draining.Load() {
+			writeReport(w, Report{Status: StatusDown, Draining: true})
+			return
+		}
+		writeReport(w, r.Check(req.Context()))
+	}
+}
+
+func writeReport(w http.ResponseWriter, report Report) {
+	code := http.StatusOK
+	if report.Status != StatusUp {
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(report)
+}
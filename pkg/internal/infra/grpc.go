@@ -0,0 +1,164 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/luizaranda/go-core/pkg/log"
+	"github.com/luizaranda/go-core/pkg/telemetry"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// NewGRPCServer returns a gRPC server instrumented like the web router, with
+// interceptors providing OpenTelemetry tracing, telemetry, logging and panic
+// recovery, in that order, followed by the given options.
+func NewGRPCServer(tracer telemetry.Client, logger log.Logger, opts ...grpc.ServerOption) *grpc.Server {
+	// GetTracerProvider returns the registered global trace provider that is set using https://github.com/luizaranda/go-core/pkg/otel.
+	// Otherwise, a NoopTracerProvider is returned.
+	otelHandler := otelgrpc.NewServerHandler(
+		otelgrpc.WithTracerProvider(otel.GetTracerProvider()),
+		otelgrpc.WithMeterProvider(otel.GetMeterProvider()),
+	)
+
+	return grpc.NewServer(append([]grpc.ServerOption{
+		grpc.StatsHandler(otelHandler),
+		grpc.ChainUnaryInterceptor(
+			unaryTelemetry(tracer),
+			unaryLogger(logger),
+			unaryPanics(),
+		),
+		grpc.ChainStreamInterceptor(
+			streamTelemetry(tracer),
+			streamLogger(logger),
+			streamPanics(),
+		),
+	}, opts...)...)
+}
+
+// contextStream is a grpc.ServerStream with a decorated context.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context { return s.ctx }
+
+func unaryTelemetry(tracer telemetry.Client) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, span := tracer.StartSpan(ctx, info.FullMethod)
+		defer span.Finish()
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		recordGRPCRequest(tracer, span, info.FullMethod, err, time.Since(start))
+
+		return resp, err
+	}
+}
+
+func streamTelemetry(tracer telemetry.Client) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, span := tracer.StartSpan(ss.Context(), info.FullMethod)
+		defer span.Finish()
+
+		start := time.Now()
+		err := handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+		recordGRPCRequest(tracer, span, info.FullMethod, err, time.Since(start))
+
+		return err
+	}
+}
+
+func recordGRPCRequest(tracer telemetry.Client, span telemetry.Span, method string, err error, delta time.Duration) {
+	code := status.Code(err)
+	switch code {
+	case codes.Unknown, codes.Internal, codes.DataLoss, codes.Unavailable:
+		span.NoticeError(err)
+	}
+
+	tags := []string{
+		"code:" + code.String(),
+		"method:" + telemetry.SanitizeMetricTagValue(method),
+	}
+
+	tracer.Incr("toolkit.grpc.server.request", tags)
+	tracer.Timing("toolkit.grpc.server.request.time", delta, tags)
+}
+
+func unaryLogger(logger log.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(loggerContext(ctx, logger), req)
+	}
+}
+
+func streamLogger(logger log.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &contextStream{ServerStream: ss, ctx: loggerContext(ss.Context(), logger)})
+	}
+}
+
+// loggerContext decorates ctx with logger, honoring the same x-debug and
+// x-request-id metadata as the web.Logger middleware does with headers.
+func loggerContext(ctx context.Context, logger log.Logger) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if v := md.Get("x-debug"); len(v) > 0 && v[0] == "true" {
+		logger = logger.WithLevel(log.DebugLevel)
+	}
+
+	if v := md.Get("x-request-id"); len(v) > 0 && v[0] != "" {
+		logger = logger.With(log.String("request_id", v[0]))
+	}
+
+	return log.Context(ctx, logger)
+}
+
+func unaryPanics() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if rvr := recover(); rvr != nil {
+				err = recoverGRPCPanic(ctx, info.FullMethod, rvr)
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+func streamPanics() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if rvr := recover(); rvr != nil {
+				err = recoverGRPCPanic(ss.Context(), info.FullMethod, rvr)
+			}
+		}()
+
+		return handler(srv, ss)
+	}
+}
+
+// recoverGRPCPanic reports a panic recovered from the handler of method,
+// returning the error answered to the client.
+func recoverGRPCPanic(ctx context.Context, method string, rvr any) error {
+	err, ok := rvr.(error)
+	if !ok {
+		err = fmt.Errorf("%v", rvr)
+	}
+
+	log.Error(ctx, "panic recover", log.Err(err), log.String("method", method))
+	telemetry.Incr(ctx, "toolkit.grpc.server.panic_recovered", []string{"method:" + telemetry.SanitizeMetricTagValue(method)})
+
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(err)
+	span.SetStatus(otelcodes.Error, "panic recovered")
+
+	return status.Error(codes.Internal, "internal error")
+}
@@ -2,12 +2,13 @@ package infra
 
 import (
 	"context"
+	"errors"
 	"expvar"
+	"fmt"
 	"net"
 	"net/http"
 	"net/http/pprof"
-	"os/signal"
-	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/luizaranda/go-core/pkg/log"
@@ -22,6 +23,7 @@ type Config struct {
 	ErrorEncoder          web.ErrorEncoder
 	NotFoundHandler       http.Handler
 	HealthCheckRegisterer func(r *web.Router)
+	RouterOptions         []web.RouterOption
 
 	DisableCompression bool
 	Logger             log.Logger
@@ -34,7 +36,7 @@ type Config struct {
 
 const (
 	// Default compression level for defined response content types.
-	// The level should be one of the ones defined in the flate package.
+	// The level should be one of the ones defined in the compress/gzip package.
 	// Higher levels typically run slower but compress more.
 	_defaultCompressionLevel = 5
 )
@@ -69,7 +71,7 @@ func NewWebApplication(config Config) (*Application, error) {
 }
 
 func defaultRouter(config Config) *web.Router {
-	router := web.New()
+	router := web.New(config.RouterOptions...)
 
 	if config.NotFoundHandler != nil {
 		router.NotFound(config.NotFoundHandler.ServeHTTP)
@@ -148,30 +150,61 @@ func wrapM(m func(h http.Handler) http.Handler) web.Middleware {
 	}
 }
 
-// newCompressor returns a middleware that compresses response body of a given content type to a data format based
-// on Accept-Encoding request header. It uses the _defaultCompressionLevel.
+// newCompressor returns a middleware that compresses response bodies of the default content types using the
+// encoding preferred by the Accept-Encoding request header. Gzip uses the _defaultCompressionLevel.
 //
 // NOTE: if you don't use web.EncodeJSON to marshal the body into the writer,
 // make sure to set the Content-Type header on your response otherwise this middleware will not compress the response body.
 func newCompressor() web.Middleware {
-	c := middleware.NewCompressor(_defaultCompressionLevel)
-	return func(next http.HandlerFunc) http.HandlerFunc {
-		return c.Handler(next).ServeHTTP
-	}
+	return web.Compress(web.CompressConfig{
+		Levels: map[string]int{web.EncodingGzip: _defaultCompressionLevel},
+	})
 }
 
-func RunListener(ctx context.Context, ln net.Listener, tracer telemetry.Client, logger log.Logger, timeouts web.Timeouts, r *web.Router) error {
-	ctx, cancel := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
-	defer cancel()
-
-	go exportedVarPolling(ctx, tracer)
+func RunListener(ctx context.Context, ln net.Listener, logger log.Logger, timeouts web.Timeouts, r http.Handler, opts ...web.ServerOption) error {
+	return RunListeners(ctx, []net.Listener{ln}, logger, timeouts, r, opts...)
+}
 
-	logger.Info("running", log.String("address", ln.Addr().String()))
+// RunListeners serves r on every one of the given listeners until ctx is done.
+// Callers stopping on signals must derive ctx from them, as with
+// signal.NotifyContext, and publish the exported variables meanwhile with
+// PollExportedVars.
+func RunListeners(ctx context.Context, lns []net.Listener, logger log.Logger, timeouts web.Timeouts, r http.Handler, opts ...web.ServerOption) error {
+	for _, ln := range lns {
+		logger.Info("running", log.String("address", ln.Addr().String()))
+	}
 
-	if err := web.RunWithContext(ctx, ln, timeouts, r); err != nil && err != http.ErrServerClosed {
+	if err := web.RunListeners(ctx, lns, timeouts, r, opts...); err != nil && err != http.ErrServerClosed {
 		return err
 	}
+	return nil
+}
+
+// RunWorker runs fn with ctx. Once ctx is done, fn is given shutdownTimeout to
+// return. See RunListeners.
+func RunWorker(ctx context.Context, logger log.Logger, shutdownTimeout time.Duration, fn func(ctx context.Context) error) error {
+	logger.Info("running worker")
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- fn(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errs:
+	case <-ctx.Done():
+		select {
+		case err = <-errs:
+		case <-time.After(shutdownTimeout):
+			return fmt.Errorf("worker did not stop within %s", shutdownTimeout)
+		}
+	}
 
-	// From this point onwards we are on "clean-up" state.
-	return tracer.Close()
+	// Returning the context error is the expected way of stopping. It's also
+	// checked when fn returns as ctx is done, since select picks either case.
+	if ctx.Err() != nil && errors.Is(err, context.Canceled) {
+		err = nil
+	}
+	return err
 }
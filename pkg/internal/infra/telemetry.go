@@ -4,12 +4,54 @@ import (
 	"context"
 	"encoding/json"
 	"expvar"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/luizaranda/go-core/pkg/telemetry"
 )
 
-func exportedVarPolling(ctx context.Context, tracer telemetry.Client) {
+// Gauge is a value which is polled periodically and published as a gauge
+// metric, along with the exported variables.
+type Gauge struct {
+	Name string
+	Fn   func() float64
+	Tags []string
+}
+
+// Gauges are the gauges polled while an application runs. The zero value is
+// ready to use.
+type Gauges struct {
+	mu     sync.Mutex // guards gauges
+	gauges []Gauge
+}
+
+// Register adds gauge to the polled gauges.
+func (g *Gauges) Register(gauge Gauge) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.gauges = append(g.gauges, gauge)
+}
+
+func (g *Gauges) publish(tracer telemetry.Client) {
+	if g == nil {
+		return
+	}
+
+	g.mu.Lock()
+	gauges := slices.Clone(g.gauges)
+	g.mu.Unlock()
+
+	for _, gauge := range gauges {
+		tracer.Gauge(gauge.Name, gauge.Fn(), gauge.Tags)
+	}
+}
+
+// PollExportedVars publishes the exported variables and the given gauges every
+// 10 seconds until ctx is done. Applications poll until they stop, so that the
+// gauges are also published while they drain.
+func PollExportedVars(ctx context.Context, tracer telemetry.Client, gauges *Gauges) {
 	ticker := time.NewTicker(10 * time.Second)
 	defer ticker.Stop()
 
@@ -17,6 +59,7 @@ func exportedVarPolling(ctx context.Context, tracer telemetry.Client) {
 		select {
 		case <-ticker.C:
 			exportedVarPoolHTTP(tracer)
+			gauges.publish(tracer)
 		case <-ctx.Done():
 			return
 		}
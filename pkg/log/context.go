@@ -74,19 +74,19 @@ func Check(ctx context.Context, lvl Level, msg string) *CheckedEntry {
 // "development panic"). This is useful for catching errors that are
 // recoverable, but shouldn't ever happen.
 func DPanic(ctx context.Context, msg string, fields ...Field) {
-	getLogger(ctx).DPanic(msg, fields...)
+	getLogger(ctx).DPanic(msg, withContext(ctx, fields)...)
 }
 
 // Debug logs a message at DebugLevel. The message includes any fields passed
 // at the log site, as well as any fields accumulated on the logger.
 func Debug(ctx context.Context, msg string, fields ...Field) {
-	getLogger(ctx).Debug(msg, fields...)
+	getLogger(ctx).Debug(msg, withContext(ctx, fields)...)
 }
 
 // Error logs a message at ErrorLevel. The message includes any fields passed
 // at the log site, as well as any fields accumulated on the logger.
 func Error(ctx context.Context, msg string, fields ...Field) {
-	getLogger(ctx).Error(msg, fields...)
+	getLogger(ctx).Error(msg, withContext(ctx, fields)...)
 }
 
 // Fatal logs a message at FatalLevel. The message includes any fields passed
@@ -95,13 +95,13 @@ func Error(ctx context.Context, msg string, fields ...Field) {
 // The logger then calls os.Exit(1), even if logging at FatalLevel is
 // disabled.
 func Fatal(ctx context.Context, msg string, fields ...Field) {
-	getLogger(ctx).Fatal(msg, fields...)
+	getLogger(ctx).Fatal(msg, withContext(ctx, fields)...)
 }
 
 // Info logs a message at InfoLevel. The message includes any fields passed
 // at the log site, as well as any fields accumulated on the logger.
 func Info(ctx context.Context, msg string, fields ...Field) {
-	getLogger(ctx).Info(msg, fields...)
+	getLogger(ctx).Info(msg, withContext(ctx, fields)...)
 }
 
 // Panic logs a message at PanicLevel. The message includes any fields passed
@@ -109,13 +109,13 @@ func Info(ctx context.Context, msg string, fields ...Field) {
 //
 // The logger then panics, even if logging at PanicLevel is disabled.
 func Panic(ctx context.Context, msg string, fields ...Field) {
-	getLogger(ctx).Panic(msg, fields...)
+	getLogger(ctx).Panic(msg, withContext(ctx, fields)...)
 }
 
 // Warn logs a message at WarnLevel. The message includes any fields passed
 // at the log site, as well as any fields accumulated on the logger.
 func Warn(ctx context.Context, msg string, fields ...Field) {
-	getLogger(ctx).Warn(msg, fields...)
+	getLogger(ctx).Warn(msg, withContext(ctx, fields)...)
 }
 
 func getLogger(ctx context.Context) Logger {
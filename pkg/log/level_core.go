@@ -67,6 +67,13 @@ func wrapCoreWithLevel(l *zap.AtomicLevel) zap.Option {
 			lvl:  l,
 		}
 
+		// The level given locks the logger into it, so the level set for its
+		// name, if any, no longer applies.
+		if namedCore, ok := core.(*namedLevelCore); ok {
+			core = namedCore.Core
+			newCore.Core = core
+		}
+
 		// If core is a coreWithLevel we want to wrap the underlying core.
 		// The underlying core should be configured at Debug level.
 		lvlCore, ok := core.(*coreWithLevel)
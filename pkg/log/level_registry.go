@@ -0,0 +1,146 @@
+package log
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	// _levelsMu serializes the changes of _levels, which is replaced on every
+	// change so that it's read without locking.
+	_levelsMu sync.Mutex
+	_levels   atomic.Pointer[map[string]Level]
+)
+
+// SetLevel sets the level of the loggers with the given name, and of their
+// named children, as in:
+//
+//	log.SetLevel("pkg/transport", log.DebugLevel)
+//
+// makes the loggers named "pkg/transport", and their children named
+// "pkg/transport.http", log at Debug level, regardless of the level of the
+// logger they were created from. The level of the closest ancestor applies to
+// loggers whose name has no level set.
+func SetLevel(name string, lvl Level) {
+	updateLevels(func(levels map[string]Level) {
+		levels[name] = lvl
+	})
+}
+
+// UnsetLevel removes the level set with SetLevel for the loggers with the given
+// name, which log again at the level of the logger they were created from.
+func UnsetLevel(name string) {
+	updateLevels(func(levels map[string]Level) {
+		delete(levels, name)
+	})
+}
+
+// LevelFor returns the level set with SetLevel which applies to the loggers
+// with the given name, if any.
+func LevelFor(name string) (Level, bool) {
+	levels := _levels.Load()
+	if levels == nil || name == "" {
+		return 0, false
+	}
+
+	for {
+		if lvl, ok := (*levels)[name]; ok {
+			return lvl, true
+		}
+
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			return 0, false
+		}
+		name = name[:i]
+	}
+}
+
+// Levels returns the levels set with SetLevel, by logger name.
+func Levels() map[string]Level {
+	levels := make(map[string]Level)
+	if current := _levels.Load(); current != nil {
+		for name, lvl := range *current {
+			levels[name] = lvl
+		}
+	}
+	return levels
+}
+
+func updateLevels(fn func(levels map[string]Level)) {
+	_levelsMu.Lock()
+	defer _levelsMu.Unlock()
+
+	levels := Levels()
+	fn(levels)
+	_levels.Store(&levels)
+}
+
+// namedLevelCore wraps the zapcore.Core of a named logger so that it logs at
+// the level set for its name with SetLevel, if any, instead of the level of
+// the logger it was created from.
+type namedLevelCore struct {
+	zapcore.Core
+
+	name string
+}
+
+// Enabled returns true if the given level is enabled by the level set for the
+// name of the core, or by the wrapped core when no level is set.
+func (c *namedLevelCore) Enabled(level zapcore.Level) bool {
+	if lvl, ok := LevelFor(c.name); ok {
+		return lvl.Enabled(level) && unwrapCoreLevel(c.Core).Enabled(level)
+	}
+	return c.Core.Enabled(level)
+}
+
+// Check delegates to the wrapped core, bypassing its dynamic level when a
+// level is set for the name of the core.
+func (c *namedLevelCore) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if lvl, ok := LevelFor(c.name); ok {
+		if !lvl.Enabled(e.Level) {
+			return ce
+		}
+		return unwrapCoreLevel(c.Core).Check(e, ce)
+	}
+	return c.Core.Check(e, ce)
+}
+
+// With adds structured context to the Core, wrapping the new one again within
+// a namedLevelCore.
+func (c *namedLevelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &namedLevelCore{
+		Core: c.Core.With(fields),
+		name: c.name,
+	}
+}
+
+// wrapCoreWithName returns a zap.Option which wraps the current zap.logger
+// core within a namedLevelCore with the given name.
+func wrapCoreWithName(name string) zap.Option {
+	return zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		// If core is a namedLevelCore we want to wrap the underlying core,
+		// since the new name replaces the previous one.
+		if namedCore, ok := core.(*namedLevelCore); ok {
+			core = namedCore.Core
+		}
+
+		return &namedLevelCore{
+			Core: core,
+			name: name,
+		}
+	})
+}
+
+// unwrapCoreLevel returns the core wrapped by core if it's a coreWithLevel,
+// which is configured at Debug level, or core itself otherwise.
+func unwrapCoreLevel(core zapcore.Core) zapcore.Core {
+	if lvlCore, ok := core.(*coreWithLevel); ok {
+		return lvlCore.Core
+	}
+	return core
+}
@@ -45,7 +45,19 @@ func NewProductionLogger(lvl *AtomicLevel, opts ...Option) Logger {
 
 	zapOptions = append(zapOptions, wrapCoreWithLevel(lvl))
 
-	l := zap.New(newZapCoreAtLevel(zap.DebugLevel, cfg), zapOptions...)
+	var core zapcore.Core = &traceCore{Core: newZapCoreAtLevel(zap.DebugLevel, cfg)}
+	if cfg.otlpEndpoint != "" && isOTLPExportEnabled() {
+		// Logging goes on without exporting if the exporter can't be built.
+		if otlp, err := newOTLPCore(cfg.otlpEndpoint, cfg.redaction); err == nil {
+			core = zapcore.NewTee(core, otlp)
+		}
+	}
+
+	if len(cfg.hooks) > 0 {
+		core = zapcore.RegisterHooks(core, cfg.hooks...)
+	}
+
+	l := zap.New(core, zapOptions...)
 
 	return &logger{
 		Logger: l,
@@ -86,8 +98,11 @@ func (l *logger) With(fields ...Field) Logger {
 
 // Named adds a new path segment to the logger's name. Segments are joined by
 // periods. By default, Loggers are unnamed.
+//
+// The child logs at the level set for its name with SetLevel, if any.
 func (l *logger) Named(s string) Logger {
 	child := l.Logger.Named(s)
+	child = child.WithOptions(wrapCoreWithName(child.Name()))
 	return &logger{
 		Logger: child,
 	}
@@ -110,9 +125,14 @@ type logConfig struct {
 	caller     bool
 	callerSkip int
 	stacktrace bool
+	color      bool
 	writer     WriteSyncer
 
 	encoderFactory encoderFactory
+	console        bool
+	otlpEndpoint   string
+	redaction      *Redaction
+	hooks          []func(zapcore.Entry) error
 }
 
 // Option configures a Logger.
@@ -167,6 +187,7 @@ func WithJSONEncoding() Option {
 		s.encoderFactory = func(config zapcore.EncoderConfig) zapcore.Encoder {
 			return zapcore.NewJSONEncoder(config)
 		}
+		s.console = false
 	}
 }
 
@@ -177,6 +198,15 @@ func WithConsoleEncoding() Option {
 		s.encoderFactory = func(config zapcore.EncoderConfig) zapcore.Encoder {
 			return zapcore.NewConsoleEncoder(config)
 		}
+		s.console = true
+	}
+}
+
+// WithColor tells the logger to color the levels of the entries, as terminals
+// display them. It only applies to the console encoding.
+func WithColor() Option {
+	return func(s *logConfig) {
+		s.color = true
 	}
 }
 
@@ -188,6 +218,29 @@ func WithKeyValueEncoding(kveOption ...encoders.KeyValueEncoderOption) Option {
 		s.encoderFactory = func(config zapcore.EncoderConfig) zapcore.Encoder {
 			return encoders.NewKeyValueEncoder(config, kveOption...)
 		}
+		s.console = false
+	}
+}
+
+// WithLogfmtEncoding tells the logger to use logfmt as its encoding, as a line
+// of space separated key=value pairs.
+func WithLogfmtEncoding() Option {
+	return func(s *logConfig) {
+		s.encoderFactory = func(config zapcore.EncoderConfig) zapcore.Encoder {
+			return encoders.NewLogfmtEncoder(config)
+		}
+		s.console = false
+	}
+}
+
+// WithHook tells the logger to call hook with every entry it writes, such as
+// for counting errors or forwarding Fatal and Panic entries to an incident
+// channel. Hooks are called in the order given, after the entry is written and
+// only if its level is enabled. Their errors are reported to the standard
+// error.
+func WithHook(hook func(entry zapcore.Entry) error) Option {
+	return func(s *logConfig) {
+		s.hooks = append(s.hooks, hook)
 	}
 }
 
@@ -232,7 +285,16 @@ func newZapCoreAtLevel(lvl zapcore.Level, cfg logConfig) zapcore.Core {
 		EncodeCaller:   zapcore.ShortCallerEncoder,
 	}
 
-	return zapcore.NewCore(cfg.encoderFactory(encoderConfig), cfg.writer, lvl)
+	if cfg.color && cfg.console {
+		encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	}
+
+	encoder := cfg.encoderFactory(encoderConfig)
+	if cfg.redaction != nil {
+		encoder = &redactingEncoder{Encoder: encoder, redaction: cfg.redaction}
+	}
+
+	return zapcore.NewCore(encoder, cfg.writer, lvl)
 }
 
 // rfc3399NanoTimeEncoder serializes a time.Time to an RFC3399-formatted string
@@ -0,0 +1,223 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// The same switches of the OpenTelemetry agent honored by applications.
+	_otelAgentEnabledEnv  = "OTEL_AGENT_ENABLED"
+	_otelAgentDisabledEnv = "OTEL_AGENT_DISABLED"
+	_otelAgentHostEnv     = "OTEL_HOST"
+	_otelAgentPortEnv     = "OTEL_PORT"
+
+	_otlpScopeName = "github.com/luizaranda/go-core/pkg/log"
+)
+
+// WithOTLPExport tells the logger to also export its entries to the
+// OpenTelemetry agent listening on the given gRPC endpoint, such as
+// "otel-agent:4317", so that they land in the same backend as traces. An empty
+// endpoint means the agent set by the OTEL_HOST and OTEL_PORT environment
+// variables, as with traces.
+//
+// Entries are only exported when OTEL_AGENT_ENABLED is "true" and
+// OTEL_AGENT_DISABLED is not, and are batched, so Sync must be called on the
+// logger before the process exits. Entries logged with the context functions,
+// such as Info, are correlated with the span of the context, if any.
+func WithOTLPExport(endpoint string) Option {
+	return func(s *logConfig) {
+		if endpoint == "" {
+			endpoint = otlpAgentEndpoint()
+		}
+		s.otlpEndpoint = endpoint
+	}
+}
+
+func isOTLPExportEnabled() bool {
+	return strings.EqualFold(os.Getenv(_otelAgentEnabledEnv), "true") &&
+		!strings.EqualFold(os.Getenv(_otelAgentDisabledEnv), "true")
+}
+
+func otlpAgentEndpoint() string {
+	host := os.Getenv(_otelAgentHostEnv)
+	if host == "" {
+		host = "otel-agent"
+	}
+	port := os.Getenv(_otelAgentPortEnv)
+	if port == "" {
+		port = "4317"
+	}
+	return host + ":" + port
+}
+
+// newOTLPCore returns a core exporting entries to the OpenTelemetry agent at
+// endpoint, redacting them with redaction, if not nil.
+func newOTLPCore(endpoint string, redaction *Redaction) (zapcore.Core, error) {
+	exp, err := otlploggrpc.New(context.Background(),
+		otlploggrpc.WithEndpoint(endpoint),
+		otlploggrpc.WithInsecure(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewBatchProcessor(exp)))
+
+	return &otlpCore{
+		provider:  provider,
+		logger:    provider.Logger(_otlpScopeName),
+		redaction: redaction,
+	}, nil
+}
+
+// otlpCore is a zapcore.Core which emits entries as OpenTelemetry log records.
+type otlpCore struct {
+	provider  *sdklog.LoggerProvider
+	logger    otellog.Logger
+	fields    []zapcore.Field
+	redaction *Redaction
+}
+
+var _ zapcore.Core = (*otlpCore)(nil)
+
+// Enabled is always true, since the level is enforced by the logger core.
+func (c *otlpCore) Enabled(zapcore.Level) bool {
+	return true
+}
+
+func (c *otlpCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = append(clone.fields[:len(clone.fields):len(clone.fields)], fields...)
+	return &clone
+}
+
+func (c *otlpCore) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(e, c)
+}
+
+func (c *otlpCore) Write(e zapcore.Entry, fields []zapcore.Field) error {
+	ctx := context.Background()
+
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range append(c.fields[:len(c.fields):len(c.fields)], fields...) {
+		if fieldCtx, ok := contextOf(f); ok {
+			ctx = fieldCtx
+			continue
+		}
+		if c.redaction != nil {
+			f = c.redaction.redactField(f)
+		}
+		f.AddTo(enc)
+	}
+
+	msg := e.Message
+	if c.redaction != nil {
+		msg = c.redaction.redactString(msg)
+	}
+
+	var r otellog.Record
+	r.SetTimestamp(e.Time)
+	r.SetObservedTimestamp(time.Now())
+	r.SetSeverity(otlpSeverity(e.Level))
+	r.SetSeverityText(e.Level.CapitalString())
+	r.SetBody(otellog.StringValue(msg))
+
+	attrs := make([]otellog.KeyValue, 0, len(enc.Fields)+2)
+	if e.LoggerName != "" {
+		attrs = append(attrs, otellog.String("logger", e.LoggerName))
+	}
+	if e.Caller.Defined {
+		attrs = append(attrs, otellog.String("caller", e.Caller.TrimmedPath()))
+	}
+	for k, v := range enc.Fields {
+		attrs = append(attrs, otellog.KeyValue{Key: k, Value: otlpValue(v)})
+	}
+	r.AddAttributes(attrs...)
+
+	c.logger.Emit(ctx, r)
+	return nil
+}
+
+// Sync flushes the batched records.
+func (c *otlpCore) Sync() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return c.provider.ForceFlush(ctx)
+}
+
+func otlpSeverity(l zapcore.Level) otellog.Severity {
+	switch l {
+	case zapcore.DebugLevel:
+		return otellog.SeverityDebug
+	case zapcore.InfoLevel:
+		return otellog.SeverityInfo
+	case zapcore.WarnLevel:
+		return otellog.SeverityWarn
+	case zapcore.ErrorLevel:
+		return otellog.SeverityError
+	case zapcore.DPanicLevel, zapcore.PanicLevel:
+		return otellog.SeverityFatal1
+	case zapcore.FatalLevel:
+		return otellog.SeverityFatal4
+	default:
+		return otellog.SeverityUndefined
+	}
+}
+
+// otlpValue converts a value of a zapcore.MapObjectEncoder to a log value.
+func otlpValue(v any) otellog.Value {
+	switch v := v.(type) {
+	case string:
+		return otellog.StringValue(v)
+	case bool:
+		return otellog.BoolValue(v)
+	case int:
+		return otellog.IntValue(v)
+	case int64:
+		return otellog.Int64Value(v)
+	case int32:
+		return otellog.Int64Value(int64(v))
+	case uint64:
+		if v > math.MaxInt64 {
+			return otellog.StringValue(fmt.Sprint(v))
+		}
+		return otellog.Int64Value(int64(v))
+	case uint32:
+		return otellog.Int64Value(int64(v))
+	case float64:
+		return otellog.Float64Value(v)
+	case float32:
+		return otellog.Float64Value(float64(v))
+	case time.Duration:
+		return otellog.StringValue(v.String())
+	case time.Time:
+		return otellog.StringValue(v.Format(time.RFC3339Nano))
+	case []byte:
+		return otellog.BytesValue(v)
+	case map[string]any:
+		kvs := make([]otellog.KeyValue, 0, len(v))
+		for k, item := range v {
+			kvs = append(kvs, otellog.KeyValue{Key: k, Value: otlpValue(item)})
+		}
+		return otellog.MapValue(kvs...)
+	case []any:
+		values := make([]otellog.Value, 0, len(v))
+		for _, item := range v {
+			values = append(values, otlpValue(item))
+		}
+		return otellog.SliceValue(values...)
+	default:
+		return otellog.StringValue(fmt.Sprint(v))
+	}
+}
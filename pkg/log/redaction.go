@@ -0,0 +1,335 @@
+package log
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// _redacted replaces the redacted values.
+const _redacted = "[REDACTED]"
+
+// Patterns of sensitive values, to be used in Redaction.Values.
+var (
+	// CreditCardPattern matches credit card numbers, of 13 to 19 digits
+	// optionally separated by spaces or dashes. When redacting, the matches
+	// which fail the Luhn checksum are kept, so that other numbers such as
+	// timestamps or ids are not redacted.
+	CreditCardPattern = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+
+	// EmailPattern matches email addresses.
+	EmailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+	// TokenPattern matches bearer tokens, as found in Authorization headers.
+	TokenPattern = regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]+=*`)
+)
+
+// Redaction configures the redaction of sensitive data from the entries of a
+// logger. See WithRedaction.
+type Redaction struct {
+	// Keys are the patterns of the keys of the fields whose values are
+	// redacted entirely, such as "password" or "*token*", matched case
+	// insensitively with the syntax of path.Match.
+	Keys []string
+
+	// Values are the patterns of the sensitive parts of string values, such
+	// as CreditCardPattern, which are redacted from the messages and the
+	// string and error fields of the entries.
+	Values []*regexp.Regexp
+}
+
+// WithRedaction tells the logger to redact sensitive data from its entries, in
+// the encoder before they are serialized:
+//
+//	log.WithRedaction(log.Redaction{
+//		Keys:   []string{"password", "*token*", "authorization"},
+//		Values: []*regexp.Regexp{log.CreditCardPattern, log.EmailPattern, log.TokenPattern},
+//	})
+//
+// Only the top level fields are redacted, not the ones of objects and arrays
+// marshaled by them. This option will panic if any key pattern is malformed.
+func WithRedaction(r Redaction) Option {
+	keys := make([]string, len(r.Keys))
+	for i, key := range r.Keys {
+		keys[i] = strings.ToLower(key)
+		if _, err := path.Match(keys[i], ""); err != nil {
+			panic(fmt.Sprintf("log: invalid redaction key pattern %q: %v", key, err))
+		}
+	}
+
+	return func(s *logConfig) {
+		s.redaction = &Redaction{Keys: keys, Values: r.Values}
+	}
+}
+
+// Secret constructs a field whose value is always redacted, regardless of the
+// redaction of the logger, for values known to be sensitive.
+func Secret(key string, _ string) Field {
+	return zap.String(key, _redacted)
+}
+
+// redactsKey reports whether the value of the fields with the given key is
+// redacted entirely.
+func (r *Redaction) redactsKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, pattern := range r.Keys {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// redactString redacts the sensitive parts of s.
+func (r *Redaction) redactString(s string) string {
+	for _, re := range r.Values {
+		if re == CreditCardPattern {
+			s = re.ReplaceAllStringFunc(s, redactCardNumber)
+			continue
+		}
+		s = re.ReplaceAllLiteralString(s, _redacted)
+	}
+	return s
+}
+
+// redactCardNumber redacts s if its digits pass the Luhn checksum.
+func redactCardNumber(s string) string {
+	var sum int
+	double := false
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] < '0' || s[i] > '9' {
+			continue
+		}
+
+		d := int(s[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+
+	if sum%10 != 0 {
+		return s
+	}
+	return _redacted
+}
+
+// redactField returns f with its sensitive data redacted.
+func (r *Redaction) redactField(f zapcore.Field) zapcore.Field {
+	if f.Type == zapcore.SkipType {
+		return f
+	}
+
+	if r.redactsKey(f.Key) {
+		return zap.String(f.Key, _redacted)
+	}
+
+	switch f.Type {
+	case zapcore.StringType:
+		f.String = r.redactString(f.String)
+	case zapcore.ByteStringType:
+		if b, ok := f.Interface.([]byte); ok {
+			return zap.String(f.Key, r.redactString(string(b)))
+		}
+	case zapcore.StringerType:
+		if s, ok := f.Interface.(fmt.Stringer); ok {
+			return zap.String(f.Key, r.redactString(s.String()))
+		}
+	case zapcore.ErrorType:
+		if err, ok := f.Interface.(error); ok {
+			if msg := err.Error(); msg != r.redactString(msg) {
+				return zap.String(f.Key, r.redactString(msg))
+			}
+		}
+	}
+	return f
+}
+
+// redactingEncoder is a zapcore.Encoder which redacts sensitive data from the
+// entries and the fields added to it before serializing them.
+type redactingEncoder struct {
+	zapcore.Encoder
+	redaction *Redaction
+}
+
+func (e *redactingEncoder) Clone() zapcore.Encoder {
+	return &redactingEncoder{Encoder: e.Encoder.Clone(), redaction: e.redaction}
+}
+
+func (e *redactingEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	ent.Message = e.redaction.redactString(ent.Message)
+
+	redacted := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		redacted[i] = e.redaction.redactField(f)
+	}
+	return e.Encoder.EncodeEntry(ent, redacted)
+}
+
+// The methods below redact the fields added to the encoder through loggers
+// With method.
+
+// addRedacted adds the field with the given key as redacted, reporting whether
+// its value is redacted entirely.
+func (e *redactingEncoder) addRedacted(key string) bool {
+	if !e.redaction.redactsKey(key) {
+		return false
+	}
+	e.Encoder.AddString(key, _redacted)
+	return true
+}
+
+func (e *redactingEncoder) AddString(key, value string) {
+	if !e.addRedacted(key) {
+		e.Encoder.AddString(key, e.redaction.redactString(value))
+	}
+}
+
+func (e *redactingEncoder) AddByteString(key string, value []byte) {
+	e.AddString(key, string(value))
+}
+
+func (e *redactingEncoder) AddBinary(key string, value []byte) {
+	if !e.addRedacted(key) {
+		e.Encoder.AddBinary(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddBool(key string, value bool) {
+	if !e.addRedacted(key) {
+		e.Encoder.AddBool(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddComplex128(key string, value complex128) {
+	if !e.addRedacted(key) {
+		e.Encoder.AddComplex128(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddComplex64(key string, value complex64) {
+	if !e.addRedacted(key) {
+		e.Encoder.AddComplex64(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddDuration(key string, value time.Duration) {
+	if !e.addRedacted(key) {
+		e.Encoder.AddDuration(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddFloat64(key string, value float64) {
+	if !e.addRedacted(key) {
+		e.Encoder.AddFloat64(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddFloat32(key string, value float32) {
+	if !e.addRedacted(key) {
+		e.Encoder.AddFloat32(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddInt(key string, value int) {
+	if !e.addRedacted(key) {
+		e.Encoder.AddInt(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddInt64(key string, value int64) {
+	if !e.addRedacted(key) {
+		e.Encoder.AddInt64(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddInt32(key string, value int32) {
+	if !e.addRedacted(key) {
+		e.Encoder.AddInt32(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddInt16(key string, value int16) {
+	if !e.addRedacted(key) {
+		e.Encoder.AddInt16(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddInt8(key string, value int8) {
+	if !e.addRedacted(key) {
+		e.Encoder.AddInt8(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddTime(key string, value time.Time) {
+	if !e.addRedacted(key) {
+		e.Encoder.AddTime(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddUint(key string, value uint) {
+	if !e.addRedacted(key) {
+		e.Encoder.AddUint(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddUint64(key string, value uint64) {
+	if !e.addRedacted(key) {
+		e.Encoder.AddUint64(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddUint32(key string, value uint32) {
+	if !e.addRedacted(key) {
+		e.Encoder.AddUint32(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddUint16(key string, value uint16) {
+	if !e.addRedacted(key) {
+		e.Encoder.AddUint16(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddUint8(key string, value uint8) {
+	if !e.addRedacted(key) {
+		e.Encoder.AddUint8(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddUintptr(key string, value uintptr) {
+	if !e.addRedacted(key) {
+		e.Encoder.AddUintptr(key, value)
+	}
+}
+
+func (e *redactingEncoder) AddReflected(key string, value any) error {
+	if e.addRedacted(key) {
+		return nil
+	}
+	return e.Encoder.AddReflected(key, value)
+}
+
+func (e *redactingEncoder) AddObject(key string, value zapcore.ObjectMarshaler) error {
+	if e.addRedacted(key) {
+		return nil
+	}
+	return e.Encoder.AddObject(key, value)
+}
+
+func (e *redactingEncoder) AddArray(key string, value zapcore.ArrayMarshaler) error {
+	if e.addRedacted(key) {
+		return nil
+	}
+	return e.Encoder.AddArray(key, value)
+}
@@ -0,0 +1,89 @@
+package log
+
+import (
+	"context"
+
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// traceCore wraps a zapcore.Core and appends the trace.id and span.id fields to
+// the entries logged with the context functions, such as Info, when their
+// context carries an OpenTelemetry span or a NewRelic transaction, so that
+// logs can be correlated with traces.
+type traceCore struct {
+	zapcore.Core
+}
+
+var _ zapcore.Core = (*traceCore)(nil)
+
+func (c *traceCore) With(fields []zapcore.Field) zapcore.Core {
+	return &traceCore{Core: c.Core.With(fields)}
+}
+
+func (c *traceCore) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(e.Level) {
+		return ce.AddCore(e, c)
+	}
+	return ce
+}
+
+func (c *traceCore) Write(e zapcore.Entry, fields []zapcore.Field) error {
+	for _, f := range fields {
+		if ctx, ok := contextOf(f); ok {
+			fields = append(fields[:len(fields):len(fields)], traceFields(ctx)...)
+			break
+		}
+	}
+	return c.Core.Write(e, fields)
+}
+
+// traceFields returns the trace.id and span.id fields of the span or the
+// transaction carried by ctx, the OpenTelemetry span taking precedence.
+func traceFields(ctx context.Context) []Field {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		return []Field{
+			zap.String("trace.id", sc.TraceID().String()),
+			zap.String("span.id", sc.SpanID().String()),
+		}
+	}
+
+	if txn := newrelic.FromContext(ctx); txn != nil {
+		md := txn.GetTraceMetadata()
+		if md.TraceID == "" {
+			return nil
+		}
+		return []Field{
+			zap.String("trace.id", md.TraceID),
+			zap.String("span.id", md.SpanID),
+		}
+	}
+	return nil
+}
+
+// contextField returns a field carrying ctx, which encoders skip, so that the
+// cores requiring it can take it from the entry. It's only added by the context
+// functions when ctx holds a span or a transaction.
+func contextField(ctx context.Context) Field {
+	return zap.Field{Type: zapcore.SkipType, Interface: ctx}
+}
+
+// withContext returns fields along with a context field for ctx, if it carries
+// a span or a transaction.
+func withContext(ctx context.Context, fields []Field) []Field {
+	if !trace.SpanContextFromContext(ctx).IsValid() && newrelic.FromContext(ctx) == nil {
+		return fields
+	}
+	return append(fields[:len(fields):len(fields)], contextField(ctx))
+}
+
+// contextOf returns the context carried by f, if it's a context field.
+func contextOf(f zapcore.Field) (context.Context, bool) {
+	if f.Type != zapcore.SkipType {
+		return nil, false
+	}
+	ctx, ok := f.Interface.(context.Context)
+	return ctx, ok
+}
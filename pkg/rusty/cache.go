@@ -0,0 +1,148 @@
+package rusty
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/luizaranda/go-core/pkg/transport"
+)
+
+// _cacheKeyPrefix keeps the keys of rusty cached responses apart from the
+// ones of the HTTP caching of the transport package, which may share the same
+// cache.
+const _cacheKeyPrefix = "rusty:"
+
+type cacheOptions struct {
+	Cache transport.Cache
+	TTL   time.Duration
+}
+
+// WithCache makes the endpoint cache successful responses to get requests,
+// keyed by their expanded URL, for the given ttl regardless of the response
+// caching headers. It's meant for read-mostly reference data endpoints.
+//
+// Concurrent requests to the same URL missing the cache are executed only
+// once, the rest of them waiting for and sharing its outcome. Responses are
+// successful when the error policy returns no error and their status code is
+// 2xx, and downloads are never cached. Interceptors given with
+// WithInterceptor are called for cached responses as well.
+func WithCache(cache transport.Cache, ttl time.Duration) EndpointOption {
+	return endpointOptionFunc(func(options *endpointOptions) {
+		options.Cache = &cacheOptions{Cache: cache, TTL: ttl}
+	})
+}
+
+// cachedResponse is a Response as stored in the cache.
+type cachedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	Expires    time.Time   `json:"expires"`
+}
+
+// cachingInvoker returns an Invoker which caches the responses returned by
+// next as described by WithCache.
+func cachingInvoker(options *cacheOptions, next Invoker) Invoker {
+	var group flightGroup
+
+	return func(ctx context.Context, req *http.Request) (*Response, error) {
+		if req.Method != http.MethodGet || downloadFromContext(ctx) != nil {
+			return next(ctx, req)
+		}
+
+		key := _cacheKeyPrefix + req.URL.String()
+		if res, ok := getCachedResponse(options.Cache, key, req); ok {
+			return res, nil
+		}
+
+		res, err := group.do(key, func() (*Response, error) {
+			res, err := next(ctx, req)
+			if err == nil && res.StatusCode >= 200 && res.StatusCode < 300 {
+				setCachedResponse(options.Cache, key, res, options.TTL)
+			}
+			return res, err
+		})
+		if res != nil {
+			// Waiters share the response, so each of them gets its own copy.
+			res2 := *res
+			res = &res2
+		}
+
+		return res, err
+	}
+}
+
+func getCachedResponse(cache transport.Cache, key string, req *http.Request) (*Response, bool) {
+	b, ok := cache.Get(key)
+	if !ok {
+		return nil, false
+	}
+
+	var cached cachedResponse
+	if err := json.Unmarshal(b, &cached); err != nil || time.Now().After(cached.Expires) {
+		return nil, false
+	}
+
+	return &Response{
+		Body:       cached.Body,
+		StatusCode: cached.StatusCode,
+		Header:     cached.Header,
+		Request:    req,
+	}, true
+}
+
+func setCachedResponse(cache transport.Cache, key string, res *Response, ttl time.Duration) {
+	b, err := json.Marshal(cachedResponse{
+		StatusCode: res.StatusCode,
+		Header:     res.Header,
+		Body:       res.Body,
+		Expires:    time.Now().Add(ttl),
+	})
+	if err != nil {
+		return
+	}
+
+	cache.Set(key, b)
+}
+
+// flightGroup executes concurrent calls with the same key only once.
+type flightGroup struct {
+	mu    sync.Mutex // guards calls
+	calls map[string]*flight
+}
+
+type flight struct {
+	done chan struct{}
+	res  *Response
+	err  error
+}
+
+func (g *flightGroup) do(key string, fn func() (*Response, error)) (*Response, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flight)
+	}
+
+	if f, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		<-f.done
+		return f.res, f.err
+	}
+
+	f := &flight{done: make(chan struct{})}
+	g.calls[key] = f
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(f.done)
+	}()
+
+	f.res, f.err = fn()
+	return f.res, f.err
+}
@@ -0,0 +1,54 @@
+package rusty
+
+import (
+	"net/url"
+)
+
+// Client builds the endpoints of a single upstream API, sharing its base URL,
+// requester and endpoint options among them. It is safe to use concurrently
+// by multiple goroutines.
+type Client struct {
+	requester Requester
+	baseURL   string
+	opts      []EndpointOption
+}
+
+// NewClient creates a new Client for the API at baseURL, which executes
+// requests with requester. The given options, such as WithHeader,
+// WithErrorPolicy, WithRetries or WithTargetIDPrefix, apply to every endpoint
+// built by the client.
+// It returns an error if baseURL is not a valid URL as defined by url.ParseRequestURI.
+func NewClient(requester Requester, baseURL string, opts ...EndpointOption) (*Client, error) {
+	if _, err := url.ParseRequestURI(baseURL); err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		requester: requester,
+		baseURL:   baseURL,
+		opts:      opts,
+	}, nil
+}
+
+// Endpoint creates a new Endpoint for the given path template, which is joined
+// to the client base URL as URL does, and may contain a query string. The
+// given options are applied after the client ones, overriding them.
+func (c *Client) Endpoint(pathTemplate string, opts ...EndpointOption) (*Endpoint, error) {
+	endpointOpts := make([]EndpointOption, 0, len(c.opts)+len(opts)+1)
+	endpointOpts = append(endpointOpts, c.opts...)
+	endpointOpts = append(endpointOpts, opts...)
+
+	// Headers set by both the client and the endpoint are added twice, so the
+	// endpoint values must replace the client ones.
+	own := defaultEndpointOptions()
+	for _, opt := range opts {
+		opt.applyEndpoint(&own)
+	}
+	endpointOpts = append(endpointOpts, endpointOptionFunc(func(options *endpointOptions) {
+		for k, v := range own.Header {
+			options.Header[k] = v
+		}
+	}))
+
+	return NewEndpoint(c.requester, URL(c.baseURL, pathTemplate), endpointOpts...)
+}
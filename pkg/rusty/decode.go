@@ -0,0 +1,125 @@
+package rusty
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"net/url"
+	"strings"
+)
+
+// _decodeErrorSnippetLen is the maximum number of body bytes included in
+// DecodeError messages.
+const _decodeErrorSnippetLen = 128
+
+// DecodeError is returned when a response body can't be decoded.
+type DecodeError struct {
+	// ContentType is the media type the body was decoded as.
+	ContentType string
+	// Snippet is the beginning of the response body.
+	Snippet string
+	// Err is the error returned by the decoder.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("decoding %s response: %v: body %q", e.ContentType, e.Err, e.Snippet)
+}
+
+// Unwrap returns the error returned by the decoder.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Decode decodes the response body into v according to its Content-Type
+// header, which can be JSON, XML or a URL encoded form. Responses without a
+// Content-Type are decoded as JSON, and empty responses leave v untouched.
+//
+// Form bodies can only be decoded into *url.Values, *map[string][]string and
+// *map[string]string values. Bodies of any other content type fail with
+// ErrUnsupportedContentType.
+//
+// Errors are of type *DecodeError, which include the beginning of the body.
+func (r *Response) Decode(v any) error {
+	if len(r.Body) == 0 {
+		return nil
+	}
+
+	mediaType := "application/json"
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		var err error
+		mediaType, _, err = mime.ParseMediaType(ct)
+		if err != nil {
+			return r.decodeError(ct, fmt.Errorf("%w: %s", ErrUnsupportedContentType, ct))
+		}
+	}
+
+	var err error
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		err = json.Unmarshal(r.Body, v)
+	case isXML(mediaType):
+		err = xml.Unmarshal(r.Body, v)
+	case mediaType == "application/x-www-form-urlencoded":
+		err = decodeForm(r.Body, v)
+	default:
+		err = fmt.Errorf("%w: %s", ErrUnsupportedContentType, mediaType)
+	}
+
+	if err != nil {
+		return r.decodeError(mediaType, err)
+	}
+
+	return nil
+}
+
+// DecodeXML unmarshals the XML response body into v, regardless of its
+// Content-Type header.
+//
+// The method is not named UnmarshalXML so that Response doesn't look like an
+// implementation of the xml.Unmarshaler interface.
+func (r *Response) DecodeXML(v any) error {
+	if err := xml.Unmarshal(r.Body, v); err != nil {
+		return r.decodeError("application/xml", err)
+	}
+	return nil
+}
+
+func (r *Response) decodeError(contentType string, err error) *DecodeError {
+	snippet := r.Body
+	if len(snippet) > _decodeErrorSnippetLen {
+		snippet = snippet[:_decodeErrorSnippetLen]
+	}
+
+	return &DecodeError{
+		ContentType: contentType,
+		Snippet:     string(snippet),
+		Err:         err,
+	}
+}
+
+func decodeForm(body []byte, v any) error {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return err
+	}
+
+	switch t := v.(type) {
+	case *url.Values:
+		*t = values
+	case *map[string][]string:
+		*t = values
+	case *map[string]string:
+		m := make(map[string]string, len(values))
+		for k := range values {
+			m[k] = values.Get(k)
+		}
+		*t = m
+	default:
+		return fmt.Errorf("form bodies can't be decoded into %T", v)
+	}
+
+	return nil
+}
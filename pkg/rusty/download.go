@@ -0,0 +1,208 @@
+package rusty
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrChecksumMismatch downloaded content doesn't match the checksum sent by the server.
+var ErrChecksumMismatch = errors.New("downloaded content checksum mismatch")
+
+// ProgressFunc is called by Download every time downloaded content is written,
+// with the number of bytes written so far and the total size of the content,
+// which is -1 if unknown. When resuming a download both of them include the
+// bytes downloaded before.
+type ProgressFunc func(written, total int64)
+
+// WithProgress sets the function Download calls to report its progress.
+func WithProgress(fn ProgressFunc) RequestOption {
+	return requestOptionFunc(func(options *requestOptions) {
+		options.Progress = fn
+	})
+}
+
+// WithResumeFrom makes Download resume a download from the given offset, the
+// number of bytes already written, by requesting the rest of the content with
+// a Range header. If the server ignores the header and sends the whole content,
+// the first offset bytes are discarded instead of written.
+func WithResumeFrom(offset int64) RequestOption {
+	return requestOptionFunc(func(options *requestOptions) {
+		options.ResumeFrom = offset
+	})
+}
+
+type downloadKey struct{}
+
+// download is the state of a Download call, carried by the request context
+// up to Endpoint.do.
+type download struct {
+	w        io.Writer
+	progress ProgressFunc
+	offset   int64
+}
+
+// Download issues a get request to the endpoint and streams the response body
+// into w instead of reading it into memory, so the Body of the returned
+// Response is always empty for successful responses. Responses with a non 2xx
+// status code are not written into w, and are read and passed to the error
+// policy as usual.
+//
+// The content is verified against the Content-MD5 and Digest headers of the
+// response, if any, failing with ErrChecksumMismatch after it was written.
+// Digest is only verified for complete content, since it covers the whole
+// representation.
+//
+// Progress can be reported with WithProgress, and interrupted downloads can
+// be resumed with WithResumeFrom.
+func (e *Endpoint) Download(ctx context.Context, w io.Writer, opts ...RequestOption) (*Response, error) {
+	opts = append(opts[:len(opts):len(opts)], requestOptionFunc(func(options *requestOptions) {
+		options.download = &download{w: w, progress: options.Progress, offset: options.ResumeFrom}
+	}))
+
+	return e.doRequest(ctx, http.MethodGet, opts...)
+}
+
+func downloadFromContext(ctx context.Context) *download {
+	d, _ := ctx.Value(downloadKey{}).(*download)
+	return d
+}
+
+// copy writes the body of res into the download writer, verifying its
+// checksums.
+func (d *download) copy(res *http.Response) error {
+	checksums, err := newChecksums(res)
+	if err != nil {
+		return err
+	}
+
+	hashes := make([]io.Writer, len(checksums))
+	for i, c := range checksums {
+		hashes[i] = c.hash
+	}
+
+	body := io.TeeReader(res.Body, io.MultiWriter(hashes...))
+
+	written, total := int64(0), res.ContentLength
+	if res.StatusCode == http.StatusPartialContent {
+		written = d.offset
+		total = contentRangeSize(res.Header.Get("Content-Range"))
+	} else if d.offset > 0 {
+		// The server ignored the Range header and sent the whole content.
+		if _, err := io.CopyN(io.Discard, body, d.offset); err != nil {
+			return err
+		}
+		written = d.offset
+	}
+
+	w := d.w
+	if d.progress != nil {
+		w = &progressWriter{w: d.w, progress: d.progress, written: written, total: total}
+	}
+
+	if _, err := io.Copy(w, body); err != nil {
+		return err
+	}
+
+	for _, c := range checksums {
+		if !bytes.Equal(c.hash.Sum(nil), c.want) {
+			return fmt.Errorf("%w: %s", ErrChecksumMismatch, c.algorithm)
+		}
+	}
+
+	return nil
+}
+
+type progressWriter struct {
+	w        io.Writer
+	progress ProgressFunc
+	written  int64
+	total    int64
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.written += int64(n)
+	p.progress(p.written, p.total)
+	return n, err
+}
+
+type checksum struct {
+	algorithm string
+	hash      hash.Hash
+	want      []byte
+}
+
+// newChecksums returns the checksums to verify the body of res with. Content-MD5
+// covers the body itself, while Digest covers the whole representation, so it's
+// ignored for partial content.
+func newChecksums(res *http.Response) ([]checksum, error) {
+	var checksums []checksum
+
+	if v := res.Header.Get("Content-MD5"); v != "" {
+		want, err := base64.StdEncoding.DecodeString(strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("invalid Content-MD5 header: %w", err)
+		}
+		checksums = append(checksums, checksum{algorithm: "md5", hash: md5.New(), want: want})
+	}
+
+	if res.StatusCode == http.StatusPartialContent {
+		return checksums, nil
+	}
+
+	for _, value := range res.Header.Values("Digest") {
+		for _, digest := range strings.Split(value, ",") {
+			algorithm, encoded, ok := strings.Cut(strings.TrimSpace(digest), "=")
+			if !ok {
+				continue
+			}
+
+			var h hash.Hash
+			switch strings.ToLower(algorithm) {
+			case "md5":
+				h = md5.New()
+			case "sha-256":
+				h = sha256.New()
+			case "sha-512":
+				h = sha512.New()
+			default:
+				continue
+			}
+
+			want, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				return nil, fmt.Errorf("invalid Digest header: %w", err)
+			}
+			checksums = append(checksums, checksum{algorithm: strings.ToLower(algorithm), hash: h, want: want})
+		}
+	}
+
+	return checksums, nil
+}
+
+// contentRangeSize returns the complete length of a Content-Range header value
+// like "bytes 100-199/1000", or -1 if it's unknown.
+func contentRangeSize(contentRange string) int64 {
+	_, size, ok := strings.Cut(contentRange, "/")
+	if !ok {
+		return -1
+	}
+
+	n, err := strconv.ParseInt(size, 10, 64)
+	if err != nil {
+		return -1
+	}
+
+	return n
+}
@@ -18,3 +18,53 @@ func (e *Error) Error() string {
 	code := strings.ReplaceAll(strings.ToLower(http.StatusText(e.StatusCode)), " ", "_")
 	return fmt.Sprintf("%d %s: %s", e.StatusCode, code, string(e.Body))
 }
+
+// APIError is an Error whose response body was decoded into a value of type
+// T. It is returned instead of Error by endpoints created with WithErrorBody.
+//
+// Both the *Error and, if it implements the error interface, the decoded
+// body can be obtained from it with errors.As.
+type APIError[T any] struct {
+	// Response is the server response that caused this error. It is always non-nil.
+	*Response
+
+	// Details is the decoded error response body.
+	Details T
+
+	err *Error
+}
+
+// Error implements the error interface.
+func (e *APIError[T]) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the Error and, if it implements the error interface, the
+// decoded body.
+func (e *APIError[T]) Unwrap() []error {
+	errs := []error{e.err}
+	if err, ok := any(e.Details).(error); ok {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// WithErrorBody makes the endpoint decode the body of error responses into a
+// value of type T, returning an *APIError[T] instead of an *Error. Bodies are
+// decoded as Response.Decode does, and those that can't be decoded leave the
+// *Error untouched.
+//
+// It applies to errors returned by the error policy which are or wrap an
+// *Error, as the ones returned by DefaultErrorPolicy.
+func WithErrorBody[T any]() EndpointOption {
+	return endpointOptionFunc(func(options *endpointOptions) {
+		options.ErrorDecoder = func(err *Error) error {
+			var details T
+			if err.Decode(&details) != nil {
+				return err
+			}
+
+			return &APIError[T]{Response: err.Response, Details: details, err: err}
+		}
+	})
+}
@@ -0,0 +1,15 @@
+/*
+Package `graphql` provides a GraphQL client built on top of a `rusty.Endpoint`, so that GraphQL APIs get the same
+HTTP client, tracing and target ID semantics as REST ones.
+
+	endpoint, _ := rusty.NewEndpoint(httpClient, "https://api.internal/graphql", rusty.WithTargetID("catalog_graphql"))
+	client := graphql.NewClient(endpoint)
+
+	var out struct {
+		Item struct {
+			Title string `json:"title"`
+		} `json:"item"`
+	}
+	err := client.Query(ctx, `query($id: ID!) { item(id: $id) { title } }`, map[string]any{"id": "1"}, &out)
+*/
+package graphql
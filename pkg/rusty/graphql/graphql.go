@@ -0,0 +1,197 @@
+package graphql
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/luizaranda/go-core/pkg/rusty"
+)
+
+// _persistedQueryNotFound is the error returned by servers implementing
+// automatic persisted queries when they don't know the hash of a query.
+const _persistedQueryNotFound = "PersistedQueryNotFound"
+
+// Client executes GraphQL operations against a rusty.Endpoint. It is safe to
+// use concurrently by multiple goroutines.
+type Client struct {
+	endpoint  *rusty.Endpoint
+	persisted bool
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithPersistedQueries makes the client use automatic persisted queries, as
+// implemented by Apollo Server and others. Operations are first sent with the
+// SHA-256 hash of the query instead of the query itself, which is sent only
+// if the server replies that the hash is unknown.
+func WithPersistedQueries() Option {
+	return func(c *Client) {
+		c.persisted = true
+	}
+}
+
+// NewClient returns a new Client sending operations with post requests to the
+// given endpoint. The endpoint options, like its target ID, headers or
+// retries, apply to every operation.
+func NewClient(endpoint *rusty.Endpoint, opts ...Option) *Client {
+	c := &Client{endpoint: endpoint}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Location is a position in the query an Error refers to.
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Error is an error of the errors array of a GraphQL response.
+type Error struct {
+	Message    string         `json:"message"`
+	Locations  []Location     `json:"locations,omitempty"`
+	Path       []any          `json:"path,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return "graphql: " + e.Message
+}
+
+// Errors is the errors array of a GraphQL response. Its elements can be
+// retrieved with errors.As.
+type Errors []*Error
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Message
+	}
+
+	return "graphql: " + strings.Join(messages, "; ")
+}
+
+func (e Errors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+
+	return errs
+}
+
+type request struct {
+	Query      string         `json:"query,omitempty"`
+	Variables  map[string]any `json:"variables,omitempty"`
+	Extensions *extensions    `json:"extensions,omitempty"`
+}
+
+type extensions struct {
+	PersistedQuery persistedQuery `json:"persistedQuery"`
+}
+
+type persistedQuery struct {
+	Version    int    `json:"version"`
+	SHA256Hash string `json:"sha256Hash"`
+}
+
+type response struct {
+	Data   json.RawMessage `json:"data"`
+	Errors Errors          `json:"errors"`
+}
+
+// Query executes the given GraphQL query or mutation with its variables, and
+// decodes the data of the response into out, which may be nil.
+//
+// If the response has errors they are returned as Errors, after decoding any
+// partial data into out. Requests failing at the HTTP level return the error
+// of the endpoint, joined with the errors of the response if it had any.
+//
+// The given request options are applied to the post request, so that a
+// particular target ID can be set with rusty.WithTargetID for instance.
+func (c *Client) Query(ctx context.Context, query string, variables map[string]any, out any, opts ...rusty.RequestOption) error {
+	req := request{Query: query, Variables: variables}
+
+	if c.persisted {
+		hash := sha256.Sum256([]byte(query))
+		req.Query = ""
+		req.Extensions = &extensions{PersistedQuery: persistedQuery{Version: 1, SHA256Hash: hex.EncodeToString(hash[:])}}
+
+		res, err := c.post(ctx, req, opts)
+		if !persistedQueryNotFound(res) {
+			return decode(res, err, out)
+		}
+
+		req.Query = query
+	}
+
+	res, err := c.post(ctx, req, opts)
+	return decode(res, err, out)
+}
+
+func (c *Client) post(ctx context.Context, req request, opts []rusty.RequestOption) (*response, error) {
+	opts = append([]rusty.RequestOption{
+		rusty.WithHeader("Content-Type", "application/json"),
+		rusty.WithHeader("Accept", "application/json"),
+		rusty.WithBody(req),
+	}, opts...)
+
+	// Responses are returned along with the error policy errors, whose body
+	// may have the errors of the operation as well.
+	res, err := c.endpoint.Post(ctx, opts...)
+	if res == nil {
+		return nil, err
+	}
+
+	var r response
+	if len(res.Body) > 0 {
+		if decodeErr := json.Unmarshal(res.Body, &r); decodeErr != nil && err == nil {
+			return nil, decodeErr
+		}
+	}
+
+	return &r, err
+}
+
+func persistedQueryNotFound(res *response) bool {
+	if res == nil {
+		return false
+	}
+
+	for _, err := range res.Errors {
+		if err.Message == _persistedQueryNotFound || err.Extensions["code"] == "PERSISTED_QUERY_NOT_FOUND" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func decode(res *response, err error, out any) error {
+	if err != nil {
+		if res != nil && len(res.Errors) > 0 {
+			return errors.Join(err, res.Errors)
+		}
+
+		return err
+	}
+
+	if out != nil && len(res.Data) > 0 && string(res.Data) != "null" {
+		if err := json.Unmarshal(res.Data, out); err != nil {
+			return err
+		}
+	}
+
+	if len(res.Errors) > 0 {
+		return res.Errors
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package rusty
+
+import (
+	"context"
+	"net/http"
+)
+
+// GetJSON will issue a get request to the endpoint and decode the response
+// body into a value of type T.
+//
+// The response is returned along with the decoded value, so that its status
+// code and headers can be inspected, and it's non-nil whenever the request
+// got a response, even if the error policy returned an error.
+func GetJSON[T any](ctx context.Context, e *Endpoint, opts ...RequestOption) (T, *Response, error) {
+	return doJSON[T](ctx, e, http.MethodGet, opts)
+}
+
+// PostJSON will issue a post request to the endpoint and decode the response
+// body into a value of type T. Bodies given with WithBody are encoded as JSON
+// unless a different Content-Type header is set.
+//
+// For more information check GetJSON.
+func PostJSON[T any](ctx context.Context, e *Endpoint, opts ...RequestOption) (T, *Response, error) {
+	return doJSON[T](ctx, e, http.MethodPost, opts)
+}
+
+// PutJSON will issue a put request to the endpoint and decode the response
+// body into a value of type T. Bodies given with WithBody are encoded as JSON
+// unless a different Content-Type header is set.
+//
+// For more information check GetJSON.
+func PutJSON[T any](ctx context.Context, e *Endpoint, opts ...RequestOption) (T, *Response, error) {
+	return doJSON[T](ctx, e, http.MethodPut, opts)
+}
+
+// PatchJSON will issue a patch request to the endpoint and decode the
+// response body into a value of type T. Bodies given with WithBody are encoded
+// as JSON unless a different Content-Type header is set.
+//
+// For more information check GetJSON.
+func PatchJSON[T any](ctx context.Context, e *Endpoint, opts ...RequestOption) (T, *Response, error) {
+	return doJSON[T](ctx, e, http.MethodPatch, opts)
+}
+
+// DeleteJSON will issue a delete request to the endpoint and decode the
+// response body into a value of type T.
+//
+// For more information check GetJSON.
+func DeleteJSON[T any](ctx context.Context, e *Endpoint, opts ...RequestOption) (T, *Response, error) {
+	return doJSON[T](ctx, e, http.MethodDelete, opts)
+}
+
+func doJSON[T any](ctx context.Context, e *Endpoint, method string, opts []RequestOption) (T, *Response, error) {
+	var v T
+
+	opts = append(opts[:len(opts):len(opts)], requestOptionFunc(func(options *requestOptions) {
+		options.DefaultHeader = http.Header{"Accept": {"application/json"}}
+		if options.RequestBody != nil {
+			options.DefaultHeader.Set("Content-Type", "application/json")
+		}
+	}))
+
+	res, err := e.doRequest(ctx, method, opts...)
+	if err != nil {
+		return v, res, err
+	}
+
+	if err := res.Decode(&v); err != nil {
+		return v, res, err
+	}
+
+	return v, res, nil
+}
@@ -1,16 +1,19 @@
 package rusty
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type commonOptions struct {
-	Header   http.Header
-	TargetID string
+	Header          http.Header
+	TargetID        string
+	QueryArrayStyle QueryArrayStyle
 }
 
 type requestOptions struct {
@@ -18,11 +21,31 @@ type requestOptions struct {
 	Params      map[string]string
 	Query       url.Values
 	RequestBody any
+
+	// DefaultHeader is overridden by both endpoint and request headers.
+	DefaultHeader http.Header
+
+	// URL replaces the endpoint URL, as given by pagination links.
+	URL *url.URL
+
+	// Page is the number of the page requested by Paginate, starting at 1.
+	Page int
+
+	Timeout time.Duration
+
+	Progress   ProgressFunc
+	ResumeFrom int64
+	download   *download
 }
 
 type endpointOptions struct {
 	commonOptions
-	ErrorPolicyFn ErrorPolicyFunc
+	ErrorPolicyFn  ErrorPolicyFunc
+	Retries        *retryOptions
+	TargetIDPrefix string
+	ErrorDecoder   func(*Error) error
+	Interceptors   []Interceptor
+	Cache          *cacheOptions
 }
 
 // Option interface is implemented by option functions that are available both at endpoint creation and request invocations.
@@ -84,8 +107,67 @@ func WithParamObject(object any) RequestOption {
 	})
 }
 
+// WithQueryObject will map every field value of struct into query string parameters, which are
+// added to the ones given by WithQuery.
+// Parameter name will be inferred from field name, if exported.
+// You can override this behavior by using the field tag `query:"parameter_name"`.
+// If you want a particular field to be ignored you can use `query:"-"`, and if you want it to be
+// ignored only when empty you can use `query:"parameter_name,omitempty"`.
+// Slice fields are mapped into one parameter value per element, and nil pointer fields are ignored.
+// The value type can be string, the integer types or Stringer, any other type will panic.
+// If object is nil or not a struct (or a pointer to a struct) then it will panic.
+func WithQueryObject(object any) RequestOption {
+	return requestOptionFunc(func(options *requestOptions) {
+		query := make(url.Values, len(options.Query))
+		for k, v := range options.Query {
+			query[k] = v
+		}
+
+		for k, v := range getQuery(object) {
+			query[k] = append(query[k], v...)
+		}
+
+		options.Query = query
+	})
+}
+
+// WithIfNoneMatch makes the request conditional on the resource not matching
+// the given entity tag, as returned in the ETag header of a previous response.
+// Tags without quotes are quoted. The server replies with 304 Not Modified,
+// which can be checked with Response.NotModified, when the resource didn't
+// change.
+func WithIfNoneMatch(etag string) RequestOption {
+	return requestOptionFunc(func(options *requestOptions) {
+		if etag != "*" && !strings.HasPrefix(etag, `"`) && !strings.HasPrefix(etag, `W/"`) {
+			etag = `"` + etag + `"`
+		}
+		options.Header.Set("If-None-Match", etag)
+	})
+}
+
+// WithIfModifiedSince makes the request conditional on the resource having
+// been modified after t, usually taken from the Last-Modified header of a
+// previous response. The server replies with 304 Not Modified, which can be
+// checked with Response.NotModified, when the resource didn't change.
+func WithIfModifiedSince(t time.Time) RequestOption {
+	return requestOptionFunc(func(options *requestOptions) {
+		options.Header.Set("If-Modified-Since", t.UTC().Format(http.TimeFormat))
+	})
+}
+
+// WithTimeout sets a deadline for this request only, which includes reading
+// the response body. The deadline of the given context still applies when
+// it's earlier. When the timeout is exceeded the request fails with an error
+// wrapping context.DeadlineExceeded, and its span has the
+// toolkits.services.restclient.timeout_exceeded attribute set.
+func WithTimeout(d time.Duration) RequestOption {
+	return requestOptionFunc(func(options *requestOptions) {
+		options.Timeout = d
+	})
+}
+
 // WithBody will set a body to the request. Can be a []byte, an io.Reader or any other type
-// that can be marshaled to JSON. If it's the latter you must provide a
+// that can be marshaled to JSON or XML. If it's the latter you must provide a
 // Content-Type header to let rusty know how to encode it. If you don't then an
 // ErrUnsupportedBodyType will be returned in any of the Request functions (Post, Put, etc).
 func WithBody(body any) RequestOption {
@@ -123,6 +205,66 @@ func WithTargetID(targetID string) Option {
 	})
 }
 
+// Invoker executes a request built by an Endpoint, returning its response
+// after applying the endpoint error policy.
+type Invoker func(ctx context.Context, req *http.Request) (*Response, error)
+
+// Interceptor wraps the execution of the requests of an Endpoint. It may
+// modify the request before calling next, inspect or replace the response
+// and error returned by it, or not call it at all.
+type Interceptor func(ctx context.Context, req *http.Request, next Invoker) (*Response, error)
+
+// WithInterceptor adds interceptors to the endpoint, which are called in the
+// given order for every request. They allow layering cross-cutting concerns,
+// such as audit logging or response validation, over the decoded Response
+// instead of the raw http.Response seen by transport hooks.
+//
+// Interceptors run within the request span, once the request is built.
+func WithInterceptor(interceptors ...Interceptor) EndpointOption {
+	return endpointOptionFunc(func(options *endpointOptions) {
+		options.Interceptors = append(options.Interceptors, interceptors...)
+	})
+}
+
+// WithTargetIDPrefix sets a prefix for the telemetry target id of every
+// request made to this endpoint, whether it's given with WithTargetID to the
+// endpoint or to the request. Endpoints with a prefix and no target id use
+// their URL path template as target id.
+//
+// It's mostly useful with Client, so that the endpoints of an upstream share
+// a common prefix, like "users-api:".
+func WithTargetIDPrefix(prefix string) EndpointOption {
+	return endpointOptionFunc(func(options *endpointOptions) {
+		options.TargetIDPrefix = prefix
+	})
+}
+
+// QueryArrayStyle is the encoding of query string parameters with many values.
+type QueryArrayStyle int
+
+const (
+	_ QueryArrayStyle = iota
+
+	// QueryArrayRepeat repeats the parameter for every value, as in a=1&a=2. It's the default.
+	QueryArrayRepeat
+
+	// QueryArrayComma joins the values with commas, as in a=1,2.
+	QueryArrayComma
+
+	// QueryArrayBrackets repeats the parameter with a [] suffix for every value, as in a[]=1&a[]=2.
+	// Parameters with a single value are suffixed too, as in a[]=1.
+	QueryArrayBrackets
+)
+
+// WithQueryArrayStyle sets how query string parameters with many values, as given by WithQuery or the
+// slice fields of WithQueryObject, are encoded. If set both at the endpoint and at the request, the
+// one set at the request will take precedence.
+func WithQueryArrayStyle(style QueryArrayStyle) Option {
+	return allOptionFunc(func(options *commonOptions) {
+		options.QueryArrayStyle = style
+	})
+}
+
 // WithQuery adds additional query values than those specified and parameterized in the endpointURL.
 // If a query parameter is both in endpointURL at creation and in the url.Values map received as
 // parameter the latter is also appended at the end.
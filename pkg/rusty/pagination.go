@@ -0,0 +1,147 @@
+package rusty
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/luizaranda/go-core/pkg/telemetry"
+	"github.com/luizaranda/go-core/pkg/telemetry/tracing"
+	"github.com/luizaranda/go-core/pkg/transport/httpclient"
+)
+
+const _paginationPageMetric = "toolkit.http.client.pagination.page"
+
+// NextPageFunc returns the option that requests the page following the given
+// response, and false if it's the last page.
+type NextPageFunc func(*Response) (RequestOption, bool)
+
+// Paginate iterates the pages of a paginated resource by issuing get requests
+// to the endpoint. The first page is requested with opts, and every following
+// page with opts and the option returned by extractNext for the previous
+// page. Pages are requested lazily as the loop advances:
+//
+//	for page, err := range endpoint.Paginate(ctx, rusty.CursorPagination("cursor", nextCursor)) {
+//		if err != nil {
+//			return err
+//		}
+//		// Use page.Body
+//	}
+//
+// Iteration ends when extractNext returns false, or after yielding an error,
+// in which case the page is yielded as well if a response was received. The
+// OffsetPagination, CursorPagination and LinkPagination functions provide
+// extractNext implementations for the usual pagination styles.
+//
+// Every page records the toolkit.http.client.pagination.page metric, and the
+// span of its request has the page number as attribute.
+func (e *Endpoint) Paginate(ctx context.Context, extractNext NextPageFunc, opts ...RequestOption) iter.Seq2[*Response, error] {
+	return func(yield func(*Response, error) bool) {
+		pageOpts := opts
+		for page := 1; ; page++ {
+			pageOpts = append(pageOpts[:len(pageOpts):len(pageOpts)], withPage(page))
+
+			res, err := e.doRequest(ctx, http.MethodGet, pageOpts...)
+			if err != nil {
+				yield(res, err)
+				return
+			}
+
+			telemetry.Incr(ctx, _paginationPageMetric, []string{
+				"technology:go",
+				"target_id:" + telemetry.SanitizeMetricTagValue(tracing.TargetID(res.Request.Context())),
+			})
+
+			if !yield(res, nil) {
+				return
+			}
+
+			next, ok := extractNext(res)
+			if !ok {
+				return
+			}
+
+			pageOpts = append(opts[:len(opts):len(opts)], next)
+		}
+	}
+}
+
+// OffsetPagination returns a NextPageFunc for resources paginated by offset
+// and limit. The offset of the next page is the offset of the current one,
+// taken from the offsetParam query parameter of its request, plus limit.
+// Iteration ends when count, which returns the number of items in a page,
+// returns less than limit.
+//
+// The offsetParam query parameter must not be part of the endpoint URL, as
+// it's set by the returned function. The limit is usually given to Paginate
+// with WithQuery or WithParam.
+func OffsetPagination(offsetParam string, limit int, count func(*Response) int) NextPageFunc {
+	return func(res *Response) (RequestOption, bool) {
+		if count(res) < limit {
+			return nil, false
+		}
+
+		offset, _ := strconv.Atoi(res.Request.URL.Query().Get(offsetParam))
+		return withQueryValue(offsetParam, strconv.Itoa(offset+limit)), true
+	}
+}
+
+// CursorPagination returns a NextPageFunc for resources paginated by cursor.
+// The cursor of the next page is taken from the current one with the cursor
+// function, and sent in the cursorParam query parameter. Iteration ends when
+// cursor returns an empty string.
+//
+// The cursorParam query parameter must not be part of the endpoint URL, as
+// it's set by the returned function.
+func CursorPagination(cursorParam string, cursor func(*Response) string) NextPageFunc {
+	return func(res *Response) (RequestOption, bool) {
+		next := cursor(res)
+		if next == "" {
+			return nil, false
+		}
+
+		return withQueryValue(cursorParam, next), true
+	}
+}
+
+// LinkPagination returns a NextPageFunc for resources paginated with the RFC
+// 5988 Link header, which requests the link with rel="next" of every page.
+// Iteration ends when a page has no such link.
+func LinkPagination() NextPageFunc {
+	return func(res *Response) (RequestOption, bool) {
+		link := httpclient.NextLink(res.Header)
+		if link == "" {
+			return nil, false
+		}
+
+		u, err := res.Request.URL.Parse(link)
+		if err != nil {
+			return nil, false
+		}
+
+		return requestOptionFunc(func(options *requestOptions) {
+			options.URL = u
+		}), true
+	}
+}
+
+func withPage(page int) RequestOption {
+	return requestOptionFunc(func(options *requestOptions) {
+		options.Page = page
+	})
+}
+
+// withQueryValue sets a query string parameter, keeping those given by
+// WithQuery.
+func withQueryValue(name, value string) RequestOption {
+	return requestOptionFunc(func(options *requestOptions) {
+		query := make(url.Values, len(options.Query)+1)
+		for k, v := range options.Query {
+			query[k] = v
+		}
+		query.Set(name, value)
+		options.Query = query
+	})
+}
@@ -0,0 +1,78 @@
+package rusty
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/luizaranda/go-core/pkg/transport/httpclient"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type retryOptions struct {
+	RetryMax int
+	Backoff  httpclient.BackoffFunc
+	Policy   httpclient.CheckRetryFunc
+}
+
+// WithRetries makes the endpoint retry failed requests up to retryMax times,
+// waiting between attempts as given by backoff, and retrying only when policy
+// says so. If backoff is nil then httpclient.DefaultBackoffStrategy is used,
+// and if policy is nil then httpclient.DefaultRetryPolicy is used.
+//
+// It allows endpoints built over a plain Requester to get retry semantics.
+// Retry-After headers are honored as httpclient.RetryableClient does, and the
+// number of retries of a call is recorded in its span. Note that retries
+// multiply when the Requester retries requests itself.
+func WithRetries(retryMax int, backoff httpclient.BackoffFunc, policy httpclient.CheckRetryFunc) EndpointOption {
+	return endpointOptionFunc(func(options *endpointOptions) {
+		if backoff == nil {
+			backoff = httpclient.DefaultBackoffStrategy
+		}
+
+		if policy == nil {
+			policy = httpclient.DefaultRetryPolicy
+		}
+
+		options.Retries = &retryOptions{
+			RetryMax: retryMax,
+			Backoff:  backoff,
+			Policy:   policy,
+		}
+	})
+}
+
+// retryingRequester wraps requester with the given retry options.
+func retryingRequester(requester Requester, options *retryOptions) Requester {
+	return &httpclient.RetryableClient{
+		Client: &http.Client{
+			Transport: requesterRoundTripper{requester},
+			// Redirects were already handled by the wrapped requester.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		RetryMax:        options.RetryMax,
+		BackoffStrategy: options.Backoff,
+		CheckRetry:      options.Policy,
+		MaxRetryAfter:   httpclient.DefaultMaxRetryAfter,
+	}
+}
+
+// requesterRoundTripper adapts a Requester to the http.RoundTripper
+// interface, marking retried requests with the x-retry header and recording
+// the number of retries in the span of the call.
+type requesterRoundTripper struct {
+	requester Requester
+}
+
+func (t requesterRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if i := httpclient.RetryCount(req); i > 0 {
+		retries := strconv.Itoa(i)
+		trace.SpanFromContext(req.Context()).SetAttributes(_retriesSpanAttribute.String(retries))
+
+		req = req.Clone(req.Context())
+		req.Header.Set("x-retry", retries)
+	}
+
+	return t.requester.Do(req)
+}
@@ -3,15 +3,20 @@ package rusty
 import (
 	"context"
 	"encoding/json"
+	"encoding/xml"
 	"errors"
+	"fmt"
 	"io"
 	"mime"
 	"net/http"
 	"net/url"
+	"slices"
+	"strings"
 
 	"github.com/luizaranda/go-core/pkg/internal"
 	"github.com/luizaranda/go-core/pkg/telemetry/tracing"
 	"github.com/luizaranda/go-core/pkg/transport/httpclient"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
@@ -23,6 +28,15 @@ var (
 
 	// ErrMissingURLParam missing param for replacing in a rusty URL.
 	ErrMissingURLParam = errors.New("missing param value for a rusty URL")
+
+	// errRequestTimeout is the cause of contexts canceled by WithTimeout.
+	errRequestTimeout = fmt.Errorf("rusty request timeout exceeded: %w", context.DeadlineExceeded)
+
+	// ErrUnsupportedContentType response content type can't be decoded.
+	ErrUnsupportedContentType = errors.New("unsupported response content type")
+
+	// ErrInvalidURLTemplate malformed placeholders in a rusty URL.
+	ErrInvalidURLTemplate = errors.New("invalid rusty URL template")
 )
 
 // Requester is responsible for making HTTP requests. It is usually an implementation provided
@@ -41,6 +55,15 @@ type Response struct {
 	StatusCode int
 	// Header is the response header map.
 	Header http.Header
+	// Request is the request that was sent to obtain this response.
+	Request *http.Request
+}
+
+// NotModified tells whether the server replied to a conditional request,
+// made with WithIfNoneMatch or WithIfModifiedSince, with 304 Not Modified, in
+// which case the body is empty and the previously obtained one is still valid.
+func (r *Response) NotModified() bool {
+	return r.StatusCode == http.StatusNotModified
 }
 
 // Endpoint represents an API endpoint at a particular URL. It is safe to use concurrently by multiple goroutines.
@@ -48,9 +71,14 @@ type Response struct {
 type Endpoint struct {
 	requester      Requester
 	formatURL      *url.URL
+	params         []string
 	defaultHeaders http.Header
 	errorPolicy    ErrorPolicyFunc
+	errorDecoder   func(*Error) error
 	targetID       string
+	targetIDPrefix string
+	arrayStyle     QueryArrayStyle
+	invoke         Invoker
 }
 
 // ErrorPolicyFunc for specifying an error policy function that will be used to determine if an error should be returned.
@@ -69,7 +97,9 @@ var DefaultErrorPolicy ErrorPolicyFunc = func(r *Response) error {
 }
 
 // NewEndpoint creates a new Endpoint with the given URL and options.
-// It returns an error if endpointURL is not a valid URL as defined by url.ParseRequestURI.
+// It returns an error if endpointURL is not a valid URL as defined by url.ParseRequestURI,
+// or if its {param} placeholders are malformed: unbalanced braces, duplicate names or names
+// with reserved characters fail with ErrInvalidURLTemplate.
 func NewEndpoint(requester Requester, endpointURL string, opts ...EndpointOption) (*Endpoint, error) {
 	options := defaultEndpointOptions()
 	for _, option := range opts {
@@ -81,13 +111,53 @@ func NewEndpoint(requester Requester, endpointURL string, opts ...EndpointOption
 		return nil, err
 	}
 
-	return &Endpoint{
+	params, err := parseURLTemplate(u)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Retries != nil {
+		requester = retryingRequester(requester, options.Retries)
+	}
+
+	targetID := options.TargetID
+	if targetID == "" && options.TargetIDPrefix != "" {
+		targetID = u.Path
+	}
+
+	e := &Endpoint{
 		requester:      requester,
 		formatURL:      u,
+		params:         params,
 		defaultHeaders: options.Header,
 		errorPolicy:    options.ErrorPolicyFn,
-		targetID:       options.TargetID,
-	}, nil
+		errorDecoder:   options.ErrorDecoder,
+		targetID:       targetID,
+		targetIDPrefix: options.TargetIDPrefix,
+		arrayStyle:     options.QueryArrayStyle,
+	}
+
+	e.invoke = e.do
+	if options.Cache != nil {
+		e.invoke = cachingInvoker(options.Cache, e.invoke)
+	}
+
+	// The first interceptor is the outermost one.
+	for i := len(options.Interceptors) - 1; i >= 0; i-- {
+		interceptor, next := options.Interceptors[i], e.invoke
+		e.invoke = func(ctx context.Context, req *http.Request) (*Response, error) {
+			return interceptor(ctx, req, next)
+		}
+	}
+
+	return e, nil
+}
+
+// Params returns the names of the {param} placeholders of the endpoint URL, in order of
+// appearance. Every one of them must be given a value with WithParam or WithParamObject
+// when calling the endpoint.
+func (e *Endpoint) Params() []string {
+	return slices.Clone(e.params)
 }
 
 // Get will issue a http get request to the endpoint.
@@ -123,22 +193,45 @@ func (e *Endpoint) doRequest(ctx context.Context, method string, opts ...Request
 	}
 
 	if options.TargetID != "" {
-		ctx = tracing.WithTargetID(ctx, options.TargetID)
+		ctx = tracing.WithTargetID(ctx, e.targetIDPrefix+options.TargetID)
 	} else if e.targetID != "" {
-		ctx = tracing.WithTargetID(ctx, e.targetID)
+		ctx = tracing.WithTargetID(ctx, e.targetIDPrefix+e.targetID)
 	}
 
 	ctx = tracing.WithEndpointTemplate(ctx, e.formatURL.Path)
 
-	targetURL, err := expandURLTemplate(e.formatURL, options.Params, options.Query)
-	if err != nil {
-		return nil, err
+	if options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, options.Timeout, errRequestTimeout)
+		defer cancel()
+	}
+
+	targetURL := options.URL
+	if targetURL == nil {
+		arrayStyle := options.QueryArrayStyle
+		if arrayStyle == 0 {
+			arrayStyle = e.arrayStyle
+		}
+
+		var err error
+		targetURL, err = expandURLTemplate(e.formatURL, options.Params, options.Query, arrayStyle)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	requestHeaders := make(http.Header, len(e.defaultHeaders)+len(options.Header))
+	requestHeaders := make(http.Header, len(options.DefaultHeader)+len(e.defaultHeaders)+len(options.Header))
+	copyHeader(requestHeaders, options.DefaultHeader)
 	copyHeader(requestHeaders, e.defaultHeaders)
 	copyHeader(requestHeaders, options.Header)
 
+	if options.download != nil {
+		ctx = context.WithValue(ctx, downloadKey{}, options.download)
+		if options.download.offset > 0 {
+			requestHeaders.Set("Range", fmt.Sprintf("bytes=%d-", options.download.offset))
+		}
+	}
+
 	body, err := getBody(options.RequestBody, requestHeaders)
 	if err != nil {
 		return nil, err
@@ -159,9 +252,20 @@ func (e *Endpoint) doRequest(ctx context.Context, method string, opts ...Request
 	ctx, span := newSpan(request)
 	defer span.End()
 
-	request = request.WithContext(ctx)
-	response, err := e.requester.Do(request)
+	if options.Page > 0 {
+		span.SetAttributes(_pageSpanAttribute.Int(options.Page))
+	}
+
+	return e.invoke(ctx, request.WithContext(ctx))
+}
+
+// do executes the request and applies the error policy to its response.
+func (e *Endpoint) do(ctx context.Context, request *http.Request) (*Response, error) {
+	span := trace.SpanFromContext(ctx)
+
+	response, err := e.requester.Do(request.WithContext(ctx))
 	recordResponseAttributes(span, response, err)
+	recordTimeout(ctx, span)
 
 	if err != nil {
 		return nil, err
@@ -169,8 +273,15 @@ func (e *Endpoint) doRequest(ctx context.Context, method string, opts ...Request
 
 	defer response.Body.Close()
 
-	b, err := io.ReadAll(response.Body)
+	var b []byte
+	if d := downloadFromContext(ctx); d != nil && response.StatusCode >= 200 && response.StatusCode < 300 {
+		err = d.copy(response)
+	} else {
+		b, err = io.ReadAll(response.Body)
+	}
+
 	if err != nil {
+		recordTimeout(ctx, span)
 		return nil, err
 	}
 
@@ -178,9 +289,17 @@ func (e *Endpoint) doRequest(ctx context.Context, method string, opts ...Request
 		Body:       b,
 		StatusCode: response.StatusCode,
 		Header:     response.Header,
+		Request:    request,
 	}
 
-	return &r, e.errorPolicy(&r)
+	err = e.errorPolicy(&r)
+
+	var rustyErr *Error
+	if e.errorDecoder != nil && errors.As(err, &rustyErr) {
+		err = e.errorDecoder(rustyErr)
+	}
+
+	return &r, err
 }
 
 func getBody(body any, headers http.Header) (any, error) {
@@ -196,9 +315,11 @@ func getBody(body any, headers http.Header) (any, error) {
 
 		var content []byte
 
-		switch ct {
-		case "application/json":
+		switch {
+		case ct == "application/json":
 			content, err = json.Marshal(body)
+		case isXML(ct):
+			content, err = xml.Marshal(body)
 		default:
 			return nil, ErrUnsupportedBodyType
 		}
@@ -211,6 +332,10 @@ func getBody(body any, headers http.Header) (any, error) {
 	}
 }
 
+func isXML(mediaType string) bool {
+	return mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml")
+}
+
 func copyHeader(dst, src http.Header) {
 	for k := range src {
 		dst.Set(k, src.Get(k))
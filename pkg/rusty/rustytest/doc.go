@@ -0,0 +1,12 @@
+/*
+Package `rustytest` provides a `rusty.Requester` test double, so that code calling `rusty.Endpoint`s can be unit tested
+without spinning up an `httptest.Server`.
+
+Expectations are set on a `Requester` fluently, and verified once the test finishes:
+
+	requester := rustytest.NewRequester(t)
+	requester.ExpectGET("/users/{id}").WithParam("id", "1").RespondJSON(200, user)
+
+	endpoint, _ := rusty.NewEndpoint(requester, "https://api.user.com/users/{id}")
+*/
+package rustytest
@@ -0,0 +1,333 @@
+package rustytest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/luizaranda/go-core/pkg/rusty"
+)
+
+var _ rusty.Requester = (*Requester)(nil)
+
+// Requester is an expectation based rusty.Requester. Requests are answered by
+// the first pending expectation they match, or fail with an error if there is
+// none. Every request is recorded, so that they can be inspected with Calls.
+type Requester struct {
+	t testing.TB
+
+	mu           sync.Mutex // guards the fields below
+	expectations []*Expectation
+	calls        []Call
+}
+
+// Call is a request received by a Requester.
+type Call struct {
+	Method string
+	URL    *url.URL
+	Header http.Header
+	Body   []byte
+}
+
+// NewRequester returns a new Requester. Once the test finishes, it's failed
+// if any of the expectations set on the Requester was not fulfilled.
+func NewRequester(t testing.TB) *Requester {
+	r := &Requester{t: t}
+	t.Cleanup(r.AssertExpectations)
+	return r
+}
+
+// ExpectGET adds an expectation for a GET request to the given path.
+func (r *Requester) ExpectGET(path string) *Expectation { return r.Expect(http.MethodGet, path) }
+
+// ExpectPOST adds an expectation for a POST request to the given path.
+func (r *Requester) ExpectPOST(path string) *Expectation { return r.Expect(http.MethodPost, path) }
+
+// ExpectPUT adds an expectation for a PUT request to the given path.
+func (r *Requester) ExpectPUT(path string) *Expectation { return r.Expect(http.MethodPut, path) }
+
+// ExpectPATCH adds an expectation for a PATCH request to the given path.
+func (r *Requester) ExpectPATCH(path string) *Expectation { return r.Expect(http.MethodPatch, path) }
+
+// ExpectDELETE adds an expectation for a DELETE request to the given path.
+func (r *Requester) ExpectDELETE(path string) *Expectation { return r.Expect(http.MethodDelete, path) }
+
+// Expect adds an expectation for a request with the given method and path.
+//
+// Path segments in the {name} form match any value, which can be required to
+// be a particular one with WithParam. So "/users/{id}" matches both
+// "/users/1" and "/users/2". The query string and body of requests are
+// ignored unless WithQuery or a body matcher is used.
+//
+// By default expectations are fulfilled by a single request, and respond with
+// an empty 200 OK response.
+func (r *Requester) Expect(method, path string) *Expectation {
+	e := &Expectation{
+		method:    method,
+		path:      strings.Split(strings.Trim(path, "/"), "/"),
+		template:  path,
+		params:    make(map[string]string),
+		query:     make(url.Values),
+		header:    make(http.Header),
+		times:     1,
+		status:    http.StatusOK,
+		resHeader: make(http.Header),
+	}
+
+	r.mu.Lock()
+	r.expectations = append(r.expectations, e)
+	r.mu.Unlock()
+
+	return e
+}
+
+// Do executes the given request against the Requester expectations.
+func (r *Requester) Do(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	call := Call{
+		Method: method,
+		URL:    req.URL,
+		Header: req.Header.Clone(),
+		Body:   body,
+	}
+
+	r.mu.Lock()
+	r.calls = append(r.calls, call)
+	var match *Expectation
+	for _, e := range r.expectations {
+		if e.calls < e.times && e.matches(&call) {
+			e.calls++
+			match = e
+			break
+		}
+	}
+	r.mu.Unlock()
+
+	if match == nil {
+		return nil, fmt.Errorf("rustytest: unexpected request %s %s", method, req.URL)
+	}
+
+	return match.response(req)
+}
+
+// Calls returns the requests received by the Requester, in order.
+func (r *Requester) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Call(nil), r.calls...)
+}
+
+// AssertExpectations fails the test if any expectation was not fulfilled.
+// It's called automatically when the test finishes.
+func (r *Requester) AssertExpectations() {
+	r.t.Helper()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, e := range r.expectations {
+		if e.calls < e.times {
+			r.t.Errorf("rustytest: expected %d %s %s requests, got %d", e.times, e.method, e.template, e.calls)
+		}
+	}
+}
+
+// Expectation is a request expected by a Requester, together with the
+// response it must get.
+type Expectation struct {
+	method   string
+	path     []string
+	template string
+	params   map[string]string
+	query    url.Values
+	header   http.Header
+	body     func([]byte) bool
+	times    int
+	calls    int
+
+	status    int
+	resHeader http.Header
+	resBody   []byte
+	err       error
+}
+
+// WithParam makes the expectation match only requests with the given value
+// for the {name} path segment.
+func (e *Expectation) WithParam(name, value string) *Expectation {
+	e.params[name] = value
+	return e
+}
+
+// WithQuery makes the expectation match only requests with the given query
+// string parameter value.
+func (e *Expectation) WithQuery(key, value string) *Expectation {
+	e.query.Add(key, value)
+	return e
+}
+
+// WithHeader makes the expectation match only requests with the given header
+// value.
+func (e *Expectation) WithHeader(key, value string) *Expectation {
+	e.header.Add(key, value)
+	return e
+}
+
+// WithBody makes the expectation match only requests whose body is accepted
+// by the given matcher.
+func (e *Expectation) WithBody(matcher func(body []byte) bool) *Expectation {
+	e.body = matcher
+	return e
+}
+
+// WithJSONBody makes the expectation match only requests whose body is the
+// JSON encoding of v, regardless of formatting and key order. This method
+// panics if v can't be encoded.
+func (e *Expectation) WithJSONBody(v any) *Expectation {
+	want := normalizeJSON(v)
+
+	return e.WithBody(func(body []byte) bool {
+		var got any
+		if err := json.Unmarshal(body, &got); err != nil {
+			return false
+		}
+		return reflect.DeepEqual(got, want)
+	})
+}
+
+// Times sets the number of requests needed to fulfill the expectation.
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+// Respond sets the status code and body of the response.
+func (e *Expectation) Respond(status int, body string) *Expectation {
+	e.status = status
+	e.resBody = []byte(body)
+	return e
+}
+
+// RespondJSON sets the status code of the response, and its body to the JSON
+// encoding of v. This method panics if v can't be encoded.
+func (e *Expectation) RespondJSON(status int, v any) *Expectation {
+	body, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("rustytest: encoding response: %v", err))
+	}
+
+	e.status = status
+	e.resBody = body
+	e.resHeader.Set("Content-Type", "application/json")
+	return e
+}
+
+// RespondHeader sets a header of the response.
+func (e *Expectation) RespondHeader(key, value string) *Expectation {
+	e.resHeader.Set(key, value)
+	return e
+}
+
+// RespondError makes requests matching the expectation fail with the given
+// error instead of getting a response.
+func (e *Expectation) RespondError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+func (e *Expectation) matches(call *Call) bool {
+	if call.Method != e.method {
+		return false
+	}
+
+	path := strings.Split(strings.Trim(call.URL.Path, "/"), "/")
+	if len(path) != len(e.path) {
+		return false
+	}
+
+	for i, segment := range e.path {
+		if name, ok := strings.CutPrefix(segment, "{"); ok && strings.HasSuffix(name, "}") {
+			value, required := e.params[strings.TrimSuffix(name, "}")]
+			if required && value != path[i] {
+				return false
+			}
+			continue
+		}
+
+		if segment != path[i] {
+			return false
+		}
+	}
+
+	query := call.URL.Query()
+	for key, values := range e.query {
+		for _, value := range values {
+			if !slices.Contains(query[key], value) {
+				return false
+			}
+		}
+	}
+
+	for key, values := range e.header {
+		for _, value := range values {
+			if !slices.Contains(call.Header.Values(key), value) {
+				return false
+			}
+		}
+	}
+
+	return e.body == nil || e.body(call.Body)
+}
+
+func (e *Expectation) response(req *http.Request) (*http.Response, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.status, http.StatusText(e.status)),
+		StatusCode:    e.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.resHeader.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.resBody)),
+		ContentLength: int64(len(e.resBody)),
+		Request:       req,
+	}, nil
+}
+
+// normalizeJSON returns v as decoded by json.Unmarshal into an any value, so
+// that it can be compared with decoded request bodies.
+func normalizeJSON(v any) any {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("rustytest: encoding body: %v", err))
+	}
+
+	var normalized any
+	_ = json.Unmarshal(b, &normalized)
+	return normalized
+}
@@ -3,7 +3,9 @@ package rusty
 import (
 	"errors"
 	"fmt"
+	"net/url"
 	"reflect"
+	"strings"
 )
 
 // getParams will extract the values from the fields of the struct v to be used as parameters.
@@ -44,6 +46,69 @@ func getParams(value any) map[string]string {
 	return params
 }
 
+// getQuery will extract the values from the fields of the struct v to be used as query string parameters.
+// The field should be considered as a parameter if it has the tag "query" or is exported in which case
+// the field name will be used as the parameter name.
+// The field will be ignored if it has the tag "query" with the value "-", or if it has the "omitempty"
+// option and its value is the zero value or an empty slice.
+// Slice and array fields result in one value per element, and nil pointers are ignored.
+// The field values will be converted to string using the function toString.
+func getQuery(value any) url.Values {
+	if value == nil {
+		panic("value is nil")
+	}
+
+	rv, err := reflectValue(value)
+	if err != nil {
+		panic(fmt.Errorf("failed to obtain reflect value: %v", err))
+	}
+
+	query := make(url.Values)
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(field.Tag.Get("query"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		fv := rv.Field(i)
+		if fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		}
+
+		omitEmpty := opts == "omitempty"
+		if omitEmpty && fv.IsZero() {
+			continue
+		}
+
+		switch fv.Kind() {
+		case reflect.Slice, reflect.Array:
+			if omitEmpty && fv.Len() == 0 {
+				continue
+			}
+
+			for j := 0; j < fv.Len(); j++ {
+				query.Add(name, toString(fv.Index(j).Interface()))
+			}
+		default:
+			query.Add(name, toString(fv.Interface()))
+		}
+	}
+
+	return query
+}
+
 // reflectValue will obtain the [reflect.Value] of v only if it is a struct or a pointer to a struct.
 // If it is a pointer to a struct, it will dereference it and return the [reflect.Value] of the struct.
 // Otherwise, it will return an error.
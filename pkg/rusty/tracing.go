@@ -2,6 +2,7 @@ package rusty
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -20,6 +21,8 @@ const (
 
 	_endpointSpanAttribute = attribute.Key("toolkits.services.restclient.endpoint_rusty")
 	_retriesSpanAttribute  = attribute.Key("toolkits.services.restclient.retries")
+	_pageSpanAttribute     = attribute.Key("toolkits.services.restclient.page")
+	_timeoutSpanAttribute  = attribute.Key("toolkits.services.restclient.timeout_exceeded")
 )
 
 func newSpan(req *http.Request) (context.Context, trace.Span) {
@@ -47,6 +50,14 @@ func recordResponseAttributes(span trace.Span, res *http.Response, err error) {
 	span.SetStatus(semconv.SpanStatusFromHTTPStatusCode(res.StatusCode))
 }
 
+// recordTimeout marks the span when the deadline set by WithTimeout was
+// exceeded, as opposed to the one of the caller context.
+func recordTimeout(ctx context.Context, span trace.Span) {
+	if errors.Is(context.Cause(ctx), errRequestTimeout) {
+		span.SetAttributes(_timeoutSpanAttribute.Bool(true))
+	}
+}
+
 func spanName(method string) string {
 	return fmt.Sprintf("%s %s", _rustySpanName, method)
 }
@@ -1,8 +1,11 @@
 package rusty
 
 import (
+	"fmt"
 	"io"
+	"maps"
 	"net/url"
+	"slices"
 	"strings"
 
 	"github.com/valyala/fasttemplate"
@@ -52,7 +55,49 @@ func URL(base string, elem string) string {
 	return unescapedURL
 }
 
-func expandURLTemplate(u *url.URL, params map[string]string, query url.Values) (*url.URL, error) {
+// _reservedParamChars are the characters which can't be part of a placeholder
+// name, the RFC 3986 reserved characters, braces and whitespace.
+const _reservedParamChars = ":/?#[]@!$&'()*+,;={} \t\r\n"
+
+// parseURLTemplate returns the names of the {param} placeholders of the path
+// and query string of u, in order of appearance.
+func parseURLTemplate(u *url.URL) ([]string, error) {
+	var params []string
+	for _, full := range []string{u.Path, u.RawQuery} {
+		for template := full; template != ""; {
+			start := strings.IndexAny(template, "{}")
+			if start == -1 {
+				break
+			}
+
+			if template[start] == '}' {
+				return nil, fmt.Errorf("%w: unbalanced '}' in %q", ErrInvalidURLTemplate, full)
+			}
+
+			end := strings.IndexByte(template[start+1:], '}')
+			if end == -1 {
+				return nil, fmt.Errorf("%w: unbalanced '{' in %q", ErrInvalidURLTemplate, full)
+			}
+
+			name := template[start+1 : start+1+end]
+			switch {
+			case name == "":
+				return nil, fmt.Errorf("%w: empty placeholder in %q", ErrInvalidURLTemplate, full)
+			case strings.ContainsAny(name, _reservedParamChars):
+				return nil, fmt.Errorf("%w: placeholder {%s} has reserved characters", ErrInvalidURLTemplate, name)
+			case slices.Contains(params, name):
+				return nil, fmt.Errorf("%w: duplicate placeholder {%s}", ErrInvalidURLTemplate, name)
+			}
+
+			params = append(params, name)
+			template = template[start+end+2:]
+		}
+	}
+
+	return params, nil
+}
+
+func expandURLTemplate(u *url.URL, params map[string]string, query url.Values, arrayStyle QueryArrayStyle) (*url.URL, error) {
 	u2 := cloneURL(u)
 	p, err := fasttemplate.ExecuteFuncStringWithErr(u.Path, "{", "}", func(w io.Writer, tag string) (int, error) { return tagFunc(w, tag, params, noneEscape) })
 	if err != nil {
@@ -73,7 +118,7 @@ func expandURLTemplate(u *url.URL, params map[string]string, query url.Values) (
 		rawQuery += "&"
 	}
 
-	rawQuery += query.Encode()
+	rawQuery += encodeQuery(query, arrayStyle)
 
 	u2.Path = p
 	u2.RawPath = rawPath
@@ -81,6 +126,51 @@ func expandURLTemplate(u *url.URL, params map[string]string, query url.Values) (
 	return u2, nil
 }
 
+// encodeQuery encodes query as url.Values.Encode does, using the given style
+// for parameters with many values.
+func encodeQuery(query url.Values, arrayStyle QueryArrayStyle) string {
+	if arrayStyle != QueryArrayComma && arrayStyle != QueryArrayBrackets {
+		return query.Encode()
+	}
+
+	var sb strings.Builder
+	write := func(key, value string) {
+		if sb.Len() > 0 {
+			sb.WriteByte('&')
+		}
+		sb.WriteString(key)
+		sb.WriteByte('=')
+		sb.WriteString(value)
+	}
+
+	for _, k := range slices.Sorted(maps.Keys(query)) {
+		values, key := query[k], url.QueryEscape(k)
+
+		switch {
+		case arrayStyle == QueryArrayBrackets:
+			// A single value can't be told apart from a slice of one element,
+			// so every parameter is suffixed.
+			for _, v := range values {
+				write(key+"[]", url.QueryEscape(v))
+			}
+
+		case len(values) < 2:
+			for _, v := range values {
+				write(key, url.QueryEscape(v))
+			}
+
+		default:
+			escaped := make([]string, len(values))
+			for i, v := range values {
+				escaped[i] = url.QueryEscape(v)
+			}
+			write(key, strings.Join(escaped, ","))
+		}
+	}
+
+	return sb.String()
+}
+
 func noopEscape(s string) string { return s }
 
 func tagFunc(w io.Writer, tag string, m map[string]string, mode int) (int, error) {
@@ -2,7 +2,17 @@ package dialtrace
 
 import (
 	"context"
+	"errors"
 	"net"
+	"time"
+)
+
+// Address families reported by DialInfo.
+const (
+	FamilyIPv4    = "ipv4"
+	FamilyIPv6    = "ipv6"
+	FamilyUnix    = "unix"
+	FamilyUnknown = "unknown"
 )
 
 // DialContextFunc is the interface that wraps the net.Dialer DialContext method.
@@ -35,6 +45,25 @@ type DialerTrace struct {
 
 	// CloseConn is called after a connection is closed.
 	CloseConn func(network, address string)
+
+	// DialDone is called after every dial operation, successful or not, with
+	// its duration and the address family of the connected, or last tried,
+	// remote address.
+	DialDone func(ctx context.Context, info DialInfo)
+}
+
+// DialInfo describes a finished dial operation.
+type DialInfo struct {
+	Network string
+	Address string
+
+	// Family is the address family of the remote address: FamilyIPv4,
+	// FamilyIPv6, FamilyUnix, or FamilyUnknown if the dial failed before
+	// trying any address, for instance on DNS errors.
+	Family string
+
+	Duration time.Duration
+	Err      error
 }
 
 // A tracedDialer contains options for wrapping a dialer DialContext func
@@ -63,7 +92,28 @@ type tracedDialer struct {
 // See func Dial for a description of the network and address
 // parameters.
 func (d *tracedDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	start := time.Now()
 	conn, err := d.dial(ctx, network, address)
+
+	if d.trace.DialDone != nil {
+		var remote net.Addr
+		var opErr *net.OpError
+		switch {
+		case err == nil:
+			remote = conn.RemoteAddr()
+		case errors.As(err, &opErr):
+			remote = opErr.Addr
+		}
+
+		d.trace.DialDone(ctx, DialInfo{
+			Network:  network,
+			Address:  address,
+			Family:   Family(remote),
+			Duration: time.Since(start),
+			Err:      err,
+		})
+	}
+
 	if err != nil {
 		if d.trace.ConnError != nil {
 			d.trace.ConnError(network, address, err)
@@ -99,3 +149,28 @@ func (c *tracedConn) Close() error {
 
 	return c.Conn.Close()
 }
+
+// Family returns the address family of addr, or FamilyUnknown if it can't be
+// told.
+func Family(addr net.Addr) string {
+	var ip net.IP
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		ip = a.IP
+	case *net.UDPAddr:
+		ip = a.IP
+	case *net.IPAddr:
+		ip = a.IP
+	case *net.UnixAddr:
+		return FamilyUnix
+	}
+
+	switch {
+	case ip == nil:
+		return FamilyUnknown
+	case ip.To4() != nil:
+		return FamilyIPv4
+	default:
+		return FamilyIPv6
+	}
+}
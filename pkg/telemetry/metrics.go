@@ -2,9 +2,16 @@ package telemetry
 
 import (
 	"context"
+	"math/rand/v2"
 	"time"
 )
 
+// SampledTimer is implemented by clients which can send timing metrics at a
+// given sample rate, letting the metrics backend scale them accordingly.
+type SampledTimer interface {
+	SampledTiming(name string, value time.Duration, tags []string, rate float64)
+}
+
 // Gauge measures the value of a metric at a particular time.
 func Gauge(ctx context.Context, name string, value float64, tags []string) {
 	FromContext(ctx).Gauge(name, value, tags)
@@ -51,3 +58,20 @@ func Timing(ctx context.Context, name string, value time.Duration, tags []string
 func TimeInMilliseconds(ctx context.Context, name string, value float64, tags []string) {
 	FromContext(ctx).TimeInMilliseconds(name, value, tags)
 }
+
+// SampledTiming sends timing information at the given sample rate, between 0
+// and 1, so that only that fraction of calls actually sends the metric.
+//
+// If the client in ctx does not implement SampledTimer then the metric is
+// sampled before calling Timing.
+func SampledTiming(ctx context.Context, name string, value time.Duration, tags []string, rate float64) {
+	client := FromContext(ctx)
+	if sampled, ok := client.(SampledTimer); ok {
+		sampled.SampledTiming(name, value, tags, rate)
+		return
+	}
+
+	if rate >= 1 || rand.Float64() < rate { //nolint:gosec
+		client.Timing(name, value, tags)
+	}
+}
@@ -84,6 +84,7 @@ var DefaultTracer = NewNoOpClient()
 type client struct {
 	nrApp  *newrelic.Application
 	statsd statsd.ClientInterface
+	rate   float64
 }
 
 var _ Client = (*client)(nil)
@@ -112,6 +113,12 @@ type Config struct {
 	// DatadogAddress is the address of the datadog agent to which statsd must
 	// connect to.
 	DatadogAddress string
+
+	// SampleRate is the rate, between 0 and 1, at which counters, histograms,
+	// distributions and timings are sent to the datadog agent. Gauges and sets
+	// are always sent, as the agent can't scale them. Default is 1, which
+	// sends all of them.
+	SampleRate float64
 }
 
 // NewClient returns a new client connected to all tracing providers.
@@ -147,9 +154,15 @@ func NewClient(cfg Config) (Client, error) {
 		return nil, err
 	}
 
+	rate := cfg.SampleRate
+	if rate <= 0 || rate > 1 {
+		rate = _defaultRate
+	}
+
 	return &client{
 		nrApp:  nrApp,
 		statsd: s,
+		rate:   rate,
 	}, nil
 }
 
@@ -160,6 +173,7 @@ func NewNoOpClient() Client {
 	return &client{
 		statsd: &statsd.NoOpClient{},
 		nrApp:  nrApp,
+		rate:   _defaultRate,
 	}
 }
 
@@ -224,27 +238,27 @@ func (c *client) Gauge(name string, value float64, tags []string) {
 
 // Count tracks how many times something happened per second.
 func (c *client) Count(name string, value int64, tags []string) {
-	_ = c.statsd.Count(name, value, tags, _defaultRate)
+	_ = c.statsd.Count(name, value, tags, c.rate)
 }
 
 // Incr is just Count of 1.
 func (c *client) Incr(name string, tags []string) {
-	_ = c.statsd.Incr(name, tags, _defaultRate)
+	_ = c.statsd.Incr(name, tags, c.rate)
 }
 
 // Decr is just Count of -1.
 func (c *client) Decr(name string, tags []string) {
-	_ = c.statsd.Decr(name, tags, _defaultRate)
+	_ = c.statsd.Decr(name, tags, c.rate)
 }
 
 // Histogram tracks the statistical distribution of a set of values on each host.
 func (c *client) Histogram(name string, value float64, tags []string) {
-	_ = c.statsd.Histogram(name, value, tags, _defaultRate)
+	_ = c.statsd.Histogram(name, value, tags, c.rate)
 }
 
 // Distribution tracks the statistical distribution of a set of values across your infrastructure.
 func (c *client) Distribution(name string, value float64, tags []string) {
-	_ = c.statsd.Distribution(name, value, tags, _defaultRate)
+	_ = c.statsd.Distribution(name, value, tags, c.rate)
 }
 
 // Set counts the number of unique elements in a group.
@@ -254,12 +268,18 @@ func (c *client) Set(name string, value string, tags []string) {
 
 // Timing sends timing information, it is an alias for TimeInMilliseconds.
 func (c *client) Timing(name string, value time.Duration, tags []string) {
-	_ = c.statsd.Timing(name, value, tags, _defaultRate)
+	_ = c.statsd.Timing(name, value, tags, c.rate)
 }
 
 // TimeInMilliseconds sends timing information in milliseconds.
 // It is flushed by statsd with percentiles, mean and other info
 // (https://github.com/etsy/statsd/blob/master/docs/metric_types.md#timing).
 func (c *client) TimeInMilliseconds(name string, value float64, tags []string) {
-	_ = c.statsd.TimeInMilliseconds(name, value, tags, _defaultRate)
+	_ = c.statsd.TimeInMilliseconds(name, value, tags, c.rate)
+}
+
+// SampledTiming sends timing information at the given sample rate, which is
+// combined with the client sample rate.
+func (c *client) SampledTiming(name string, value time.Duration, tags []string, rate float64) {
+	_ = c.statsd.Timing(name, value, tags, c.rate*rate)
 }
@@ -132,6 +132,24 @@ func RequestID(ctx context.Context) string {
 	return headers.Get(RequestIDHeader)
 }
 
+// WithRequestID returns a copy of ctx whose forwarded headers, as returned by
+// ForwardedHeaders, carry the given request id. If requestID is empty a new
+// one is generated.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	if requestID == "" {
+		requestID = newRequestID()
+	}
+
+	forwarded := ForwardedHeaders(ctx)
+	headers := make(Header, len(forwarded)+1)
+	for k, v := range forwarded {
+		headers[k] = v
+	}
+	headers.Set(RequestIDHeader, requestID)
+
+	return context.WithValue(ctx, tracingKey{}, headers)
+}
+
 // NewFlowStarterContext decorates the given context with a
 // request id and marks it as an internal request.
 func NewFlowStarterContext(ctx context.Context) context.Context {
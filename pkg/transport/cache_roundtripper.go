@@ -1,7 +1,9 @@
 package transport
 
 import (
+	"context"
 	"net/http"
+	"time"
 
 	"github.com/luizaranda/go-core/pkg/transport/internal/httpcache"
 )
@@ -17,17 +19,57 @@ type Cache interface {
 	Delete(key string)
 }
 
+// WithCacheTTL returns a new context which makes requests executed with it
+// consider cached responses fresh for the given duration since their Date
+// header, regardless of the freshness given by the server through the
+// Cache-Control and Expires headers.
+func WithCacheTTL(ctx context.Context, ttl time.Duration) context.Context {
+	return httpcache.WithTTL(ctx, ttl)
+}
+
+// SkipCache returns a new context which makes requests executed with it
+// ignore cached responses and reach the server. The response is still cached
+// for subsequent requests.
+func SkipCache(ctx context.Context) context.Context {
+	return httpcache.WithSkip(ctx)
+}
+
+// CacheOption configures the caching behavior of CacheDecorator.
+type CacheOption func(*httpcache.Transport)
+
+// CacheKeyFunc sets the function used for computing the key under which
+// responses are cached, which by default is given by DefaultCacheKey. It
+// allows, for instance, ignoring tracking query parameters or caching
+// responses per user by including a header in the key.
+func CacheKeyFunc(keyFunc func(*http.Request) string) CacheOption {
+	return func(t *httpcache.Transport) {
+		t.KeyFunc = keyFunc
+	}
+}
+
+// DefaultCacheKey returns the key under which responses to req are cached by
+// default, which is its URL.
+func DefaultCacheKey(req *http.Request) string {
+	return req.URL.String()
+}
+
 // CacheDecorator returns a RoundTripDecorator that provides caching
 // capabilities to the given http.RoundTripper by wrapping RoundTrip calls to
 // return from a cache where possible (avoiding the HTTP request). It will
 // additionally add validators (etag/if-modified-since) to repeated requests
 // allowing servers to return 304 / Not Modified.
-func CacheDecorator(cache Cache) RoundTripDecorator {
+func CacheDecorator(cache Cache, opts ...CacheOption) RoundTripDecorator {
 	return func(base http.RoundTripper) http.RoundTripper {
-		return &httpcache.Transport{
+		t := &httpcache.Transport{
 			Transport:           base,
 			Cache:               cache,
 			MarkCachedResponses: true,
 		}
+
+		for _, opt := range opts {
+			opt(t)
+		}
+
+		return t
 	}
 }
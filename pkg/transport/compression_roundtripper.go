@@ -0,0 +1,149 @@
+package transport
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// EncodingGzip is the gzip content coding as defined in RFC 9110.
+	EncodingGzip = "gzip"
+
+	// EncodingZstd is the zstd content coding as defined in RFC 8878.
+	EncodingZstd = "zstd"
+)
+
+// DefaultCompressionMinSize is the minimum size in bytes a request body must
+// have in order to be compressed by CompressionDecorator. Compressing small
+// bodies usually results in bigger payloads and wasted CPU cycles.
+var DefaultCompressionMinSize int64 = 1024
+
+// CompressionDecorator returns a RoundTripDecorator that compresses request
+// bodies using the given content coding, which must be either EncodingGzip or
+// EncodingZstd, or else this function will panic.
+//
+// For more information check CompressionRoundTripper struct.
+func CompressionDecorator(encoding string, minSize int64) RoundTripDecorator {
+	compress := compressorFor(encoding)
+
+	return func(base http.RoundTripper) http.RoundTripper {
+		return &CompressionRoundTripper{
+			Transport: base,
+			Encoding:  encoding,
+			MinSize:   minSize,
+			compress:  compress,
+		}
+	}
+}
+
+// CompressionRoundTripper is a http.RoundTripper that compresses the body of
+// outgoing requests and sets the Content-Encoding header accordingly.
+//
+// Only bodies of at least MinSize bytes are compressed. Requests without body
+// or that already have a Content-Encoding header are left untouched.
+//
+// The compressed request has a GetBody function which returns the compressed
+// body, so that the request can be safely retried.
+type CompressionRoundTripper struct {
+	Transport http.RoundTripper
+	Encoding  string
+	MinSize   int64
+
+	compress func([]byte) ([]byte, error)
+}
+
+// RoundTrip executes a single HTTP transaction, returning
+// a Response for the provided Request.
+func (t *CompressionRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body == nil || req.Body == http.NoBody || req.Header.Get("Content-Encoding") != "" {
+		return t.Transport.RoundTrip(req)
+	}
+
+	if req.ContentLength > 0 && req.ContentLength < t.MinSize {
+		return t.Transport.RoundTrip(req)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	// Body length could not be known beforehand, so the request is sent with
+	// the original body which we have already consumed.
+	if int64(len(body)) < t.MinSize {
+		return t.Transport.RoundTrip(withBody(req, body))
+	}
+
+	compressed, err := t.compress(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req2 := withBody(req, compressed)
+	req2.Header.Set("Content-Encoding", t.Encoding)
+
+	return t.Transport.RoundTrip(req2)
+}
+
+// withBody returns a shallow copy of req with the given body, and a GetBody
+// function which returns it.
+func withBody(req *http.Request, body []byte) *http.Request {
+	req2 := req.Clone(req.Context())
+	req2.ContentLength = int64(len(body))
+	req2.Body = io.NopCloser(bytes.NewReader(body))
+	req2.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return req2
+}
+
+func compressorFor(encoding string) func([]byte) ([]byte, error) {
+	switch encoding {
+	case EncodingGzip:
+		return gzipCompress
+	case EncodingZstd:
+		return zstdCompress
+	default:
+		panic(fmt.Sprintf("transport: unsupported content coding %q", encoding))
+	}
+}
+
+var _gzipWriterPool = sync.Pool{New: func() any {
+	return gzip.NewWriter(nil)
+}}
+
+func gzipCompress(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w := _gzipWriterPool.Get().(*gzip.Writer)
+	defer _gzipWriterPool.Put(w)
+
+	w.Reset(&buf)
+	if _, err := w.Write(body); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// _zstdEncoder is lazily initialized as it allocates its internal buffers
+// eagerly. Calling EncodeAll on it is safe for concurrent use.
+var _zstdEncoder = sync.OnceValue(func() *zstd.Encoder {
+	enc, _ := zstd.NewWriter(nil)
+	return enc
+})
+
+func zstdCompress(body []byte) ([]byte, error) {
+	return _zstdEncoder().EncodeAll(body, make([]byte, 0, len(body)/2)), nil
+}
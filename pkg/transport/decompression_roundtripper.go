@@ -0,0 +1,169 @@
+package transport
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/luizaranda/go-core/pkg/telemetry"
+	"github.com/luizaranda/go-core/pkg/telemetry/tracing"
+)
+
+const (
+	// EncodingBrotli is the br content coding as defined in RFC 7932.
+	EncodingBrotli = "br"
+
+	_httpResponseBytesSavedMetric = "toolkit.http.client.response.compression.bytes_saved"
+
+	_acceptEncoding = EncodingGzip + ", " + EncodingBrotli + ", " + EncodingZstd
+)
+
+// DecompressionDecorator returns a RoundTripDecorator that provides
+// transparent response decompression to the given http.RoundTripper.
+//
+// For more information check DecompressionRoundTripper struct.
+func DecompressionDecorator() RoundTripDecorator {
+	return func(base http.RoundTripper) http.RoundTripper {
+		return &DecompressionRoundTripper{Transport: base}
+	}
+}
+
+// DecompressionRoundTripper is a http.RoundTripper that advertises support
+// for the gzip, br and zstd content codings and transparently decodes
+// responses encoded with any of them.
+//
+// It behaves like the http.Transport does for gzip: if the caller explicitly
+// sets the Accept-Encoding header then the response is returned untouched.
+// Decoded responses have their Content-Encoding and Content-Length headers
+// removed, and the Uncompressed field set to true.
+//
+// Once a decoded body is fully read, the difference between the decoded and
+// encoded sizes is recorded in the
+// toolkit.http.client.response.compression.bytes_saved metric.
+type DecompressionRoundTripper struct {
+	Transport http.RoundTripper
+}
+
+// RoundTrip executes a single HTTP transaction, returning
+// a Response for the provided Request.
+func (t *DecompressionRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Accept-Encoding") != "" || req.Header.Get("Range") != "" || req.Method == http.MethodHead {
+		return t.Transport.RoundTrip(req)
+	}
+
+	req2 := req.Clone(req.Context())
+	req2.Header.Set("Accept-Encoding", _acceptEncoding)
+
+	res, err := t.Transport.RoundTrip(req2)
+	if err != nil || res.Body == nil || res.Body == http.NoBody {
+		return res, err
+	}
+
+	encoding := strings.ToLower(strings.TrimSpace(res.Header.Get("Content-Encoding")))
+	switch encoding {
+	case EncodingGzip, EncodingBrotli, EncodingZstd:
+	default:
+		return res, nil
+	}
+
+	res.Body = &decodingReadCloser{
+		ctx:      req.Context(),
+		encoding: encoding,
+		encoded:  &countingReader{R: res.Body},
+		body:     res.Body,
+	}
+	res.Header.Del("Content-Encoding")
+	res.Header.Del("Content-Length")
+	res.ContentLength = -1
+	res.Uncompressed = true
+
+	return res, nil
+}
+
+// decodingReadCloser lazily decodes the wrapped body on the first call to
+// Read, as decoders usually read the stream header on creation.
+type decodingReadCloser struct {
+	ctx      context.Context
+	encoding string
+	encoded  *countingReader
+	body     io.Closer
+
+	once    sync.Once
+	decoder io.Reader
+	closer  func()
+	err     error
+	decoded int64
+
+	// recorded tells whether the saved bytes were recorded, since Read may be
+	// called again after returning io.EOF.
+	recorded bool
+}
+
+func (r *decodingReadCloser) Read(p []byte) (int, error) {
+	r.once.Do(r.init)
+	if r.err != nil {
+		return 0, r.err
+	}
+
+	n, err := r.decoder.Read(p)
+	r.decoded += int64(n)
+	if err == io.EOF && !r.recorded {
+		r.recorded = true
+		r.recordBytesSaved()
+	}
+	return n, err
+}
+
+func (r *decodingReadCloser) Close() error {
+	if r.closer != nil {
+		r.closer()
+	}
+	return r.body.Close()
+}
+
+func (r *decodingReadCloser) init() {
+	switch r.encoding {
+	case EncodingGzip:
+		zr, err := gzip.NewReader(r.encoded)
+		r.decoder, r.err = zr, err
+	case EncodingBrotli:
+		r.decoder = brotli.NewReader(r.encoded)
+	case EncodingZstd:
+		zr, err := zstd.NewReader(r.encoded, zstd.WithDecoderConcurrency(1))
+		if err == nil {
+			r.closer = zr.Close
+		}
+		r.decoder, r.err = zr, err
+	}
+}
+
+func (r *decodingReadCloser) recordBytesSaved() {
+	saved := r.decoded - r.encoded.N
+	if saved < 0 {
+		saved = 0
+	}
+
+	tags := []string{"technology:go", "encoding:" + r.encoding}
+	if targetID := tracing.TargetID(r.ctx); targetID != "" {
+		tags = append(tags, "target_id:"+telemetry.SanitizeMetricTagValue(targetID))
+	}
+
+	telemetry.Histogram(r.ctx, _httpResponseBytesSavedMetric, float64(saved), tags)
+}
+
+// countingReader is an io.Reader that counts the number of bytes read from R.
+type countingReader struct {
+	R io.Reader
+	N int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.R.Read(p)
+	c.N += int64(n)
+	return n, err
+}
@@ -0,0 +1,215 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/luizaranda/go-core/pkg/telemetry"
+)
+
+const (
+	_dnsCacheMetric = "toolkit.http.client.dns.cache"
+
+	_defaultDNSCacheTTL         = 30 * time.Second
+	_defaultDNSCacheNegativeTTL = 5 * time.Second
+)
+
+// DNSCacheConfig configures a DNSCache.
+type DNSCacheConfig struct {
+	// TTL is the amount of time a successful lookup is considered fresh.
+	// Default is 30 seconds.
+	TTL time.Duration
+
+	// NegativeTTL is the amount of time a lookup that failed because the
+	// host does not exist is cached. Default is 5 seconds. A negative value
+	// disables negative caching.
+	NegativeTTL time.Duration
+
+	// Resolver is the resolver used for looking up hosts. Default is
+	// net.DefaultResolver.
+	Resolver *net.Resolver
+}
+
+// DNSCache is a DNS cache that can be used by the transport dialer to avoid
+// resolving hosts on every new connection. It is safe for concurrent use, and
+// can be shared among many transports.
+//
+// Hosts whose entry is no longer fresh, but has not been expired for more than
+// TTL, are answered from the cache while they are refreshed asynchronously, so
+// that dials never wait for lookups of frequently used hosts.
+//
+// Every lookup records the toolkit.http.client.dns.cache metric, tagged with
+// its result: hit, stale, negative_hit or miss.
+type DNSCache struct {
+	ttl         time.Duration
+	negativeTTL time.Duration
+	resolver    *net.Resolver
+
+	mu        sync.Mutex // guards the fields below
+	entries   map[string]*dnsCacheEntry
+	lastPrune time.Time
+}
+
+type dnsCacheEntry struct {
+	addrs      []string
+	err        error
+	expiresAt  time.Time
+	refreshing bool
+}
+
+// NewDNSCache returns a DNSCache configured with the given config.
+func NewDNSCache(config DNSCacheConfig) *DNSCache {
+	if config.TTL <= 0 {
+		config.TTL = _defaultDNSCacheTTL
+	}
+
+	if config.NegativeTTL == 0 {
+		config.NegativeTTL = _defaultDNSCacheNegativeTTL
+	}
+
+	if config.Resolver == nil {
+		config.Resolver = net.DefaultResolver
+	}
+
+	return &DNSCache{
+		ttl:         config.TTL,
+		negativeTTL: config.NegativeTTL,
+		resolver:    config.Resolver,
+		entries:     make(map[string]*dnsCacheEntry),
+		lastPrune:   time.Now(),
+	}
+}
+
+// OptionDNSCache makes the transport dialer resolve hosts using the given
+// DNSCache. Dials to hosts with many addresses try each of them in order
+// until one succeeds.
+func OptionDNSCache(cache *DNSCache) Option {
+	return transportOptFunc(func(t *http.Transport) {
+		t.DialContext = cache.dialContext(t.DialContext)
+	})
+}
+
+// LookupHost looks up the given host, returning a slice of its addresses.
+func (c *DNSCache) LookupHost(ctx context.Context, host string) ([]string, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[host]
+	switch {
+	case ok && now.Before(entry.expiresAt):
+		c.mu.Unlock()
+
+		result := "hit"
+		if entry.err != nil {
+			result = "negative_hit"
+		}
+		recordDNSCache(ctx, result)
+		return entry.addrs, entry.err
+
+	case ok && entry.err == nil && now.Before(entry.expiresAt.Add(c.ttl)):
+		if !entry.refreshing {
+			entry.refreshing = true
+			go c.refresh(context.WithoutCancel(ctx), host)
+		}
+		c.mu.Unlock()
+
+		recordDNSCache(ctx, "stale")
+		return entry.addrs, nil
+	}
+	c.mu.Unlock()
+
+	recordDNSCache(ctx, "miss")
+	return c.lookup(ctx, host)
+}
+
+func (c *DNSCache) refresh(ctx context.Context, host string) {
+	ctx, cancel := context.WithTimeout(ctx, c.ttl)
+	defer cancel()
+
+	if _, err := c.lookup(ctx, host); err != nil {
+		// Let the next lookup retry the refresh.
+		c.mu.Lock()
+		if entry, ok := c.entries[host]; ok {
+			entry.refreshing = false
+		}
+		c.mu.Unlock()
+	}
+}
+
+func (c *DNSCache) lookup(ctx context.Context, host string) ([]string, error) {
+	addrs, err := c.resolver.LookupHost(ctx, host)
+
+	var entry *dnsCacheEntry
+	var dnsErr *net.DNSError
+	switch {
+	case err == nil:
+		entry = &dnsCacheEntry{addrs: addrs, expiresAt: time.Now().Add(c.ttl)}
+	case errors.As(err, &dnsErr) && dnsErr.IsNotFound && c.negativeTTL > 0:
+		entry = &dnsCacheEntry{err: err, expiresAt: time.Now().Add(c.negativeTTL)}
+	default:
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[host] = entry
+	c.prune()
+	c.mu.Unlock()
+
+	return addrs, err
+}
+
+// prune removes entries that can't be served anymore. It must be called with
+// c.mu held.
+func (c *DNSCache) prune() {
+	now := time.Now()
+	if now.Sub(c.lastPrune) < c.ttl {
+		return
+	}
+	c.lastPrune = now
+
+	for host, entry := range c.entries {
+		if now.After(entry.expiresAt.Add(c.ttl)) {
+			delete(c.entries, host)
+		}
+	}
+}
+
+func (c *DNSCache) dialContext(dial func(ctx context.Context, network, address string) (net.Conn, error)) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil || net.ParseIP(host) != nil {
+			return dial(ctx, network, address)
+		}
+
+		addrs, err := c.LookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(addrs) == 0 {
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+
+		var conn net.Conn
+		for _, addr := range addrs {
+			conn, err = dial(ctx, network, net.JoinHostPort(addr, port))
+			if err == nil {
+				return conn, nil
+			}
+
+			if ctx.Err() != nil {
+				break
+			}
+		}
+
+		return nil, err
+	}
+}
+
+func recordDNSCache(ctx context.Context, result string) {
+	telemetry.Incr(ctx, _dnsCacheMetric, []string{"technology:go", "result:" + result})
+}
@@ -0,0 +1,103 @@
+package transport
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/luizaranda/go-core/pkg/telemetry"
+	"github.com/luizaranda/go-core/pkg/telemetry/tracing"
+)
+
+const _failoverRequestMetric = "toolkit.http.client.failover.request"
+
+// DefaultFailoverCheckFunc reports whether a request must be failed over,
+// which happens on transport errors and 5xx responses.
+func DefaultFailoverCheckFunc(res *http.Response, err error) bool {
+	return err != nil || res.StatusCode >= 500
+}
+
+// FailoverDecorator returns a RoundTripDecorator that executes requests using
+// the fallback http.RoundTripper when the base one fails, as reported by
+// shouldFailover. If shouldFailover is nil then DefaultFailoverCheckFunc is
+// used.
+//
+// For more information check FailoverRoundTripper struct.
+func FailoverDecorator(fallback http.RoundTripper, shouldFailover func(*http.Response, error) bool) RoundTripDecorator {
+	if shouldFailover == nil {
+		shouldFailover = DefaultFailoverCheckFunc
+	}
+
+	return func(base http.RoundTripper) http.RoundTripper {
+		return &FailoverRoundTripper{
+			Transport:      base,
+			Fallback:       fallback,
+			ShouldFailover: shouldFailover,
+		}
+	}
+}
+
+// FailoverRoundTripper is a http.RoundTripper that executes requests using
+// Transport, and executes them again using Fallback when ShouldFailover
+// reports the primary attempt failed. Fallback receives a copy of the original
+// request, so it's responsible for routing it to the secondary host or region,
+// for instance by rewriting its URL.
+//
+// Requests whose context is done are never failed over. Every attempt records
+// the toolkit.http.client.failover.request metric, tagged with the route
+// taken, primary or fallback, and its status.
+type FailoverRoundTripper struct {
+	Transport      http.RoundTripper
+	Fallback       http.RoundTripper
+	ShouldFailover func(*http.Response, error) bool
+}
+
+// RoundTrip executes a single HTTP transaction, returning
+// a Response for the provided Request.
+func (t *FailoverRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	// The body is consumed by the primary attempt, so the fallback one needs
+	// a way of getting a fresh copy of it.
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		body, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req = withBody(req, body)
+	}
+
+	res, err := t.Transport.RoundTrip(req)
+	recordFailover(req, "primary", res, err)
+
+	if req.Context().Err() != nil || !t.ShouldFailover(res, err) {
+		return res, err
+	}
+
+	req2 := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, bodyErr := req.GetBody()
+		if bodyErr != nil {
+			return res, err
+		}
+		req2.Body = body
+	}
+
+	if err == nil {
+		_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 4096))
+		_ = res.Body.Close()
+	}
+
+	res, err = t.Fallback.RoundTrip(req2)
+	recordFailover(req, "fallback", res, err)
+
+	return res, err
+}
+
+func recordFailover(req *http.Request, route string, res *http.Response, err error) {
+	ctx := req.Context()
+	telemetry.Incr(ctx, _failoverRequestMetric, []string{
+		"technology:go",
+		"target_id:" + telemetry.SanitizeMetricTagValue(tracing.TargetID(ctx)),
+		"route:" + route,
+		"status:" + mirrorStatus(res, err),
+	})
+}
@@ -0,0 +1,52 @@
+package transport
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// HostOverrideDecorator returns a RoundTripDecorator that allows redirecting
+// requests to a different host.
+//
+// For more information check HostOverrideRoundTripper struct.
+func HostOverrideDecorator(override func(*http.Request) string) RoundTripDecorator {
+	return func(base http.RoundTripper) http.RoundTripper {
+		return &HostOverrideRoundTripper{
+			Transport: base,
+			Override:  override,
+		}
+	}
+}
+
+// HostOverrideRoundTripper sends requests to the host returned by Override,
+// in the host or host:port form, instead of the one in the request URL. If
+// Override returns an empty string then the request is sent unmodified.
+//
+// Overridden requests keep the original Host header and context, so servers
+// and telemetry see them as if they were sent to the original host. This
+// enables client-side canarying without the need of a proxy.
+type HostOverrideRoundTripper struct {
+	Transport http.RoundTripper
+	Override  func(*http.Request) string
+}
+
+// RoundTrip executes a single HTTP transaction, returning
+// a Response for the provided Request.
+func (t *HostOverrideRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := t.Override(req)
+	if host == "" || host == req.URL.Host {
+		return t.Transport.RoundTrip(req)
+	}
+
+	req2 := new(http.Request)
+	*req2 = *req
+	req2.URL = new(url.URL)
+	*req2.URL = *req.URL
+	req2.URL.Host = host
+
+	if req2.Host == "" {
+		req2.Host = req.URL.Host
+	}
+
+	return t.Transport.RoundTrip(req2)
+}
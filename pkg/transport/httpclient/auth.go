@@ -0,0 +1,175 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/luizaranda/go-core/pkg/telemetry"
+	"github.com/luizaranda/go-core/pkg/telemetry/tracing"
+	"github.com/luizaranda/go-core/pkg/transport"
+)
+
+const (
+	_authErrorMetric = "toolkit.http.client.auth.error"
+
+	_authSchemeBearer = "bearer"
+	_authSchemeBasic  = "basic"
+
+	// DefaultTokenExpiryLeeway is the time before a token expiry at which
+	// the token returned by NewTokenSource is considered expired and is
+	// refreshed.
+	DefaultTokenExpiryLeeway = 10 * time.Second
+)
+
+// TokenSource returns the token to be sent in the Authorization header of an
+// outgoing request. It is called before every request attempt, including
+// retries, so implementations are expected to cache tokens and refresh them
+// when needed. Use NewTokenSource for an implementation that does so.
+type TokenSource func(ctx context.Context) (string, error)
+
+// StaticToken returns a TokenSource that always returns the given token.
+func StaticToken(token string) TokenSource {
+	return func(context.Context) (string, error) {
+		return token, nil
+	}
+}
+
+// Token is a credential with an optional expiry time.
+type Token struct {
+	// Value is the token value to be sent in the Authorization header.
+	Value string
+
+	// Expiry is the time at which the token stops being valid. A zero
+	// value means the token never expires.
+	Expiry time.Time
+}
+
+// TokenFetchFunc retrieves a new Token from an authorization server.
+type TokenFetchFunc func(ctx context.Context) (Token, error)
+
+// NewTokenSource returns a TokenSource that caches the Token returned by fetch
+// and transparently calls fetch again once the token is about to expire,
+// as defined by DefaultTokenExpiryLeeway.
+//
+// It is safe for concurrent use. Concurrent requests that find the token
+// expired wait for a single call to fetch.
+func NewTokenSource(fetch TokenFetchFunc) TokenSource {
+	c := &tokenCache{fetch: fetch, leeway: DefaultTokenExpiryLeeway}
+	return c.Token
+}
+
+// tokenCache holds the last Token returned by fetch.
+type tokenCache struct {
+	fetch  TokenFetchFunc
+	leeway time.Duration
+
+	mu    sync.Mutex // guards token
+	token *Token
+}
+
+// Token returns the cached token value, or fetches a new one if there's no
+// cached token or if it is about to expire.
+func (c *tokenCache) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != nil && c.valid(c.token) {
+		return c.token.Value, nil
+	}
+
+	token, err := c.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	c.token = &token
+	return token.Value, nil
+}
+
+func (c *tokenCache) valid(t *Token) bool {
+	if t.Value == "" {
+		return false
+	}
+
+	if t.Expiry.IsZero() {
+		return true
+	}
+
+	return time.Now().Add(c.leeway).Before(t.Expiry)
+}
+
+// WithBearerToken authenticates every outgoing request by setting the
+// Authorization header to "Bearer <token>", where token is the value
+// returned by tokenSource.
+//
+// If tokenSource returns an error the request is not executed and the error is
+// returned to the caller. Requests that already contain an Authorization
+// header are left untouched.
+//
+// Both token source errors and 401 responses are recorded in the
+// toolkit.http.client.auth.error metric.
+func WithBearerToken(tokenSource TokenSource) Option {
+	return optFunc(func(options *clientOptions) {
+		options.ReqHooks = append(options.ReqHooks, bearerTokenRequestHook(tokenSource))
+		options.ResHooks = append(options.ResHooks, authFailureResponseHook(_authSchemeBearer))
+	})
+}
+
+// WithBasicAuth authenticates every outgoing request by setting the
+// Authorization header with the given username and password using HTTP Basic
+// Authentication. Requests that already contain an Authorization header are
+// left untouched.
+//
+// 401 responses are recorded in the toolkit.http.client.auth.error metric.
+func WithBasicAuth(username, password string) Option {
+	return optFunc(func(options *clientOptions) {
+		options.ReqHooks = append(options.ReqHooks, basicAuthRequestHook(username, password))
+		options.ResHooks = append(options.ResHooks, authFailureResponseHook(_authSchemeBasic))
+	})
+}
+
+func bearerTokenRequestHook(tokenSource TokenSource) transport.RequestHook {
+	return func(req *http.Request) error {
+		if req.Header.Get("Authorization") != "" {
+			return nil
+		}
+
+		token, err := tokenSource(req.Context())
+		if err != nil {
+			recordAuthError(req, _authSchemeBearer, "token_source")
+			return fmt.Errorf("httpclient: obtaining bearer token: %w", err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+}
+
+func basicAuthRequestHook(username, password string) transport.RequestHook {
+	return func(req *http.Request) error {
+		if req.Header.Get("Authorization") == "" {
+			req.SetBasicAuth(username, password)
+		}
+		return nil
+	}
+}
+
+func authFailureResponseHook(scheme string) transport.ResponseHook {
+	return func(req *http.Request, res *http.Response, err error) {
+		if err == nil && res.StatusCode == http.StatusUnauthorized {
+			recordAuthError(req, scheme, "unauthorized")
+		}
+	}
+}
+
+func recordAuthError(req *http.Request, scheme, reason string) {
+	telemetry.Incr(req.Context(), _authErrorMetric, telemetry.Tags(
+		"technology", "go",
+		"target_id", telemetry.SanitizeMetricTagValue(tracing.TargetID(req.Context())),
+		"scheme", scheme,
+		"reason", reason,
+	))
+}
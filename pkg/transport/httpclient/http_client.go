@@ -35,17 +35,27 @@ type clientOptions struct {
 	Timeout           time.Duration
 	CheckRedirect     CheckRedirectFunc
 	Transport         *transport.PooledTransport
+	HTTP3Transport    *transport.PooledHTTP3Transport
 	ReqHooks          []transport.RequestHook
 	ResHooks          []transport.ResponseHook
 	Cache             transport.Cache
+	CacheKeyFunc      func(*http.Request) string
 	CircuitBreaker    transport.CircuitBreaker
+	Limiter           *transport.ConcurrencyLimiter
 	EnableClientTrace bool
+	MetricsSampleRate float64
+
+	RequestCompression string
+	MaxResponseBytes   int64
+	HostOverride       func(*http.Request) string
 }
 
 type retryOptions struct {
 	clientOptions
-	BackoffStrategy BackoffFunc
-	CheckRetry      CheckRetryFunc
+	BackoffStrategy  BackoffFunc
+	CheckRetry       CheckRetryFunc
+	IgnoreRetryAfter bool
+	MaxRetryAfter    time.Duration
 }
 
 // Option signature for client configurable parameters.
@@ -80,6 +90,17 @@ func WithTransport(t *transport.PooledTransport) Option {
 	})
 }
 
+// WithHTTP3Transport makes the client execute requests using HTTP/3 over the
+// given transport instead of the one given by WithTransport. All other client
+// features, such as telemetry, hooks and circuit breaking, remain the same.
+//
+// A transport can be built by using transport.NewPooledHTTP3.
+func WithHTTP3Transport(t *transport.PooledHTTP3Transport) Option {
+	return optFunc(func(options *clientOptions) {
+		options.HTTP3Transport = t
+	})
+}
+
 // DisableTimeout disables the timeout for outgoing requests.
 //
 // Requests may still timeout if Requester needs to establish a new TCP conn as
@@ -156,6 +177,19 @@ func WithCache(cache transport.Cache) Option {
 	})
 }
 
+// WithCacheKeyFunc allows the user to set how the keys under which responses
+// are cached are computed. By default, responses are cached by their request
+// URL, as returned by transport.DefaultCacheKey.
+//
+// This allows, for instance, ignoring tracking query parameters, or keeping
+// different cached responses per user by including a hash of the credentials
+// in the key.
+func WithCacheKeyFunc(keyFunc func(*http.Request) string) Option {
+	return optFunc(func(options *clientOptions) {
+		options.CacheKeyFunc = keyFunc
+	})
+}
+
 // WithCircuitBreaker allows the user to set the circuit breaker to use in the
 // httpclient. Requests will be bucketed in the circuit breaker based on their
 // `tracing.EndpointTemplate` value.
@@ -165,6 +199,17 @@ func WithCircuitBreaker(cb transport.CircuitBreaker) Option {
 	})
 }
 
+// WithConcurrencyLimiter allows the user to limit the number of requests in
+// flight per target_id, using a limit which adapts to the target latency.
+// Requests exceeding the limit fail with transport.ErrConcurrencyLimit.
+//
+// The same limiter can be given to many clients so that they share limits.
+func WithConcurrencyLimiter(limiter *transport.ConcurrencyLimiter) Option {
+	return optFunc(func(options *clientOptions) {
+		options.Limiter = limiter
+	})
+}
+
 // WithEnableClientTrace enables the tracing of low level metrics
 // of the HTTP requests performed by the httpclient.
 func WithEnableClientTrace() Option {
@@ -173,6 +218,55 @@ func WithEnableClientTrace() Option {
 	})
 }
 
+// WithMetricsSampleRate makes the client send timing metrics, such as
+// toolkit.http.client.request.time, at the given sample rate, between 0 and
+// 1, instead of sending all of them. It's meant for high throughput clients
+// for which the volume of metrics sent is a concern.
+func WithMetricsSampleRate(rate float64) Option {
+	return optFunc(func(options *clientOptions) {
+		options.MetricsSampleRate = rate
+	})
+}
+
+// WithRequestCompression compresses the body of outgoing requests using the
+// given content coding, which must be either transport.EncodingGzip or
+// transport.EncodingZstd.
+//
+// Only bodies of at least transport.DefaultCompressionMinSize bytes are
+// compressed. Compressed requests can still be retried, as their body is
+// rewound by using http.Request.GetBody.
+func WithRequestCompression(encoding string) Option {
+	return optFunc(func(options *clientOptions) {
+		options.RequestCompression = encoding
+	})
+}
+
+// WithMaxResponseBytes limits the size of response bodies to n bytes, so that
+// unexpectedly big responses don't exhaust the application memory. Reading
+// past the limit fails with a *transport.ResponseTooLargeError, as do
+// responses whose Content-Length exceeds it.
+//
+// The limit applies to decoded bodies, so compressed responses are limited by
+// their uncompressed size. A value of 0 disables the limit.
+func WithMaxResponseBytes(n int64) Option {
+	return optFunc(func(options *clientOptions) {
+		options.MaxResponseBytes = n
+	})
+}
+
+// WithHostOverride sends requests to the host returned by the given function,
+// in the host or host:port form, instead of the one in their URL. Requests for
+// which the function returns an empty string are sent unmodified.
+//
+// Overridden requests keep their original Host header and target_id, so that
+// a percentage of them can be routed to a canary host without affecting
+// telemetry.
+func WithHostOverride(override func(*http.Request) string) Option {
+	return optFunc(func(options *clientOptions) {
+		options.HostOverride = override
+	})
+}
+
 // WithBackoffStrategy controls the wait time between requests when retrying.
 func WithBackoffStrategy(strategy BackoffFunc) OptionRetryable {
 	return retryableOptFunc(func(options *retryOptions) {
@@ -187,6 +281,20 @@ func WithRetryPolicy(checkRetry CheckRetryFunc) OptionRetryable {
 	})
 }
 
+// WithRetryAfter controls whether the client honors the Retry-After header of
+// 429 and 503 responses when waiting between retries. If respect is false then
+// BackoffStrategy is always used.
+//
+// When honored, the server-suggested wait is capped to max, recording the
+// toolkit.http.client.request.retry_after.truncated metric when it's exceeded.
+// A max of 0 means no cap.
+func WithRetryAfter(respect bool, max time.Duration) OptionRetryable {
+	return retryableOptFunc(func(options *retryOptions) {
+		options.IgnoreRetryAfter = !respect
+		options.MaxRetryAfter = max
+	})
+}
+
 var (
 	// DefaultTimeout is the timeout used by default when building a Client.
 	DefaultTimeout = 3 * time.Second
@@ -200,6 +308,10 @@ var (
 	// Default is to not follow HTTP redirects.
 	DefaultCheckRedirect = CheckRedirectFunc(NoRedirect)
 
+	// DefaultMaxRetryAfter is the maximum time a retryable client waits by
+	// default when honoring a Retry-After header.
+	DefaultMaxRetryAfter = 30 * time.Second
+
 	// DefaultRetryPolicy is the function that tells on any given request if the
 	// client should retry it or not. By default, it retries on connection and 5xx errors only.
 	DefaultRetryPolicy = ServerErrorsRetryPolicy()
@@ -243,6 +355,7 @@ func NewRetryable(retryMax int, opts ...OptionRetryable) *RetryableClient {
 	config := retryOptions{
 		BackoffStrategy: DefaultBackoffStrategy,
 		CheckRetry:      DefaultRetryPolicy,
+		MaxRetryAfter:   DefaultMaxRetryAfter,
 		clientOptions: clientOptions{
 			Timeout:       DefaultTimeout,
 			CheckRedirect: DefaultCheckRedirect,
@@ -257,9 +370,11 @@ func NewRetryable(retryMax int, opts ...OptionRetryable) *RetryableClient {
 	}
 
 	return &RetryableClient{
-		RetryMax:        retryMax,
-		BackoffStrategy: config.BackoffStrategy,
-		CheckRetry:      config.CheckRetry,
+		RetryMax:         retryMax,
+		BackoffStrategy:  config.BackoffStrategy,
+		CheckRetry:       config.CheckRetry,
+		IgnoreRetryAfter: config.IgnoreRetryAfter,
+		MaxRetryAfter:    config.MaxRetryAfter,
 		Client: &http.Client{
 			Timeout:       config.Timeout,
 			CheckRedirect: config.CheckRedirect,
@@ -272,15 +387,31 @@ func roundTripper(config *clientOptions) http.RoundTripper {
 	chain := transport.RoundTripChain{transport.UserAgentDecorator()}
 
 	if config.Cache != nil {
-		chain = append(chain, transport.CacheDecorator(config.Cache))
+		var opts []transport.CacheOption
+		if config.CacheKeyFunc != nil {
+			opts = append(opts, transport.CacheKeyFunc(config.CacheKeyFunc))
+		}
+
+		chain = append(chain, transport.CacheDecorator(config.Cache, opts...))
+	}
+
+	// Compression happens before executing hooks, so that they can inspect
+	// the body as it will be sent over the wire.
+	if config.RequestCompression != "" {
+		chain = append(chain, transport.CompressionDecorator(config.RequestCompression, transport.DefaultCompressionMinSize))
 	}
 
 	chain = append(chain, transport.HookDecorator(config.ReqHooks, config.ResHooks))
 
+	var traceOpts []transport.TraceOption
+	if config.MetricsSampleRate > 0 {
+		traceOpts = append(traceOpts, transport.TraceSampleRate(config.MetricsSampleRate))
+	}
+
 	if config.EnableClientTrace {
-		chain = append(chain, transport.ExtendedTraceDecorator())
+		chain = append(chain, transport.ExtendedTraceDecorator(traceOpts...))
 	} else {
-		chain = append(chain, transport.TraceDecorator())
+		chain = append(chain, transport.TraceDecorator(traceOpts...))
 	}
 
 	if config.CircuitBreaker != nil {
@@ -298,10 +429,34 @@ func roundTripper(config *clientOptions) http.RoundTripper {
 		))
 	}
 
+	if config.Limiter != nil {
+		chain = append(chain, transport.ConcurrencyLimitDecorator(config.Limiter))
+	}
+
+	// Limits apply to decoded bodies, so it goes before DecompressionDecorator.
+	if config.MaxResponseBytes > 0 {
+		chain = append(chain, transport.ResponseLimitDecorator(config.MaxResponseBytes))
+	}
+
+	// DecompressionDecorator goes after telemetry decorators so that the
+	// response body they observe is the decoded one.
+	chain = append(chain, transport.DecompressionDecorator())
+
 	// OpenTelemetryDecorator must be last to avoid conflict with the TraceDecorator
 	chain = append(chain, transport.OpenTelemetryDecorator())
 
-	return chain.Apply(config.Transport)
+	// HostOverrideDecorator goes after every telemetry decorator so that they
+	// observe the request as it was built by the caller.
+	if config.HostOverride != nil {
+		chain = append(chain, transport.HostOverrideDecorator(config.HostOverride))
+	}
+
+	var base http.RoundTripper = config.Transport
+	if config.HTTP3Transport != nil {
+		base = config.HTTP3Transport
+	}
+
+	return chain.Apply(base)
 }
 
 // ForwardTracingHeadersRequestHook adds to the outgoing request any headers
@@ -0,0 +1,10 @@
+/*
+Package `httpclienttest` provides utilities for testing code which executes HTTP requests by using a
+`httpclient.Requester`, without reaching real servers.
+
+It provides two `http.RoundTripper` implementations, both of which are also `Requester`s:
+
+- `Mock`: An expectation based mock, for example `mock.ExpectGET("/users/{id}").RespondJSON(200, user)`.
+- `Recorder`: A VCR-style transport which records real responses into a cassette file, and replays them afterwards.
+*/
+package httpclienttest
@@ -0,0 +1,245 @@
+package httpclienttest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var _ http.RoundTripper = (*Mock)(nil)
+
+// Mock is an expectation based http.RoundTripper, which also implements
+// httpclient.Requester. Requests are answered by the first pending expectation
+// matching their method and path, or fail with an error if there is none.
+//
+// Use Client for building an *http.Client backed by the Mock.
+type Mock struct {
+	t testing.TB
+
+	mu           sync.Mutex // guards the fields below
+	expectations []*Expectation
+}
+
+// NewMock returns a new Mock. Once the test finishes, it's failed if any of
+// the expectations set on the Mock was not fulfilled.
+func NewMock(t testing.TB) *Mock {
+	m := &Mock{t: t}
+	t.Cleanup(m.AssertExpectations)
+	return m
+}
+
+// ExpectGET adds an expectation for a GET request to the given path.
+func (m *Mock) ExpectGET(path string) *Expectation { return m.Expect(http.MethodGet, path) }
+
+// ExpectHEAD adds an expectation for a HEAD request to the given path.
+func (m *Mock) ExpectHEAD(path string) *Expectation { return m.Expect(http.MethodHead, path) }
+
+// ExpectPOST adds an expectation for a POST request to the given path.
+func (m *Mock) ExpectPOST(path string) *Expectation { return m.Expect(http.MethodPost, path) }
+
+// ExpectPUT adds an expectation for a PUT request to the given path.
+func (m *Mock) ExpectPUT(path string) *Expectation { return m.Expect(http.MethodPut, path) }
+
+// ExpectPATCH adds an expectation for a PATCH request to the given path.
+func (m *Mock) ExpectPATCH(path string) *Expectation { return m.Expect(http.MethodPatch, path) }
+
+// ExpectDELETE adds an expectation for a DELETE request to the given path.
+func (m *Mock) ExpectDELETE(path string) *Expectation { return m.Expect(http.MethodDelete, path) }
+
+// Expect adds an expectation for a request with the given method and path.
+//
+// Path segments in the {name} form match any value, so "/users/{id}" matches
+// both "/users/1" and "/users/2". The query string of requests is ignored
+// unless WithQuery is used.
+//
+// By default expectations are fulfilled by a single request, and respond with
+// an empty 200 OK response.
+func (m *Mock) Expect(method, path string) *Expectation {
+	e := &Expectation{
+		method:   method,
+		path:     strings.Split(strings.Trim(path, "/"), "/"),
+		template: path,
+		times:    1,
+		status:   http.StatusOK,
+		header:   make(http.Header),
+	}
+
+	m.mu.Lock()
+	m.expectations = append(m.expectations, e)
+	m.mu.Unlock()
+
+	return e
+}
+
+// Do executes the given request against the Mock expectations.
+func (m *Mock) Do(req *http.Request) (*http.Response, error) {
+	return m.RoundTrip(req)
+}
+
+// RoundTrip executes the given request against the Mock expectations.
+func (m *Mock) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		defer req.Body.Close()
+	}
+
+	m.mu.Lock()
+	var match *Expectation
+	for _, e := range m.expectations {
+		if e.calls < e.times && e.matches(req) {
+			e.calls++
+			match = e
+			break
+		}
+	}
+	m.mu.Unlock()
+
+	if match == nil {
+		return nil, fmt.Errorf("httpclienttest: unexpected request %s %s", req.Method, req.URL.Path)
+	}
+
+	return match.response(req)
+}
+
+// Client returns an *http.Client which executes requests against the Mock.
+func (m *Mock) Client() *http.Client {
+	return &http.Client{Transport: m}
+}
+
+// AssertExpectations fails the test if any expectation was not fulfilled. It's
+// called automatically when the test finishes.
+func (m *Mock) AssertExpectations() {
+	m.t.Helper()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, e := range m.expectations {
+		if e.calls < e.times {
+			m.t.Errorf("httpclienttest: expected %d %s %s requests, got %d", e.times, e.method, e.template, e.calls)
+		}
+	}
+}
+
+// Expectation is a request expected by a Mock, together with the response
+// it must get.
+type Expectation struct {
+	method   string
+	path     []string
+	template string
+	query    map[string]string
+	times    int
+	calls    int
+
+	status int
+	header http.Header
+	body   []byte
+	err    error
+}
+
+// WithQuery makes the expectation match only requests with the given query
+// string parameter value.
+func (e *Expectation) WithQuery(key, value string) *Expectation {
+	if e.query == nil {
+		e.query = make(map[string]string)
+	}
+	e.query[key] = value
+	return e
+}
+
+// Times sets the number of requests needed to fulfill the expectation.
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+// Respond sets the status code and body of the response.
+func (e *Expectation) Respond(status int, body string) *Expectation {
+	e.status = status
+	e.body = []byte(body)
+	return e
+}
+
+// RespondJSON sets the status code of the response, and its body to the JSON
+// encoding of v. This method panics if v can't be encoded.
+func (e *Expectation) RespondJSON(status int, v any) *Expectation {
+	body, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("httpclienttest: encoding response: %v", err))
+	}
+
+	e.status = status
+	e.body = body
+	e.header.Set("Content-Type", "application/json")
+	return e
+}
+
+// RespondHeader sets a header of the response.
+func (e *Expectation) RespondHeader(key, value string) *Expectation {
+	e.header.Set(key, value)
+	return e
+}
+
+// RespondError makes requests matching the expectation fail with the given
+// error instead of getting a response.
+func (e *Expectation) RespondError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+func (e *Expectation) matches(req *http.Request) bool {
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	if method != e.method {
+		return false
+	}
+
+	path := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if len(path) != len(e.path) {
+		return false
+	}
+
+	for i, segment := range e.path {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			continue
+		}
+
+		if segment != path[i] {
+			return false
+		}
+	}
+
+	query := req.URL.Query()
+	for key, value := range e.query {
+		if query.Get(key) != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (e *Expectation) response(req *http.Request) (*http.Response, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.status, http.StatusText(e.status)),
+		StatusCode:    e.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}, nil
+}
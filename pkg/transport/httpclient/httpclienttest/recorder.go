@@ -0,0 +1,275 @@
+package httpclienttest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"unicode/utf8"
+)
+
+// RecordEnv is the environment variable which, when set to a non-empty value,
+// makes every Recorder record its cassette again instead of replaying it.
+const RecordEnv = "HTTPCLIENTTEST_RECORD"
+
+var _ http.RoundTripper = (*Recorder)(nil)
+
+// Recorder is a VCR-style http.RoundTripper, which also implements
+// httpclient.Requester. It works in one of two modes:
+//
+//   - Record: requests are executed by the underlying transport, and the
+//     received responses are saved in the cassette file when the test finishes.
+//   - Replay: requests are answered with the responses saved in the cassette
+//     file, without reaching the network. Requests with no recorded response
+//     fail with an error.
+//
+// A Recorder records when its cassette file doesn't exist or when the RecordEnv
+// environment variable is set, and replays otherwise. This allows recording
+// real responses once, committing the cassette as a golden file, and replaying
+// it in CI.
+//
+// Requests are matched by method, URL and body. Requests matching many
+// recorded interactions get them in the order they were recorded. Request
+// headers are never recorded, so that credentials don't end up in cassettes.
+type Recorder struct {
+	path      string
+	transport http.RoundTripper
+	recording bool
+
+	mu           sync.Mutex // guards the fields below
+	interactions []*interaction
+	replayed     map[string]int
+}
+
+// NewRecorder returns a Recorder backed by the given cassette file, which
+// executes requests using transport while recording. If transport is nil then
+// http.DefaultTransport is used.
+//
+// The test is failed if the cassette file can't be read, or, when recording,
+// written once the test finishes.
+func NewRecorder(t testing.TB, path string, transport http.RoundTripper) *Recorder {
+	t.Helper()
+
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	r := &Recorder{
+		path:      path,
+		transport: transport,
+		replayed:  make(map[string]int),
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist) || os.Getenv(RecordEnv) != "":
+		r.recording = true
+	case err != nil:
+		t.Fatalf("httpclienttest: reading cassette: %v", err)
+	default:
+		if err := json.Unmarshal(data, &r.interactions); err != nil {
+			t.Fatalf("httpclienttest: decoding cassette %s: %v", path, err)
+		}
+	}
+
+	if r.recording {
+		t.Cleanup(func() {
+			if err := r.save(); err != nil {
+				t.Errorf("httpclienttest: writing cassette: %v", err)
+			}
+		})
+	}
+
+	return r
+}
+
+// Recording tells whether the Recorder is recording responses, or replaying
+// them otherwise.
+func (r *Recorder) Recording() bool {
+	return r.recording
+}
+
+// Do executes the given request, returning either a real or a recorded
+// response depending on the Recorder mode.
+func (r *Recorder) Do(req *http.Request) (*http.Response, error) {
+	return r.RoundTrip(req)
+}
+
+// Client returns an *http.Client which executes requests using the Recorder.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// RoundTrip executes the given request, returning either a real or a recorded
+// response depending on the Recorder mode.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	recorded := &recordedRequest{Method: method, URL: req.URL.String()}
+	recorded.Body, recorded.BodyBase64 = encodeBody(body)
+
+	if r.recording {
+		return r.record(req, recorded, body)
+	}
+
+	return r.replay(req, recorded)
+}
+
+func (r *Recorder) record(req *http.Request, recorded *recordedRequest, body []byte) (*http.Response, error) {
+	req2 := req.Clone(req.Context())
+	req2.Body = io.NopCloser(bytes.NewReader(body))
+	req2.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+
+	res, err := r.transport.RoundTrip(req2)
+	if err != nil {
+		return nil, err
+	}
+
+	resBody, err := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(resBody))
+
+	i := &interaction{
+		Request: *recorded,
+		Response: recordedResponse{
+			StatusCode: res.StatusCode,
+			Header:     res.Header.Clone(),
+		},
+	}
+	i.Response.Body, i.Response.BodyBase64 = encodeBody(resBody)
+
+	r.mu.Lock()
+	r.interactions = append(r.interactions, i)
+	r.mu.Unlock()
+
+	return res, nil
+}
+
+func (r *Recorder) replay(req *http.Request, recorded *recordedRequest) (*http.Response, error) {
+	key := recorded.key()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Skip the interactions already replayed for this request, so that
+	// repeated requests get their responses in the order they were recorded.
+	// Once all of them were replayed, the last one is always used.
+	var match *interaction
+	skip := r.replayed[key]
+	for _, i := range r.interactions {
+		if i.Request.key() != key {
+			continue
+		}
+
+		match = i
+		if skip == 0 {
+			break
+		}
+		skip--
+	}
+
+	if match == nil {
+		return nil, fmt.Errorf("httpclienttest: no recorded response for %s %s in %s", recorded.Method, recorded.URL, r.path)
+	}
+	r.replayed[key]++
+
+	body, err := match.Response.body()
+	if err != nil {
+		return nil, err
+	}
+
+	status := match.Response.StatusCode
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        match.Response.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+func (r *Recorder) save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, err := json.MarshalIndent(r.interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(r.path, append(data, '\n'), 0o644) //nolint:gosec
+}
+
+// interaction is a request and its response, as saved in cassette files.
+type interaction struct {
+	Request  recordedRequest  `json:"request"`
+	Response recordedResponse `json:"response"`
+}
+
+type recordedRequest struct {
+	Method     string `json:"method"`
+	URL        string `json:"url"`
+	Body       string `json:"body,omitempty"`
+	BodyBase64 string `json:"body_base64,omitempty"`
+}
+
+func (r *recordedRequest) key() string {
+	return r.Method + " " + r.URL + "\n" + r.Body + r.BodyBase64
+}
+
+type recordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	BodyBase64 string      `json:"body_base64,omitempty"`
+}
+
+func (r *recordedResponse) body() ([]byte, error) {
+	if r.BodyBase64 != "" {
+		return base64.StdEncoding.DecodeString(r.BodyBase64)
+	}
+	return []byte(r.Body), nil
+}
+
+// encodeBody returns the given body as text if it's valid UTF-8, so that
+// cassettes are human-readable, or base64 encoded otherwise.
+func encodeBody(body []byte) (text, encoded string) {
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+	return "", base64.StdEncoding.EncodeToString(body)
+}
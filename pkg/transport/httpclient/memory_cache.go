@@ -1,12 +1,17 @@
 package httpclient
 
 import (
+	"context"
+	"sync/atomic"
 	"time"
 
 	"github.com/karlseguin/ccache/v2"
+	"github.com/luizaranda/go-core/pkg/telemetry"
 	"github.com/luizaranda/go-core/pkg/transport"
 )
 
+const _cacheEvictMetric = "toolkit.http.client.cache.evict"
+
 // A Cache interface is used by HTTP client to store and retrieve responses.
 type Cache interface {
 	transport.Cache
@@ -33,16 +38,38 @@ var (
 	DefaultCache transport.Cache = NewLocalCache(500)
 )
 
+// WithCacheTTL returns a new context which makes requests executed with it
+// consider cached responses fresh for the given duration, overriding the
+// freshness given by the server. It allows individual calls to accept older,
+// or require newer, responses than the rest.
+func WithCacheTTL(ctx context.Context, ttl time.Duration) context.Context {
+	return transport.WithCacheTTL(ctx, ttl)
+}
+
+// SkipCache returns a new context which makes requests executed with it
+// bypass cached responses and fetch them from the server. The fetched
+// response is still cached for subsequent requests.
+func SkipCache(ctx context.Context) context.Context {
+	return transport.SkipCache(ctx)
+}
+
 type cache struct{ cache *ccache.Cache }
 
-// sizedBytes is an slice alias which provides the Size() method, to fulfill
-// the ccache.Sized interface and allow ccache to measure item size correctly.
-type sizedBytes []byte
+// cacheEntry is a cached response which provides the Size() method, to
+// fulfill the ccache.Sized interface and allow ccache to measure item size
+// correctly.
+type cacheEntry struct {
+	bytes []byte
+
+	// removed tells whether the entry was deleted or overwritten, as opposed
+	// to evicted because the cache was full.
+	removed atomic.Bool
+}
 
-func (s sizedBytes) Size() int64 {
+func (e *cacheEntry) Size() int64 {
 	// ccache has an overhead of ~350 bytes per entry that's not taken into
 	// account. We add it so that the memory tracking is more precise.
-	return int64(len(s)) + 350
+	return int64(len(e.bytes)) + 350
 }
 
 // NewLocalCache instantiates a new memory cache, specifically tailored for
@@ -56,6 +83,10 @@ func (s sizedBytes) Size() int64 {
 // It is highly recommended against users using this cache for other purposes
 // other than this package HTTP caching mechanisms.
 //
+// Entries evicted because the cache is full record the
+// toolkit.http.client.cache.evict metric with the reason:size tag. A high rate
+// of them means the cache is too small for its workload.
+//
 // If creating a cache that has a short life, then in order to avoid memory
 // leaks the user is required to call Close() on the cache when it finishes
 // using it.
@@ -72,7 +103,8 @@ func NewLocalCache(maxSize MiB) Cache {
 
 	cfg := ccache.Configure().
 		MaxSize(bytes).
-		ItemsToPrune(gcThreshold)
+		ItemsToPrune(gcThreshold).
+		OnDelete(recordEviction)
 
 	return &cache{
 		cache: ccache.New(cfg),
@@ -85,18 +117,39 @@ func (c *cache) Get(key string) ([]byte, bool) {
 		return nil, false
 	}
 
-	bytes, ok := item.Value().(sizedBytes)
-	return bytes, ok
+	entry, ok := item.Value().(*cacheEntry)
+	if !ok {
+		return nil, false
+	}
+	return entry.bytes, true
 }
 
 func (c *cache) Set(key string, responseBytes []byte) {
-	c.cache.Set(key, sizedBytes(responseBytes), 1*time.Hour)
+	c.markRemoved(key)
+	c.cache.Set(key, &cacheEntry{bytes: responseBytes}, 1*time.Hour)
 }
 
 func (c *cache) Delete(key string) {
+	c.markRemoved(key)
 	c.cache.Delete(key)
 }
 
+// markRemoved flags the entry under key, if any, so that its removal is not
+// recorded as an eviction.
+func (c *cache) markRemoved(key string) {
+	if item := c.cache.Get(key); item != nil {
+		if entry, ok := item.Value().(*cacheEntry); ok {
+			entry.removed.Store(true)
+		}
+	}
+}
+
+func recordEviction(item *ccache.Item) {
+	if entry, ok := item.Value().(*cacheEntry); ok && !entry.removed.Load() {
+		telemetry.Incr(context.Background(), _cacheEvictMetric, []string{"technology:go", "reason:size"})
+	}
+}
+
 func (c *cache) Close() error {
 	c.cache.Stop()
 	return nil
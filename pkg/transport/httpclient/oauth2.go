@@ -0,0 +1,133 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/luizaranda/go-core/pkg/telemetry"
+)
+
+const (
+	_oauth2TokenTimingMetric = "toolkit.http.client.oauth2.token.time"
+
+	// Max size of a token endpoint response that is read into memory.
+	_oauth2MaxTokenResponseBytes = 1 << 20
+)
+
+// WithOAuth2 authenticates every outgoing request with an access token
+// obtained from tokenURL using the OAuth2 client credentials grant
+// (RFC 6749, section 4.4).
+//
+// Tokens are requested lazily on the first request, cached, and renewed
+// transparently shortly before they expire. Client credentials are sent to
+// the token endpoint using HTTP Basic Authentication.
+//
+// Every token request records the toolkit.http.client.oauth2.token.time metric
+// tagged with its status, and failures to obtain a token are also reported
+// the same way as WithBearerToken does.
+func WithOAuth2(clientID, clientSecret, tokenURL string, scopes ...string) Option {
+	fetcher := &clientCredentialsFetcher{
+		client:       New(),
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		tokenURL:     tokenURL,
+		scopes:       scopes,
+	}
+
+	return WithBearerToken(NewTokenSource(fetcher.Fetch))
+}
+
+// clientCredentialsFetcher requests tokens from an authorization server using
+// the client credentials grant.
+type clientCredentialsFetcher struct {
+	client       *http.Client
+	clientID     string
+	clientSecret string
+	tokenURL     string
+	scopes       []string
+}
+
+// tokenResponse is the successful response of an authorization server token
+// endpoint as defined in RFC 6749, section 5.1.
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// Fetch requests a new token. It is a TokenFetchFunc.
+func (f *clientCredentialsFetcher) Fetch(ctx context.Context) (Token, error) {
+	start := time.Now()
+
+	token, err := f.fetch(ctx)
+
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+
+	telemetry.Timing(ctx, _oauth2TokenTimingMetric, time.Since(start), telemetry.Tags(
+		"technology", "go",
+		"status", status,
+	))
+
+	return token, err
+}
+
+func (f *clientCredentialsFetcher) fetch(ctx context.Context) (Token, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(f.scopes) > 0 {
+		form.Set("scope", strings.Join(f.scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Token{}, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(f.clientID), url.QueryEscape(f.clientSecret))
+
+	res, err := f.client.Do(req)
+	if err != nil {
+		return Token{}, fmt.Errorf("oauth2: requesting token: %w", err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, _oauth2MaxTokenResponseBytes))
+	if err != nil {
+		return Token{}, fmt.Errorf("oauth2: reading token response: %w", err)
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return Token{}, fmt.Errorf("oauth2: token endpoint returned %d: %s", res.StatusCode, body)
+	}
+
+	var tr tokenResponse
+	if err := json.Unmarshal(body, &tr); err != nil {
+		return Token{}, fmt.Errorf("oauth2: decoding token response: %w", err)
+	}
+
+	if tr.AccessToken == "" {
+		return Token{}, errors.New("oauth2: token endpoint returned an empty access_token")
+	}
+
+	if tr.TokenType != "" && !strings.EqualFold(tr.TokenType, "bearer") {
+		return Token{}, fmt.Errorf("oauth2: unsupported token type %q", tr.TokenType)
+	}
+
+	token := Token{Value: tr.AccessToken}
+	if tr.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(tr.ExpiresIn) * time.Second)
+	}
+
+	return token, nil
+}
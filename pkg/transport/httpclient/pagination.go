@@ -0,0 +1,150 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"iter"
+	"net/http"
+	"strings"
+
+	"github.com/luizaranda/go-core/pkg/telemetry"
+	"github.com/luizaranda/go-core/pkg/telemetry/tracing"
+)
+
+const _paginationPageMetric = "toolkit.http.client.pagination.page"
+
+// Pages iterates the pages of a paginated API, starting from the given
+// request and following the RFC 5988 Link header with rel="next" of every
+// response. Pages are requested lazily as the loop advances:
+//
+//	for res, err := range httpclient.Pages(ctx, client, req) {
+//		if err != nil {
+//			return err
+//		}
+//		// Read res.Body
+//	}
+//
+// The body of every page is closed once the loop moves to the next page or
+// ends, so it must be read inside the loop. Following pages are requested
+// with the GET method, the headers of the first request and the given ctx.
+// Iteration ends when a response has no next link, or after yielding an
+// error.
+//
+// Pages are executed by the given Requester, so a RetryableClient retries
+// every page on its own and each of them records the usual request telemetry.
+func Pages(ctx context.Context, client Requester, req *http.Request) iter.Seq2[*http.Response, error] {
+	return func(yield func(*http.Response, error) bool) {
+		req := req.WithContext(ctx)
+		seen := make(map[string]bool)
+
+		for {
+			seen[req.URL.String()] = true
+
+			res, err := client.Do(req)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			telemetry.Incr(ctx, _paginationPageMetric, []string{
+				"technology:go",
+				"target_id:" + telemetry.SanitizeMetricTagValue(tracing.TargetID(ctx)),
+			})
+
+			next := nextPageRequest(ctx, req, res)
+
+			more := yield(res, nil)
+			_, _ = io.Copy(io.Discard, res.Body)
+			_ = res.Body.Close()
+
+			// Links pointing to an already requested page would loop forever.
+			if !more || next == nil || seen[next.URL.String()] {
+				return
+			}
+
+			req = next
+		}
+	}
+}
+
+// Pages iterates the pages of a paginated API using the client. For more
+// information check the Pages function.
+func (c *RetryableClient) Pages(ctx context.Context, req *http.Request) iter.Seq2[*http.Response, error] {
+	return Pages(ctx, c, req)
+}
+
+// nextPageRequest returns the request for the page following res, or nil if
+// it's the last one.
+func nextPageRequest(ctx context.Context, req *http.Request, res *http.Response) *http.Request {
+	link := NextLink(res.Header)
+	if link == "" {
+		return nil
+	}
+
+	u, err := req.URL.Parse(link)
+	if err != nil {
+		return nil
+	}
+
+	next, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil
+	}
+	next.Header = req.Header.Clone()
+	next.Header.Del("Content-Type")
+	next.Header.Del("Content-Length")
+
+	return next
+}
+
+// NextLink returns the target of the link with rel="next" in the Link header
+// of the given response header, as defined by RFC 5988, or an empty string if
+// there is none. Links look like `<https://api/items?page=2>; rel="next"`, and
+// may be relative to the request URL.
+func NextLink(header http.Header) string {
+	for _, value := range header.Values("Link") {
+		for _, link := range splitLinks(value) {
+			target, params, ok := strings.Cut(strings.TrimSpace(link), ";")
+			if !ok || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+
+			for _, param := range strings.Split(params, ";") {
+				key, val, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if !strings.EqualFold(strings.TrimSpace(key), "rel") {
+					continue
+				}
+
+				// A link may have many space separated relation types.
+				for _, rel := range strings.Fields(strings.Trim(strings.TrimSpace(val), `"`)) {
+					if strings.EqualFold(rel, "next") {
+						return target[1 : len(target)-1]
+					}
+				}
+			}
+		}
+	}
+
+	return ""
+}
+
+// splitLinks splits a Link header value into its links, ignoring the commas
+// inside link targets.
+func splitLinks(value string) []string {
+	var links []string
+	var inTarget bool
+	start := 0
+	for i, r := range value {
+		switch {
+		case r == '<':
+			inTarget = true
+		case r == '>':
+			inTarget = false
+		case r == ',' && !inTarget:
+			links = append(links, value[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(links, value[start:])
+}
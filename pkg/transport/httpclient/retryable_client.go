@@ -9,9 +9,16 @@ import (
 	"net/http"
 	"strconv"
 	"time"
+
+	"github.com/luizaranda/go-core/pkg/telemetry"
+	"github.com/luizaranda/go-core/pkg/telemetry/tracing"
 )
 
+const _retryAfterTruncatedMetric = "toolkit.http.client.request.retry_after.truncated"
+
 type retryAttemptContextKey struct{}
+type requestMethodContextKey struct{}
+type idempotentContextKey struct{}
 
 // CheckRetryFunc specifies a policy for handling retries. It is called
 // following each request with the response and error values returned by
@@ -46,6 +53,14 @@ type RetryableClient struct {
 
 	// BackoffStrategy tells the client how much time it must wait between retries.
 	BackoffStrategy BackoffFunc
+
+	// IgnoreRetryAfter makes the client wait for BackoffStrategy between
+	// retries even when 429 and 503 responses carry a Retry-After header.
+	IgnoreRetryAfter bool
+
+	// MaxRetryAfter caps the time the client waits when honoring a Retry-After
+	// header. If zero then the wait is not capped.
+	MaxRetryAfter time.Duration
 }
 
 // Do sends an HTTP request and returns an HTTP response, following policy
@@ -67,7 +82,7 @@ func (c *RetryableClient) Do(req *http.Request) (*http.Response, error) {
 		// to allow the user to define what a successful request is. If this call
 		// return (false, nil) then we can assert that the request was successful
 		// and therefore, we can return the given response to the user.
-		shouldRetry, retryErr := c.checkRetry(req.Context(), resp, err)
+		shouldRetry, retryErr := c.checkRetry(withRequestMethod(req.Context(), req.Method), resp, err)
 
 		// Now decide if we should continue based on checkRetries answer.
 		if !shouldRetry {
@@ -132,9 +147,9 @@ func (c *RetryableClient) checkRetry(ctx context.Context, res *http.Response, er
 func (c *RetryableClient) backoffDuration(attemptNum int, resp *http.Response) time.Duration {
 	if resp != nil {
 		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
-			if s, ok := resp.Header["Retry-After"]; ok {
+			if s, ok := resp.Header["Retry-After"]; ok && !c.IgnoreRetryAfter {
 				if sleep, err := retryAfterDuration(s[0]); err == nil {
-					return sleep
+					return c.capRetryAfter(resp, sleep)
 				}
 			}
 		}
@@ -147,6 +162,29 @@ func (c *RetryableClient) backoffDuration(attemptNum int, resp *http.Response) t
 	return 0
 }
 
+// capRetryAfter limits the wait suggested by the server to MaxRetryAfter,
+// recording a metric when it does.
+func (c *RetryableClient) capRetryAfter(resp *http.Response, sleep time.Duration) time.Duration {
+	if sleep < 0 {
+		return 0
+	}
+
+	if c.MaxRetryAfter <= 0 || sleep <= c.MaxRetryAfter {
+		return sleep
+	}
+
+	if resp.Request != nil {
+		ctx := resp.Request.Context()
+		telemetry.Incr(ctx, _retryAfterTruncatedMetric, []string{
+			"technology:go",
+			"target_id:" + telemetry.SanitizeMetricTagValue(tracing.TargetID(ctx)),
+			"status:" + strconv.Itoa(resp.StatusCode),
+		})
+	}
+
+	return c.MaxRetryAfter
+}
+
 // retryAfterDuration returns the duration for the Retry-After header.
 func retryAfterDuration(t string) (time.Duration, error) {
 	when, err := time.Parse(http.TimeFormat, t)
@@ -177,6 +215,19 @@ func withRetries(ctx context.Context, retryAttempt int) context.Context {
 	return context.WithValue(ctx, retryAttemptContextKey{}, retryAttempt)
 }
 
+// withRequestMethod returns a new context decorated with the method of the
+// request being checked for retries.
+func withRequestMethod(ctx context.Context, method string) context.Context {
+	return context.WithValue(ctx, requestMethodContextKey{}, method)
+}
+
+// MarkIdempotent returns a new context which marks requests executed with it
+// as idempotent, so that they are retried by IdempotentRetryPolicy regardless
+// of their method.
+func MarkIdempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentContextKey{}, true)
+}
+
 // requestFromInternal builds an *http.Request from our internal request.
 func requestFromInternal(req *http.Request, retryAttempt int) (*http.Request, error) {
 	// If this is a retry attempt then set a value in context indicating so.
@@ -236,6 +287,43 @@ func ServerErrorsRetryPolicy() CheckRetryFunc {
 	}
 }
 
+// IdempotentRetryPolicy provides a CheckRetryFunc with the same semantics as
+// ServerErrorsRetryPolicy, but which only retries idempotent requests. That is,
+// requests whose method is GET, HEAD, OPTIONS, PUT or DELETE, or requests whose
+// context was marked by using MarkIdempotent. This guards against executing
+// non-idempotent requests, such as POSTs, more than once.
+func IdempotentRetryPolicy() CheckRetryFunc {
+	serverErrors := ServerErrorsRetryPolicy()
+
+	return func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		retry, retryErr := serverErrors(ctx, resp, err)
+		if !retry {
+			return false, retryErr
+		}
+
+		return isIdempotent(ctx, resp), retryErr
+	}
+}
+
+func isIdempotent(ctx context.Context, resp *http.Response) bool {
+	if marked, _ := ctx.Value(idempotentContextKey{}).(bool); marked {
+		return true
+	}
+
+	method, _ := ctx.Value(requestMethodContextKey{}).(string)
+	if method == "" && resp != nil && resp.Request != nil {
+		method = resp.Request.Method
+	}
+
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		// An empty method means GET, as in http.Request.
+		return true
+	}
+
+	return false
+}
+
 // NoRedirect is a compatible http.CheckRedirect function that tells the
 // http.Client to do not follow redirects.
 func NoRedirect(*http.Request, []*http.Request) error {
@@ -0,0 +1,251 @@
+package httpclient
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Signer signs outgoing requests, usually by setting headers computed from
+// the request contents and a secret.
+type Signer interface {
+	Sign(req *http.Request) error
+}
+
+// WithSigner signs every outgoing request with the given Signer. Requests are
+// signed right before being sent, on every attempt, so retried requests get a
+// fresh signature and compressed bodies are signed as sent over the wire.
+func WithSigner(signer Signer) Option {
+	return WithRequestHook(signer.Sign)
+}
+
+// HMACSigner is a Signer which sets the hex encoded HMAC of the request body
+// in a header.
+type HMACSigner struct {
+	// Key is the secret key used for computing the HMAC.
+	Key []byte
+
+	// Header is the request header to set with the signature. Default is
+	// X-Signature.
+	Header string
+
+	// Hash is the hash function used by the HMAC. Default is sha256.New.
+	Hash func() hash.Hash
+}
+
+// Sign implements the Signer interface.
+func (s *HMACSigner) Sign(req *http.Request) error {
+	body, err := requestBody(req)
+	if err != nil {
+		return err
+	}
+
+	h := s.Hash
+	if h == nil {
+		h = sha256.New
+	}
+
+	header := s.Header
+	if header == "" {
+		header = "X-Signature"
+	}
+
+	mac := hmac.New(h, s.Key)
+	mac.Write(body)
+	req.Header.Set(header, hex.EncodeToString(mac.Sum(nil)))
+
+	return nil
+}
+
+const _sigV4Algorithm = "AWS4-HMAC-SHA256"
+
+// AWSSigV4Signer is a Signer which implements AWS Signature Version 4, as
+// required by AWS services APIs. It sets the Authorization, X-Amz-Date and
+// X-Amz-Content-Sha256 headers, and X-Amz-Security-Token when using temporary
+// credentials.
+type AWSSigV4Signer struct {
+	// AccessKeyID and SecretAccessKey are the credentials used for signing.
+	AccessKeyID     string
+	SecretAccessKey string
+
+	// SessionToken is the session token of temporary credentials, if any.
+	SessionToken string
+
+	// Region is the AWS region the requests are sent to, e.g. us-east-1.
+	Region string
+
+	// Service is the signing name of the AWS service the requests are sent
+	// to, e.g. s3 or execute-api.
+	Service string
+}
+
+// Sign implements the Signer interface.
+func (s *AWSSigV4Signer) Sign(req *http.Request) error {
+	body, err := requestBody(req)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := hexSHA256(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+
+	signedHeaders, canonicalHeaders := sigV4Headers(req)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s.canonicalURI(req.URL),
+		sigV4Query(req.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{date, s.Region, s.Service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		_sigV4Algorithm,
+		amzDate,
+		scope,
+		hexSHA256([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretAccessKey), date)
+	for _, part := range []string{s.Region, s.Service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", _sigV4Algorithm+
+		" Credential="+s.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+
+		", Signature="+signature)
+
+	return nil
+}
+
+// canonicalURI returns the URI encoded path. Every service but S3 expects
+// paths to be encoded twice.
+func (s *AWSSigV4Signer) canonicalURI(u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		return "/"
+	}
+
+	if s.Service == "s3" {
+		return path
+	}
+
+	return strings.ReplaceAll(sigV4Escape(path), "%2F", "/")
+}
+
+// sigV4Headers returns the signed headers list and the canonical headers,
+// which include the Host, Content-Type and every X-Amz-* header.
+func sigV4Headers(req *http.Request) (signed, canonical string) {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if name != "content-type" && !strings.HasPrefix(name, "x-amz-") {
+			continue
+		}
+
+		trimmed := make([]string, len(values))
+		for i, v := range values {
+			trimmed[i] = strings.Join(strings.Fields(v), " ")
+		}
+		headers[name] = strings.Join(trimmed, ",")
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name + ":" + headers[name] + "\n")
+	}
+
+	return strings.Join(names, ";"), b.String()
+}
+
+// sigV4Query returns the canonical query string, sorted by key and value.
+func sigV4Query(query url.Values) string {
+	pairs := make([]string, 0, len(query))
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, sigV4Escape(key)+"="+sigV4Escape(value))
+		}
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, "&")
+}
+
+// sigV4Escape encodes s as defined by RFC 3986, as url.QueryEscape encodes
+// spaces as '+' instead of "%20".
+func sigV4Escape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func hexSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// requestBody returns the body of the given request without consuming it.
+// Requests whose body can't be rewound by using GetBody have their body
+// replaced by an in-memory copy.
+func requestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+
+		return io.ReadAll(body)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+
+	return body, nil
+}
@@ -8,6 +8,7 @@ package httpcache
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"io"
 
@@ -16,6 +17,9 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/luizaranda/go-core/pkg/telemetry"
+	"github.com/luizaranda/go-core/pkg/telemetry/tracing"
 )
 
 const (
@@ -37,19 +41,65 @@ type Cache interface {
 	Delete(key string)
 }
 
-// cacheKey returns the cache key for req.
-func cacheKey(req *http.Request) string {
+type ttlContextKey struct{}
+type skipContextKey struct{}
+
+// WithTTL returns a new context which makes requests executed with it consider
+// cached responses fresh for the given duration since their Date header,
+// regardless of their Cache-Control and Expires headers.
+func WithTTL(ctx context.Context, ttl time.Duration) context.Context {
+	return context.WithValue(ctx, ttlContextKey{}, ttl)
+}
+
+// WithSkip returns a new context which makes requests executed with it ignore
+// cached responses and reach the server. Responses are still stored in the
+// cache.
+func WithSkip(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipContextKey{}, true)
+}
+
+// freshnessFor returns the freshness of the cached response for req, taking
+// into account the TTL set in its context, if any.
+func freshnessFor(cachedResp *http.Response, req *http.Request) int {
+	ttl, ok := req.Context().Value(ttlContextKey{}).(time.Duration)
+	if !ok {
+		return getFreshness(cachedResp.Header, req.Header)
+	}
+
+	if _, ok := parseCacheControl(req.Header)["no-cache"]; ok {
+		return transparent
+	}
+
+	date, err := Date(cachedResp.Header)
+	if err != nil || clock.since(date) >= ttl {
+		return stale
+	}
+	return fresh
+}
+
+// cacheKey returns the cache key for req, using keyFunc for computing the
+// key of the URL if given.
+func cacheKey(req *http.Request, keyFunc func(*http.Request) string) string {
+	key := req.URL.String()
+	if keyFunc != nil {
+		key = keyFunc(req)
+	}
+
 	if req.Method == http.MethodGet {
-		return req.URL.String()
+		return key
 	} else {
-		return req.Method + " " + req.URL.String()
+		return req.Method + " " + key
 	}
 }
 
 // CachedResponse returns the cached http.Response for req if present, and nil
 // otherwise.
 func CachedResponse(c Cache, req *http.Request) (resp *http.Response, err error) {
-	cachedVal, ok := c.Get(cacheKey(req))
+	return cachedResponse(c, req, cacheKey(req, nil))
+}
+
+func cachedResponse(c Cache, req *http.Request, key string) (resp *http.Response, err error) {
+	cachedVal, ok := c.Get(key)
 	if !ok {
 		return
 	}
@@ -102,6 +152,10 @@ type Transport struct {
 	Cache     Cache
 	// If true, responses returned from the cache will be given an extra header, X-From-Cache
 	MarkCachedResponses bool
+	// KeyFunc computes the key under which responses are cached. If nil, the
+	// request URL is used. Requests with methods other than GET have their
+	// key prefixed with the method.
+	KeyFunc func(*http.Request) string
 }
 
 // NewTransport returns a new Transport with the
@@ -135,13 +189,31 @@ func varyMatches(cachedResp *http.Response, req *http.Request) bool {
 // If there is a stale Response, then any validators it contains will be set on the new request
 // to give the server a chance to respond with NotModified. If this happens, then the cached Response
 // will be returned.
+//
+// Every cacheable request records either the toolkit.http.client.cache.hit or
+// the toolkit.http.client.cache.miss metric. Stored responses record the
+// toolkit.http.client.cache.store metric and their size, and invalidated ones
+// the toolkit.http.client.cache.evict metric with the reason:invalidated tag.
 func (t *Transport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
-	cacheKey := cacheKey(req)
+	cacheKey := cacheKey(req, t.KeyFunc)
 	cacheable := (req.Method == "GET" || req.Method == "HEAD") && req.Header.Get("range") == ""
-	var cachedResp *http.Response
+	skip, _ := req.Context().Value(skipContextKey{}).(bool)
+
+	var hit bool
 	if cacheable {
-		cachedResp, err = CachedResponse(t.Cache, req)
-	} else {
+		defer func() {
+			if hit {
+				recordCacheEvent(req, _cacheHitMetric)
+			} else {
+				recordCacheEvent(req, _cacheMissMetric)
+			}
+		}()
+	}
+
+	var cachedResp *http.Response
+	if cacheable && !skip {
+		cachedResp, err = cachedResponse(t.Cache, req, cacheKey)
+	} else if !cacheable {
 		// Need to invalidate an existing value
 		t.Cache.Delete(cacheKey)
 	}
@@ -152,54 +224,53 @@ func (t *Transport) RoundTrip(req *http.Request) (resp *http.Response, err error
 	}
 
 	if cacheable && cachedResp != nil && err == nil {
+		var revalidating bool
 		if t.MarkCachedResponses {
 			cachedResp.Header.Set(XFromCache, "1")
 		}
 
 		if varyMatches(cachedResp, req) {
 			// Can only use cached value if the new request doesn't Vary significantly
-			freshness := getFreshness(cachedResp.Header, req.Header)
+			freshness := freshnessFor(cachedResp, req)
 			if freshness == fresh {
+				hit = true
 				return cachedResp, nil
 			}
 
 			if freshness == stale {
-				var req2 *http.Request
-				// Add validators if caller hasn't already done so
-				etag := cachedResp.Header.Get("etag")
-				if etag != "" && req.Header.Get("etag") == "" {
-					req2 = cloneRequest(req)
-					req2.Header.Set("if-none-match", etag)
-				}
-				lastModified := cachedResp.Header.Get("last-modified")
-				if lastModified != "" && req.Header.Get("last-modified") == "" {
-					if req2 == nil {
-						req2 = cloneRequest(req)
-					}
-					req2.Header.Set("if-modified-since", lastModified)
-				}
-				if req2 != nil {
+				// Add validators if caller hasn't already done so. Conditional
+				// requests made by the caller get the 304 response untouched.
+				if req2 := revalidationRequest(req, cachedResp); req2 != nil {
 					req = req2
+					revalidating = true
 				}
 			}
 		}
 
 		resp, err = transport.RoundTrip(req)
-		if err == nil && req.Method == "GET" && resp.StatusCode == http.StatusNotModified {
+		if err == nil && revalidating && resp.StatusCode == http.StatusNotModified {
 			// Replace the 304 response with the one from cache, but update with some new headers
 			endToEndHeaders := getEndToEndHeaders(resp.Header)
 			for _, header := range endToEndHeaders {
 				cachedResp.Header[header] = resp.Header[header]
 			}
 			resp = cachedResp
+			hit = true
 		} else if (err != nil || (cachedResp != nil && resp.StatusCode >= 500)) &&
 			req.Method == "GET" && canStaleOnError(cachedResp.Header, req.Header) {
 			// In case of transport failure and stale-if-error activated, returns cached content
 			// when available
+			hit = true
 			return cachedResp, nil
+		} else if err == nil && resp.StatusCode == http.StatusNotModified {
+			// The caller made its own conditional request, so it gets the 304
+			// while the cached response is kept.
+			return resp, nil
 		} else {
 			if err != nil || resp.StatusCode != http.StatusOK {
 				t.Cache.Delete(cacheKey)
+				recordCacheEvict(req)
+				cachedResp = nil
 			}
 			if err != nil {
 				return nil, err
@@ -217,7 +288,7 @@ func (t *Transport) RoundTrip(req *http.Request) (resp *http.Response, err error
 		}
 	}
 
-	if cacheable && canStore(parseCacheControl(req.Header), parseCacheControl(resp.Header)) {
+	if cacheable && resp.StatusCode != http.StatusNotModified && canStore(parseCacheControl(req.Header), parseCacheControl(resp.Header)) {
 		for _, varyKey := range headerAllCommaSepValues(resp.Header, "vary") {
 			varyKey = http.CanonicalHeaderKey(varyKey)
 			fakeHeader := "X-Varied-" + varyKey
@@ -237,6 +308,7 @@ func (t *Transport) RoundTrip(req *http.Request) (resp *http.Response, err error
 					respBytes, err := httputil.DumpResponse(&resp, true)
 					if err == nil {
 						t.Cache.Set(cacheKey, respBytes)
+						recordCacheStore(req, len(respBytes))
 					}
 				},
 			}
@@ -244,14 +316,72 @@ func (t *Transport) RoundTrip(req *http.Request) (resp *http.Response, err error
 			respBytes, err := httputil.DumpResponse(resp, true)
 			if err == nil {
 				t.Cache.Set(cacheKey, respBytes)
+				recordCacheStore(req, len(respBytes))
 			}
 		}
 	} else {
 		t.Cache.Delete(cacheKey)
+		if cachedResp != nil {
+			recordCacheEvict(req)
+		}
 	}
 	return resp, nil
 }
 
+const (
+	_cacheHitMetric       = "toolkit.http.client.cache.hit"
+	_cacheMissMetric      = "toolkit.http.client.cache.miss"
+	_cacheStoreMetric     = "toolkit.http.client.cache.store"
+	_cacheEvictMetric     = "toolkit.http.client.cache.evict"
+	_cacheEntrySizeMetric = "toolkit.http.client.cache.entry_size"
+)
+
+func recordCacheEvent(req *http.Request, metric string) {
+	telemetry.Incr(req.Context(), metric, cacheTags(req))
+}
+
+func recordCacheEvict(req *http.Request) {
+	telemetry.Incr(req.Context(), _cacheEvictMetric, append(cacheTags(req), "reason:invalidated"))
+}
+
+func recordCacheStore(req *http.Request, size int) {
+	tags := cacheTags(req)
+	telemetry.Incr(req.Context(), _cacheStoreMetric, tags)
+	telemetry.Histogram(req.Context(), _cacheEntrySizeMetric, float64(size), tags)
+}
+
+func cacheTags(req *http.Request) []string {
+	return []string{
+		"technology:go",
+		"target_id:" + telemetry.SanitizeMetricTagValue(tracing.TargetID(req.Context())),
+	}
+}
+
+// revalidationRequest returns a clone of req with the validators of the cached
+// response, ETag and Last-Modified, set as If-None-Match and If-Modified-Since
+// headers respectively. It returns nil if the cached response has no
+// validators or if req is already a conditional request.
+func revalidationRequest(req *http.Request, cachedResp *http.Response) *http.Request {
+	if req.Header.Get("if-none-match") != "" || req.Header.Get("if-modified-since") != "" {
+		return nil
+	}
+
+	etag := cachedResp.Header.Get("etag")
+	lastModified := cachedResp.Header.Get("last-modified")
+	if etag == "" && lastModified == "" {
+		return nil
+	}
+
+	req2 := cloneRequest(req)
+	if etag != "" {
+		req2.Header.Set("if-none-match", etag)
+	}
+	if lastModified != "" {
+		req2.Header.Set("if-modified-since", lastModified)
+	}
+	return req2
+}
+
 // ErrNoDateHeader indicates that the HTTP headers contained no Date header.
 var ErrNoDateHeader = errors.New("no Date header")
 
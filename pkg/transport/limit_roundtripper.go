@@ -0,0 +1,112 @@
+package transport
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/luizaranda/go-core/pkg/telemetry"
+	"github.com/luizaranda/go-core/pkg/telemetry/tracing"
+)
+
+const _httpResponseTooLargeMetric = "toolkit.http.client.response.too_large"
+
+// ResponseTooLargeError is returned when the body of a response exceeds the
+// limit set by ResponseLimitDecorator.
+type ResponseTooLargeError struct {
+	Limit int64
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("transport: response body exceeds the limit of %d bytes", e.Limit)
+}
+
+// ResponseLimitDecorator returns a RoundTripDecorator that limits the size of
+// response bodies to the given number of bytes.
+//
+// For more information check ResponseLimitRoundTripper struct.
+func ResponseLimitDecorator(limit int64) RoundTripDecorator {
+	return func(base http.RoundTripper) http.RoundTripper {
+		return &ResponseLimitRoundTripper{
+			Transport: base,
+			Limit:     limit,
+		}
+	}
+}
+
+// ResponseLimitRoundTripper is a http.RoundTripper that protects callers from
+// reading response bodies bigger than Limit bytes into memory.
+//
+// Responses whose Content-Length exceeds Limit fail right away with a
+// *ResponseTooLargeError. Otherwise, reading from the body returns the same
+// error once more than Limit bytes would be read. In both cases the
+// toolkit.http.client.response.too_large metric is recorded.
+type ResponseLimitRoundTripper struct {
+	Transport http.RoundTripper
+	Limit     int64
+}
+
+// RoundTrip executes a single HTTP transaction, returning
+// a Response for the provided Request.
+func (t *ResponseLimitRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.Transport.RoundTrip(req)
+	if err != nil || res.Body == nil || res.Body == http.NoBody {
+		return res, err
+	}
+
+	if res.ContentLength > t.Limit {
+		_ = res.Body.Close()
+		recordResponseTooLarge(req)
+		return nil, &ResponseTooLargeError{Limit: t.Limit}
+	}
+
+	res.Body = &limitedReadCloser{
+		ReadCloser: res.Body,
+		req:        req,
+		limit:      t.Limit,
+		remaining:  t.Limit,
+	}
+
+	return res, nil
+}
+
+// limitedReadCloser fails with a *ResponseTooLargeError once more than limit
+// bytes are read from the wrapped io.ReadCloser.
+type limitedReadCloser struct {
+	io.ReadCloser
+	req       *http.Request
+	limit     int64
+	remaining int64
+	err       error
+}
+
+func (r *limitedReadCloser) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+
+	// Reading one byte past the limit tells bodies of exactly limit bytes
+	// apart from bigger ones.
+	if int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
+	}
+
+	n, err := r.ReadCloser.Read(p)
+	if int64(n) > r.remaining {
+		n = int(r.remaining)
+		r.err = &ResponseTooLargeError{Limit: r.limit}
+		recordResponseTooLarge(r.req)
+		err = r.err
+	}
+	r.remaining -= int64(n)
+
+	return n, err
+}
+
+func recordResponseTooLarge(req *http.Request) {
+	ctx := req.Context()
+	telemetry.Incr(ctx, _httpResponseTooLargeMetric, []string{
+		"technology:go",
+		"target_id:" + telemetry.SanitizeMetricTagValue(tracing.TargetID(ctx)),
+	})
+}
@@ -0,0 +1,247 @@
+package transport
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/luizaranda/go-core/pkg/telemetry"
+	"github.com/luizaranda/go-core/pkg/telemetry/tracing"
+)
+
+const (
+	_concurrencyLimitRejectedMetric = "toolkit.http.client.concurrency_limit.rejected"
+	_concurrencyLimitMetric         = "toolkit.http.client.concurrency_limit.limit"
+
+	// _limitRTTTolerance is how much the latency can grow over the long term
+	// average before the limit is reduced.
+	_limitRTTTolerance = 1.5
+
+	// _limitSmoothing weights how fast the limit moves towards the new
+	// estimate on every sample.
+	_limitSmoothing = 0.2
+
+	// _limitLongRTTWindow is the number of samples averaged by the long term
+	// latency.
+	_limitLongRTTWindow = 600
+
+	// _limitBackoffRatio is applied to the limit when a request fails.
+	_limitBackoffRatio = 0.9
+)
+
+// ErrConcurrencyLimit is returned when a request is rejected because the
+// number of requests in flight to its target reached the concurrency limit.
+var ErrConcurrencyLimit = errors.New("transport: concurrency limit exceeded")
+
+// ConcurrencyLimitConfig contains the attributes required by
+// NewConcurrencyLimiter. Zero values are replaced by their defaults.
+type ConcurrencyLimitConfig struct {
+	// InitialLimit is the concurrency limit each target starts with. Default
+	// is 20.
+	InitialLimit int
+
+	// MinLimit is the lowest the concurrency limit can go. Default is 1.
+	MinLimit int
+
+	// MaxLimit is the highest the concurrency limit can go. Default is 200.
+	MaxLimit int
+}
+
+// ConcurrencyLimiter limits the number of requests in flight per target_id,
+// adapting the limit to the latency of the target. It can be shared among
+// many clients so that they are limited as a whole.
+//
+// The limit follows a gradient algorithm: while the latency of requests stays
+// close to its long term average the limit grows, and when the target slows
+// down and latency increases the limit shrinks proportionally. Failed requests
+// reduce the limit as well. This way a slow dependency can't consume all of
+// the process goroutines.
+type ConcurrencyLimiter struct {
+	config  ConcurrencyLimitConfig
+	targets sync.Map // map[string]*gradientLimit
+}
+
+// NewConcurrencyLimiter returns a ConcurrencyLimiter configured with the
+// given config.
+func NewConcurrencyLimiter(config ConcurrencyLimitConfig) *ConcurrencyLimiter {
+	if config.MinLimit <= 0 {
+		config.MinLimit = 1
+	}
+
+	if config.MaxLimit <= 0 {
+		config.MaxLimit = 200
+	}
+
+	if config.InitialLimit <= 0 {
+		config.InitialLimit = 20
+	}
+
+	config.MaxLimit = max(config.MaxLimit, config.MinLimit)
+	config.InitialLimit = min(max(config.InitialLimit, config.MinLimit), config.MaxLimit)
+
+	return &ConcurrencyLimiter{config: config}
+}
+
+// Limit returns the current concurrency limit for the given target.
+func (l *ConcurrencyLimiter) Limit(targetID string) int {
+	return l.target(targetID).current()
+}
+
+func (l *ConcurrencyLimiter) target(targetID string) *gradientLimit {
+	if g, ok := l.targets.Load(targetID); ok {
+		return g.(*gradientLimit)
+	}
+
+	g, _ := l.targets.LoadOrStore(targetID, &gradientLimit{
+		config: l.config,
+		limit:  float64(l.config.InitialLimit),
+	})
+	return g.(*gradientLimit)
+}
+
+// ConcurrencyLimitDecorator returns a RoundTripDecorator that limits the
+// number of requests in flight using the given ConcurrencyLimiter.
+//
+// For more information check ConcurrencyLimitRoundTripper struct.
+func ConcurrencyLimitDecorator(limiter *ConcurrencyLimiter) RoundTripDecorator {
+	return func(base http.RoundTripper) http.RoundTripper {
+		return &ConcurrencyLimitRoundTripper{
+			Transport: base,
+			Limiter:   limiter,
+		}
+	}
+}
+
+// ConcurrencyLimitRoundTripper is a http.RoundTripper that rejects requests
+// with ErrConcurrencyLimit when the number of requests in flight to their
+// target_id reaches the limit given by Limiter. A request is in flight until
+// its response body is fully read or closed.
+//
+// Rejected requests record the toolkit.http.client.concurrency_limit.rejected
+// metric, and changes to the limit are recorded in the
+// toolkit.http.client.concurrency_limit.limit gauge.
+type ConcurrencyLimitRoundTripper struct {
+	Transport http.RoundTripper
+	Limiter   *ConcurrencyLimiter
+}
+
+// RoundTrip executes a single HTTP transaction, returning
+// a Response for the provided Request.
+func (t *ConcurrencyLimitRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	targetID := tracing.TargetID(ctx)
+	target := t.Limiter.target(targetID)
+
+	tags := []string{"technology:go", "target_id:" + telemetry.SanitizeMetricTagValue(targetID)}
+
+	if !target.acquire() {
+		telemetry.Incr(ctx, _concurrencyLimitRejectedMetric, tags)
+		return nil, ErrConcurrencyLimit
+	}
+
+	start := time.Now()
+	res, err := t.Transport.RoundTrip(req)
+	rtt := time.Since(start)
+
+	release := sync.OnceFunc(func() {
+		// Requests canceled by the caller say nothing about the target.
+		if ctx.Err() != nil {
+			target.inFlight.Add(-1)
+			return
+		}
+
+		if limit, changed := target.release(rtt, err); changed {
+			telemetry.Gauge(ctx, _concurrencyLimitMetric, float64(limit), tags)
+		}
+	})
+
+	if err != nil {
+		release()
+		return res, err
+	}
+
+	res.Body = &errorReadCloser{
+		R: &closeFuncReadCloser{ReadCloser: res.Body, OnClose: release},
+		OnErr: func(error) {
+			release()
+		},
+	}
+
+	return res, nil
+}
+
+// gradientLimit keeps the concurrency limit of a single target.
+type gradientLimit struct {
+	config ConcurrencyLimitConfig
+
+	inFlight atomic.Int64
+
+	mu      sync.Mutex // guards the fields below
+	limit   float64
+	longRTT float64
+	samples int
+}
+
+func (g *gradientLimit) current() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return int(g.limit)
+}
+
+func (g *gradientLimit) acquire() bool {
+	limit := int64(g.current())
+	if g.inFlight.Add(1) > limit {
+		g.inFlight.Add(-1)
+		return false
+	}
+	return true
+}
+
+// release frees the slot taken by a request and updates the limit with its
+// outcome, returning the new limit and whether its integer value changed.
+func (g *gradientLimit) release(rtt time.Duration, err error) (int, bool) {
+	inFlight := g.inFlight.Add(-1) + 1
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	previous := int(g.limit)
+
+	if err != nil {
+		g.limit *= _limitBackoffRatio
+	} else {
+		g.sample(float64(rtt), inFlight)
+	}
+
+	g.limit = math.Min(math.Max(g.limit, float64(g.config.MinLimit)), float64(g.config.MaxLimit))
+
+	return int(g.limit), int(g.limit) != previous
+}
+
+// sample updates the limit with a successful request latency. It must be
+// called with g.mu held.
+func (g *gradientLimit) sample(rtt float64, inFlight int64) {
+	if g.samples < _limitLongRTTWindow {
+		g.samples++
+	}
+	if g.longRTT == 0 {
+		g.longRTT = rtt
+	}
+	g.longRTT += (rtt - g.longRTT) / float64(g.samples)
+
+	gradient := math.Max(0.5, math.Min(1, _limitRTTTolerance*g.longRTT/rtt))
+	queueSize := math.Sqrt(g.limit)
+	estimate := g.limit*gradient + queueSize
+
+	// The limit only grows while it's being used, otherwise an idle target
+	// would end up with a limit it was never proven to handle.
+	if estimate > g.limit && float64(inFlight) < g.limit/2 {
+		return
+	}
+
+	g.limit = g.limit*(1-_limitSmoothing) + estimate*_limitSmoothing
+}
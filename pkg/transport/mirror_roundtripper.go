@@ -0,0 +1,132 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/luizaranda/go-core/pkg/telemetry"
+	"github.com/luizaranda/go-core/pkg/telemetry/tracing"
+)
+
+const (
+	_mirrorRequestMetric     = "toolkit.http.client.mirror.request"
+	_mirrorLatencyDiffMetric = "toolkit.http.client.mirror.latency_diff"
+)
+
+// MirrorDecorator returns a RoundTripDecorator that duplicates a sampleRate
+// fraction of the requests to the given secondary http.RoundTripper. The
+// sampleRate must be between 0 and 1, or else this function will panic.
+//
+// For more information check MirrorRoundTripper struct.
+func MirrorDecorator(secondary http.RoundTripper, sampleRate float64) RoundTripDecorator {
+	if sampleRate < 0 || sampleRate > 1 {
+		panic(fmt.Sprintf("transport: mirror sample rate must be between 0 and 1, got %v", sampleRate))
+	}
+
+	return func(base http.RoundTripper) http.RoundTripper {
+		return &MirrorRoundTripper{
+			Transport:  base,
+			Secondary:  secondary,
+			SampleRate: sampleRate,
+		}
+	}
+}
+
+// MirrorRoundTripper is a http.RoundTripper that shadows traffic: requests are
+// executed by Transport, and a SampleRate fraction of them is also sent
+// asynchronously to Secondary once Transport returns. Responses from Secondary
+// are discarded, so callers are never affected by the shadow host.
+//
+// Secondary receives a copy of the original request, so it's responsible for
+// routing it to the shadow host, for instance by rewriting its URL.
+//
+// For every mirrored request the toolkit.http.client.mirror.request metric is
+// recorded, tagged with both status codes and whether they match, together
+// with the toolkit.http.client.mirror.latency_diff histogram, which holds the
+// secondary latency minus the primary one in milliseconds.
+type MirrorRoundTripper struct {
+	Transport  http.RoundTripper
+	Secondary  http.RoundTripper
+	SampleRate float64
+}
+
+// RoundTrip executes a single HTTP transaction, returning
+// a Response for the provided Request.
+func (t *MirrorRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.SampleRate <= 0 || rand.Float64() >= t.SampleRate { //nolint:gosec
+		return t.Transport.RoundTrip(req)
+	}
+
+	// The body is consumed by the primary request, so the shadow one needs a
+	// way of getting a fresh copy of it.
+	getBody := req.GetBody
+	if req.Body != nil && req.Body != http.NoBody && getBody == nil {
+		body, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		req = withBody(req, body)
+		getBody = req.GetBody
+	}
+
+	start := time.Now()
+	res, err := t.Transport.RoundTrip(req)
+	primary := mirrorResult{latency: time.Since(start), status: mirrorStatus(res, err)}
+
+	shadow := req.Clone(context.WithoutCancel(req.Context()))
+	if getBody != nil {
+		body, bodyErr := getBody()
+		if bodyErr != nil {
+			return res, err
+		}
+		shadow.Body = body
+	}
+
+	go t.mirror(shadow, primary)
+
+	return res, err
+}
+
+type mirrorResult struct {
+	latency time.Duration
+	status  string
+}
+
+func (t *MirrorRoundTripper) mirror(req *http.Request, primary mirrorResult) {
+	start := time.Now()
+	res, err := t.Secondary.RoundTrip(req)
+	secondary := mirrorResult{latency: time.Since(start), status: mirrorStatus(res, err)}
+
+	if err == nil {
+		_, _ = io.Copy(io.Discard, res.Body)
+		_ = res.Body.Close()
+	}
+
+	ctx := req.Context()
+	tags := telemetry.Tags(
+		"technology", "go",
+		"primary_status", primary.status,
+		"secondary_status", secondary.status,
+		"match", strconv.FormatBool(primary.status == secondary.status),
+	)
+	if targetID := tracing.TargetID(ctx); targetID != "" {
+		tags = append(tags, "target_id:"+telemetry.SanitizeMetricTagValue(targetID))
+	}
+
+	telemetry.Incr(ctx, _mirrorRequestMetric, tags)
+	telemetry.Histogram(ctx, _mirrorLatencyDiffMetric, float64((secondary.latency - primary.latency).Milliseconds()), tags)
+}
+
+func mirrorStatus(res *http.Response, err error) string {
+	if err != nil {
+		return "error"
+	}
+	return strconv.Itoa(res.StatusCode)
+}
@@ -0,0 +1,65 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/luizaranda/go-core/pkg/telemetry"
+	"github.com/luizaranda/go-core/pkg/telemetry/tracing"
+)
+
+const (
+	_throttleWaitMetric    = "toolkit.http.client.throttle.wait"
+	_throttleDroppedMetric = "toolkit.http.client.throttle.dropped"
+)
+
+// Throttler paces requests. Wait blocks until a request is allowed to proceed,
+// returning an error if it can't be allowed before ctx is done.
+//
+// The token bucket limiter *rate.Limiter from golang.org/x/time/rate
+// implements this interface.
+type Throttler interface {
+	Wait(ctx context.Context) error
+}
+
+// ThrottleDecorator returns a RoundTripDecorator that paces requests using
+// the given Throttler.
+//
+// For more information check ThrottleRoundTripper struct.
+func ThrottleDecorator(throttler Throttler) RoundTripDecorator {
+	return func(base http.RoundTripper) http.RoundTripper {
+		return &ThrottleRoundTripper{
+			Transport: base,
+			Throttler: throttler,
+		}
+	}
+}
+
+// ThrottleRoundTripper is a http.RoundTripper that waits for Throttler before
+// executing each request. Requests that can't proceed before their context is
+// done are dropped, returning the error given by Throttler.
+//
+// The time spent waiting is recorded in the toolkit.http.client.throttle.wait
+// metric, and dropped requests in the toolkit.http.client.throttle.dropped
+// metric.
+type ThrottleRoundTripper struct {
+	Transport http.RoundTripper
+	Throttler Throttler
+}
+
+// RoundTrip executes a single HTTP transaction, returning
+// a Response for the provided Request.
+func (t *ThrottleRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	tags := []string{"technology:go", "target_id:" + telemetry.SanitizeMetricTagValue(tracing.TargetID(ctx))}
+
+	start := time.Now()
+	if err := t.Throttler.Wait(ctx); err != nil {
+		telemetry.Incr(ctx, _throttleDroppedMetric, tags)
+		return nil, err
+	}
+	telemetry.Timing(ctx, _throttleWaitMetric, time.Since(start), tags)
+
+	return t.Transport.RoundTrip(req)
+}
@@ -32,13 +32,41 @@ const (
 	_httpResponseFullyReadTimingMetric    = "toolkit.http.client.response_fully_read.time"
 )
 
+// TraceOption configures the round trippers returned by TraceDecorator and
+// ExtendedTraceDecorator.
+type TraceOption func(*traceConfig)
+
+type traceConfig struct {
+	sampleRate float64
+}
+
+// TraceSampleRate makes traced round trippers send timing metrics at the
+// given sample rate, between 0 and 1, instead of sending all of them. This
+// reduces the volume of metrics sent by high throughput applications, at the
+// cost of precision.
+func TraceSampleRate(rate float64) TraceOption {
+	return func(c *traceConfig) {
+		c.sampleRate = rate
+	}
+}
+
+func newTraceConfig(opts []TraceOption) traceConfig {
+	var config traceConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return config
+}
+
 // TraceDecorator returns a RoundTripDecorator that provides HTTP tracing
 // capabilities to the given http.RoundTripper.
 //
 // For more information check TracedRoundTripper struct.
-func TraceDecorator() RoundTripDecorator {
+func TraceDecorator(opts ...TraceOption) RoundTripDecorator {
+	config := newTraceConfig(opts)
+
 	return func(base http.RoundTripper) http.RoundTripper {
-		return &TracedRoundTripper{Transport: base}
+		return &TracedRoundTripper{Transport: base, SampleRate: config.sampleRate}
 	}
 }
 
@@ -55,6 +83,14 @@ func TraceDecorator() RoundTripDecorator {
 // transaction (web or non-web).
 type TracedRoundTripper struct {
 	Transport http.RoundTripper
+
+	// SampleRate is the rate, between 0 and 1, at which timing metrics are
+	// sent. Zero means all metrics are sent.
+	SampleRate float64
+}
+
+func (t *TracedRoundTripper) sampleRate() float64 {
+	return sampleRateOrDefault(t.SampleRate)
 }
 
 // RoundTrip executes a single HTTP transaction, returning
@@ -81,7 +117,7 @@ func (t *TracedRoundTripper) RoundTrip(request *http.Request) (*http.Response, e
 	// request, either successfully or with error. The following method will
 	// record a request metric with information about the response status, which
 	// is either the response status code, a timeout or an unknown error.
-	recordResponse(request.Context(), commonTags, startTime, _httpRequestMetric, response, err)
+	recordResponse(request.Context(), t.sampleRate(), commonTags, startTime, _httpRequestMetric, response, err)
 
 	return response, err
 }
@@ -90,9 +126,11 @@ func (t *TracedRoundTripper) RoundTrip(request *http.Request) (*http.Response, e
 // capabilities to the given http.RoundTripper.
 //
 // For more information check ExtendedTracedRoundTripper struct.
-func ExtendedTraceDecorator() RoundTripDecorator {
+func ExtendedTraceDecorator(opts ...TraceOption) RoundTripDecorator {
+	config := newTraceConfig(opts)
+
 	return func(base http.RoundTripper) http.RoundTripper {
-		return &ExtendedTracedRoundTripper{Transport: base}
+		return &ExtendedTracedRoundTripper{Transport: base, SampleRate: config.sampleRate}
 	}
 }
 
@@ -109,6 +147,14 @@ func ExtendedTraceDecorator() RoundTripDecorator {
 // transaction (web or non-web).
 type ExtendedTracedRoundTripper struct {
 	Transport http.RoundTripper
+
+	// SampleRate is the rate, between 0 and 1, at which timing metrics are
+	// sent. Zero means all metrics are sent.
+	SampleRate float64
+}
+
+func (t *ExtendedTracedRoundTripper) sampleRate() float64 {
+	return sampleRateOrDefault(t.SampleRate)
 }
 
 // RoundTrip executes a single HTTP transaction, returning
@@ -122,7 +168,7 @@ func (t *ExtendedTracedRoundTripper) RoundTrip(request *http.Request) (*http.Res
 
 	commonTags := tracedCommonTags(request)
 	startTime := time.Now()
-	extendedTracedRequest := newTracedRequest(request, commonTags, startTime)
+	extendedTracedRequest := newTracedRequest(request, t.sampleRate(), commonTags, startTime)
 
 	// At last, we RoundTrip de request into the wrapped transport.
 	response, err := t.Transport.RoundTrip(extendedTracedRequest)
@@ -138,7 +184,7 @@ func (t *ExtendedTracedRoundTripper) RoundTrip(request *http.Request) (*http.Res
 				if err == io.EOF {
 					err = nil
 				}
-				recordResponse(request.Context(), commonTags, startTime, _httpResponseFullyReadTimingMetric, response, err)
+				recordResponse(request.Context(), t.sampleRate(), commonTags, startTime, _httpResponseFullyReadTimingMetric, response, err)
 			},
 		}
 	}
@@ -149,11 +195,18 @@ func (t *ExtendedTracedRoundTripper) RoundTrip(request *http.Request) (*http.Res
 	// request, either successfully or with error. The following method will
 	// record a request metric with information about the response status, which
 	// is either the response status code, a timeout or an unknown error.
-	recordResponse(request.Context(), commonTags, startTime, _httpRequestMetric, response, err)
+	recordResponse(request.Context(), t.sampleRate(), commonTags, startTime, _httpRequestMetric, response, err)
 
 	return response, err
 }
 
+func sampleRateOrDefault(rate float64) float64 {
+	if rate <= 0 || rate > 1 {
+		return 1
+	}
+	return rate
+}
+
 func tracedCommonTags(req *http.Request) []string {
 	targetID := tracing.TargetID(req.Context())
 
@@ -187,7 +240,7 @@ func buildSegmentProcedure(request *http.Request) string {
 	return ""
 }
 
-func recordResponse(ctx context.Context, tags []string, startTime time.Time, metric string, response *http.Response, err error) {
+func recordResponse(ctx context.Context, rate float64, tags []string, startTime time.Time, metric string, response *http.Response, err error) {
 	status, statusClass := "error", "error"
 	if err == nil {
 		status = strconv.Itoa(response.StatusCode)
@@ -196,10 +249,10 @@ func recordResponse(ctx context.Context, tags []string, startTime time.Time, met
 		status = "timeout"
 	}
 
-	recordTimeSince(ctx, metric, startTime, append(tags, "status:"+status, "status_class:"+statusClass))
+	recordTimeSince(ctx, rate, metric, startTime, append(tags, "status:"+status, "status_class:"+statusClass))
 }
 
-func newTracedRequest(request *http.Request, tags []string, startTime time.Time) *http.Request {
+func newTracedRequest(request *http.Request, rate float64, tags []string, startTime time.Time) *http.Request {
 	ctx := request.Context()
 
 	var (
@@ -231,13 +284,13 @@ func newTracedRequest(request *http.Request, tags []string, startTime time.Time)
 		},
 		// Callbacks for gathering stats on request connection state.
 		DNSDone: func(info httptrace.DNSDoneInfo) {
-			recordTimeSince(ctx, _httpDNSTimingMetric, dnsStart, append(tags, statusTag(info.Err)))
+			recordTimeSince(ctx, rate, _httpDNSTimingMetric, dnsStart, append(tags, statusTag(info.Err)))
 		},
 		ConnectDone: func(network, addr string, err error) {
-			recordTimeSince(ctx, _httpTCPConnectTimingMetric, tcpConnectStart, append(tags, statusTag(err)))
+			recordTimeSince(ctx, rate, _httpTCPConnectTimingMetric, tcpConnectStart, append(tags, statusTag(err)))
 		},
 		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
-			recordTimeSince(ctx, _httpTLSHandshakeTimingMetric, tlsHandshakeStart, append(tags, statusTag(err)))
+			recordTimeSince(ctx, rate, _httpTLSHandshakeTimingMetric, tlsHandshakeStart, append(tags, statusTag(err)))
 		},
 		// GotConn is called just before starting sending the request body. GotConnInfo will tell
 		// us whether the connection was reused (in which case no DNS, TLS or Connect was performed)
@@ -247,14 +300,14 @@ func newTracedRequest(request *http.Request, tags []string, startTime time.Time)
 			tags := append(tags,
 				"reused:"+strconv.FormatBool(info.Reused),
 				"was_idle:"+strconv.FormatBool(info.WasIdle))
-			recordTimeSince(ctx, _httpConnectionGotTimingMetric, startTime, tags)
+			recordTimeSince(ctx, rate, _httpConnectionGotTimingMetric, startTime, tags)
 		},
 		// The following callbacks are executed only if the connection phase returned successfully.
 		WroteRequest: func(info httptrace.WroteRequestInfo) {
-			recordTimeSince(ctx, _httpWroteRequestTimingMetric, startTime, append(tags, statusTag(info.Err)))
+			recordTimeSince(ctx, rate, _httpWroteRequestTimingMetric, startTime, append(tags, statusTag(info.Err)))
 		},
 		GotFirstResponseByte: func() {
-			recordTimeSince(ctx, _httpGotFirstResponseByteTimingMetric, startTime, tags)
+			recordTimeSince(ctx, rate, _httpGotFirstResponseByteTimingMetric, startTime, tags)
 		},
 	}
 
@@ -273,12 +326,12 @@ func statusTag(err error) string {
 	return "status:error"
 }
 
-func recordTimeSince(ctx context.Context, metric string, start time.Time, tags []string) {
+func recordTimeSince(ctx context.Context, rate float64, metric string, start time.Time, tags []string) {
 	if start.IsZero() {
 		return
 	}
 
-	telemetry.Timing(ctx, metric, time.Since(start), tags)
+	telemetry.SampledTiming(ctx, metric, time.Since(start), tags, rate)
 }
 
 // errorReadCloser is a wrapper around ReadCloser R that calls OnErr handler
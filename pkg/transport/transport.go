@@ -1,6 +1,7 @@
 package transport
 
 import (
+	"context"
 	"crypto/tls"
 	"net"
 	"net/http"
@@ -77,6 +78,59 @@ func OptionTLSClientConfig(config *tls.Config) Option {
 	})
 }
 
+// OptionUnixSocket makes the transport dial the Unix domain socket at path for
+// every request, regardless of the request URL host, which is still used for
+// the Host header. This is useful for talking to sidecar agents which listen
+// on a local socket.
+//
+// Dialer options, such as OptionDialTimeout, keep applying to the socket.
+// Proxies are disabled, as requests never leave the host.
+func OptionUnixSocket(path string) Option {
+	return transportOptFunc(func(t *http.Transport) {
+		t.Proxy = nil
+
+		dial := t.DialContext
+		t.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dial(ctx, "unix", path)
+		}
+	})
+}
+
+// OptionFallbackDelay sets how long the transports net.Dialer waits for an
+// IPv6 connection before trying IPv4 in parallel, when a host resolves to
+// addresses of both families, as described by RFC 6555 (Happy Eyeballs).
+// Default is 300ms. A negative value disables the fallback, so addresses are
+// tried one after the other.
+//
+// Lowering it helps in environments with broken IPv6, where dialing IPv6
+// addresses hangs until the dial timeout.
+func OptionFallbackDelay(delay time.Duration) Option {
+	return dialerOptFunc(func(d *net.Dialer) {
+		d.FallbackDelay = delay
+	})
+}
+
+// OptionDualStack sets whether the transport dials both IPv4 and IPv6
+// addresses, which is the default. When disabled the transport only dials
+// IPv4 addresses, ignoring the IPv6 ones hosts resolve to.
+func OptionDualStack(enabled bool) Option {
+	return transportOptFunc(func(t *http.Transport) {
+		if enabled {
+			return
+		}
+
+		dial := t.DialContext
+		t.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+			if network == "tcp" {
+				network = "tcp4"
+			}
+			return dial(ctx, network, address)
+		}
+	})
+}
+
+// NewTransport returns an *http.Transport with sane defaults for the internal
+// network, customized by the given options.
 func NewTransport(opts ...Option) *http.Transport {
 	dialer := &net.Dialer{
 		Timeout:   DefaultDialTimeout,
@@ -0,0 +1,82 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+var _ http.RoundTripper = (*PooledHTTP3Transport)(nil)
+
+// NewPooledHTTP3 creates an *http3.Transport, which executes requests using
+// HTTP/3 over QUIC, and decorates its dialer returning a PooledHTTP3Transport
+// which provides insight on the number of opened connections per network
+// address, the same way PooledTransport does.
+//
+// Servers must support HTTP/3, as requests are never downgraded to HTTP/1.1
+// or HTTP/2.
+func NewPooledHTTP3(name string) *PooledHTTP3Transport {
+	return NewPooledHTTP3FromTransport(name, &http3.Transport{
+		TLSClientConfig: &tls.Config{MinVersion: tls.VersionTLS13},
+		QUICConfig: &quic.Config{
+			HandshakeIdleTimeout: DefaultDialTimeout,
+			KeepAlivePeriod:      DefaultKeepAliveProbeInterval,
+		},
+	})
+}
+
+// NewPooledHTTP3FromTransport wraps an *http3.Transport and decorates its
+// dialer returning a PooledHTTP3Transport which provides insight on the
+// number of opened connections per network address.
+//
+// If the given transport has a custom Dial function then it's used for
+// establishing connections, otherwise quic.DialAddrEarly is used.
+func NewPooledHTTP3FromTransport(name string, transport *http3.Transport) *PooledHTTP3Transport {
+	t := &PooledHTTP3Transport{
+		Transport: transport,
+		Name:      name,
+	}
+
+	dial := transport.Dial
+	if dial == nil {
+		dial = quic.DialAddrEarly
+	}
+
+	gotConn, closeConn := t.stats.traceConn(1), t.stats.traceConn(-1)
+	transport.Dial = func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (*quic.Conn, error) {
+		conn, err := dial(ctx, addr, tlsCfg, cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		gotConn("udp", addr)
+		go func() {
+			<-conn.Context().Done()
+			closeConn("udp", addr)
+		}()
+
+		return conn, nil
+	}
+
+	registerExpVar(t.Name, &t.stats)
+
+	return t
+}
+
+// PooledHTTP3Transport is an implementation of an http.RoundTripper which
+// executes requests using HTTP/3 and provides insight on the number of
+// connections it has opened per network address.
+type PooledHTTP3Transport struct {
+	*http3.Transport
+
+	Name  string
+	stats connStats
+}
+
+// Stats returns transport statistics.
+func (t *PooledHTTP3Transport) Stats() map[string]int64 {
+	return t.stats.snapshot()
+}
@@ -0,0 +1,180 @@
+package transport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultCertificateReloadInterval is the minimum interval at which the files
+// given to OptionMutualTLS are checked for changes.
+var DefaultCertificateReloadInterval = 30 * time.Second
+
+// OptionMutualTLS configures the transport to present the client certificate
+// contained in certFile and keyFile to servers that request one, and to verify
+// servers certificates using the CA certificates contained in caFile. If
+// caFile is empty then the host's root CA set is used.
+//
+// The client certificate files are checked for changes at most once every
+// DefaultCertificateReloadInterval, when a new connection is established,
+// and reloaded if modified. This allows certificates to be rotated without
+// restarting the application. If reloading fails then the last valid
+// certificate keeps being used.
+//
+// This function will panic if any of the given files can't be loaded, as it
+// is considered a configuration error.
+func OptionMutualTLS(certFile, keyFile, caFile string) Option {
+	reloader, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		panic(fmt.Errorf("transport: loading client certificate: %w", err))
+	}
+
+	var roots *x509.CertPool
+	if caFile != "" {
+		caPEM, err := os.ReadFile(caFile)
+		if err != nil {
+			panic(fmt.Errorf("transport: loading CA certificates: %w", err))
+		}
+
+		if roots, err = certPool(caPEM); err != nil {
+			panic(err)
+		}
+	}
+
+	return transportOptFunc(func(t *http.Transport) {
+		config := mutualTLSConfig(t.TLSClientConfig, roots)
+		config.GetClientCertificate = reloader.GetClientCertificate
+		t.TLSClientConfig = config
+	})
+}
+
+// OptionMutualTLSFromPEM is like OptionMutualTLS but takes the PEM encoded
+// certificate, key and CA certificates from memory. Certificates given to this
+// option are never reloaded. If caPEM is empty then the host's root CA set is
+// used.
+//
+// This function will panic if any of the given certificates is not valid.
+func OptionMutualTLSFromPEM(certPEM, keyPEM, caPEM []byte) Option {
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		panic(fmt.Errorf("transport: loading client certificate: %w", err))
+	}
+
+	var roots *x509.CertPool
+	if len(caPEM) > 0 {
+		if roots, err = certPool(caPEM); err != nil {
+			panic(err)
+		}
+	}
+
+	return transportOptFunc(func(t *http.Transport) {
+		config := mutualTLSConfig(t.TLSClientConfig, roots)
+		config.Certificates = []tls.Certificate{cert}
+		t.TLSClientConfig = config
+	})
+}
+
+// mutualTLSConfig returns a copy of base, or a new tls.Config if base is nil,
+// configured with the given root CAs.
+func mutualTLSConfig(base *tls.Config, roots *x509.CertPool) *tls.Config {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if base != nil {
+		config = base.Clone()
+	}
+
+	if roots != nil {
+		config.RootCAs = roots
+	}
+
+	return config
+}
+
+func certPool(caPEM []byte) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, errors.New("transport: no valid CA certificates found")
+	}
+	return pool, nil
+}
+
+// certReloader keeps a client certificate loaded from disk, reloading it when
+// the underlying files are modified.
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu        sync.Mutex // guards the fields below
+	cert      *tls.Certificate
+	modTime   time.Time
+	lastCheck time.Time
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+
+	modTime, err := r.filesModTime()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.load(modTime); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// GetClientCertificate implements the tls.Config GetClientCertificate callback.
+func (r *certReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.lastCheck) >= DefaultCertificateReloadInterval {
+		r.lastCheck = time.Now()
+
+		// Errors are ignored on purpose, as we prefer to keep using the last
+		// valid certificate rather than failing all new connections.
+		if modTime, err := r.filesModTime(); err == nil && modTime.After(r.modTime) {
+			_ = r.load(modTime)
+		}
+	}
+
+	return r.cert, nil
+}
+
+func (r *certReloader) load(modTime time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	r.cert = &cert
+	r.modTime = modTime
+	r.lastCheck = time.Now()
+	return nil
+}
+
+// filesModTime returns the latest modification time of the certificate and
+// key files.
+func (r *certReloader) filesModTime() (time.Time, error) {
+	var latest time.Time
+	for _, name := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
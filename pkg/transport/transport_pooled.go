@@ -1,16 +1,27 @@
 package transport
 
 import (
+	"context"
+	"errors"
 	"expvar"
+	"io"
+	"net"
 	"net/http"
+	"net/http/httptrace"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
+	"github.com/luizaranda/go-core/pkg/telemetry"
 	"github.com/luizaranda/go-core/pkg/telemetry/dialtrace"
 )
 
 const (
 	_expvarPrefix = "toolkit.http.client.conn_pools"
+
+	_connPoolOpenMetric = "toolkit.http.client.conn_pool.open"
+	_dialTimeMetric     = "toolkit.http.client.dial.time"
 )
 
 var (
@@ -27,6 +38,9 @@ func NewPooled(name string, opts ...Option) *PooledTransport {
 // NewPooledFromTransport wraps an *http.Transport and decorates its net.Dialer
 // returning a PooledTransport which provides insight on the number of
 // opened connections per network address.
+//
+// Every dial records the toolkit.http.client.dial.time metric, tagged with the
+// address family, ipv4 or ipv6, and whether it succeeded.
 func NewPooledFromTransport(name string, transport *http.Transport) *PooledTransport {
 	t := &PooledTransport{
 		Transport: transport,
@@ -34,11 +48,12 @@ func NewPooledFromTransport(name string, transport *http.Transport) *PooledTrans
 	}
 
 	t.DialContext = dialtrace.NewTracedDialer(t.DialContext, dialtrace.DialerTrace{
-		GotConn:   t.traceConn(1),
-		CloseConn: t.traceConn(-1),
+		GotConn:   t.stats.traceConn(1),
+		CloseConn: t.stats.traceConn(-1),
+		DialDone:  t.recordDial,
 	})
 
-	t.registerExpVar()
+	registerExpVar(t.Name, &t.stats)
 
 	return t
 }
@@ -49,24 +64,227 @@ type PooledTransport struct {
 	*http.Transport
 
 	Name  string
-	stats sync.Map
+	stats connStats
+
+	// In-flight requests are only tracked once StartStatsExporter is called.
+	trackInFlight atomic.Bool
+	inFlight      connStats
+}
+
+// Stats returns transport statistics.
+func (t *PooledTransport) Stats() map[string]int64 {
+	return t.stats.snapshot()
+}
+
+// RoundTrip executes a single HTTP transaction, returning
+// a Response for the provided Request.
+func (t *PooledTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.trackInFlight.Load() {
+		return t.Transport.RoundTrip(req)
+	}
+
+	network, address := "tcp", canonicalAddr(req)
+	t.inFlight.traceConn(1)(network, address)
+	done := sync.OnceFunc(func() { t.inFlight.traceConn(-1)(network, address) })
+
+	res, err := t.Transport.RoundTrip(req)
+	if err != nil {
+		done()
+		return res, err
+	}
+
+	res.Body = &errorReadCloser{
+		R: &closeFuncReadCloser{ReadCloser: res.Body, OnClose: done},
+		OnErr: func(error) {
+			done()
+		},
+	}
+
+	return res, nil
+}
+
+// StartStatsExporter starts a goroutine that records, every interval, the
+// toolkit.http.client.conn_pool.open gauge with the number of open
+// connections of the transport per network address, split by state:
+// in_flight or idle. The goroutine stops when ctx is done.
+//
+// Requests in flight are counted from the moment this method is called. As
+// HTTP/2 connections serve many concurrent requests, the in_flight value for
+// an address may be greater than its number of open connections, in which
+// case idle is zero.
+func (t *PooledTransport) StartStatsExporter(ctx context.Context, client telemetry.Client, interval time.Duration) {
+	t.trackInFlight.Store(true)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				t.exportStats(client)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (t *PooledTransport) exportStats(client telemetry.Client) {
+	inFlight := t.inFlight.snapshot()
+
+	for key, open := range t.stats.snapshot() {
+		busy := inFlight[key]
+		idle := open - busy
+		if idle < 0 {
+			idle = 0
+		}
+
+		client.Gauge(_connPoolOpenMetric, float64(busy), telemetry.Tags("pool", t.Name, "host", key, "state", "in_flight"))
+		client.Gauge(_connPoolOpenMetric, float64(idle), telemetry.Tags("pool", t.Name, "host", key, "state", "idle"))
+	}
 }
 
-func (t *PooledTransport) traceConn(delta int64) func(network, address string) {
+// Warmup establishes n connections to the given host ahead of traffic, leaving
+// them idle in the pool so that the first requests don't pay for DNS
+// resolution, TCP connect and TLS handshake. The host may be given as a base
+// URL, like "http://api.internal", or as a host with an optional port, in
+// which case HTTPS is used.
+//
+// Connections are established by sending concurrent HEAD requests to the
+// root path of the host, whose responses are discarded. The number of
+// connections is capped by MaxConnsPerHost and MaxIdleConnsPerHost, and
+// HTTP/2 hosts end up with a single connection as it serves any number of
+// concurrent requests. Warmup returns once every request finished, or ctx is
+// done, with the errors of the failed ones.
+func (t *PooledTransport) Warmup(ctx context.Context, host string, n int) error {
+	if !strings.Contains(host, "://") {
+		host = "https://" + host
+	}
+
+	maxIdle := t.MaxIdleConnsPerHost
+	if maxIdle <= 0 {
+		maxIdle = http.DefaultMaxIdleConnsPerHost
+	}
+	n = min(n, maxIdle)
+	if t.MaxConnsPerHost > 0 {
+		n = min(n, t.MaxConnsPerHost)
+	}
+
+	if n <= 0 {
+		return nil
+	}
+
+	// Requests hold their connection until every request got one, otherwise
+	// the transport would reuse the connections of the first ones to finish
+	// instead of dialing new ones.
+	var ready sync.WaitGroup
+	ready.Add(n)
+	allReady := make(chan struct{})
+	go func() {
+		ready.Wait()
+		close(allReady)
+	}()
+
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			done := sync.OnceFunc(ready.Done)
+			defer done()
+
+			trace := &httptrace.ClientTrace{
+				GotConn: func(httptrace.GotConnInfo) {
+					done()
+					select {
+					case <-allReady:
+					case <-ctx.Done():
+					}
+				},
+			}
+
+			req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), http.MethodHead, host, nil)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+
+			res, err := t.RoundTrip(req)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+
+			_, _ = io.Copy(io.Discard, res.Body)
+			_ = res.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+func (t *PooledTransport) recordDial(ctx context.Context, info dialtrace.DialInfo) {
+	status := "ok"
+	if info.Err != nil {
+		status = "error"
+	}
+
+	telemetry.Timing(ctx, _dialTimeMetric, info.Duration, telemetry.Tags(
+		"technology", "go",
+		"pool", t.Name,
+		"family", info.Family,
+		"status", status,
+	))
+}
+
+// canonicalAddr returns the host:port the transport dials for the given
+// request, adding the default port for the scheme if missing.
+func canonicalAddr(req *http.Request) string {
+	host := req.URL.Host
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+
+	port := "80"
+	if strings.EqualFold(req.URL.Scheme, "https") {
+		port = "443"
+	}
+
+	return net.JoinHostPort(strings.Trim(host, "[]"), port)
+}
+
+// closeFuncReadCloser calls OnClose after closing the wrapped io.ReadCloser.
+type closeFuncReadCloser struct {
+	io.ReadCloser
+	OnClose func()
+}
+
+func (r *closeFuncReadCloser) Close() error {
+	defer r.OnClose()
+	return r.ReadCloser.Close()
+}
+
+// connStats keeps the number of opened connections per network address.
+type connStats struct {
+	m sync.Map
+}
+
+func (s *connStats) traceConn(delta int64) func(network, address string) {
 	return func(network, address string) {
 		key := dialTraceKey(network, address)
-		value, _ := t.stats.LoadOrStore(key, new(int64))
+		value, _ := s.m.LoadOrStore(key, new(int64))
 		atomic.AddInt64(value.(*int64), delta)
 	}
 }
 
-func dialTraceKey(network, address string) string { return network + ":" + address }
-
-// Stats returns transport statistics.
-func (t *PooledTransport) Stats() map[string]int64 {
+func (s *connStats) snapshot() map[string]int64 {
 	stats := map[string]int64{}
 
-	t.stats.Range(func(key, value interface{}) bool {
+	s.m.Range(func(key, value interface{}) bool {
 		stats[key.(string)] = atomic.LoadInt64(value.(*int64))
 		return true
 	})
@@ -74,7 +292,9 @@ func (t *PooledTransport) Stats() map[string]int64 {
 	return stats
 }
 
-func (t *PooledTransport) registerExpVar() {
-	f := func() interface{} { return t.Stats() }
-	_expvar.Set(t.Name, expvar.Func(f))
+func dialTraceKey(network, address string) string { return network + ":" + address }
+
+func registerExpVar(name string, stats *connStats) {
+	f := func() interface{} { return stats.snapshot() }
+	_expvar.Set(name, expvar.Func(f))
 }
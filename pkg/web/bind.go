@@ -0,0 +1,177 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	_timeType     = reflect.TypeOf(time.Time{})
+	_durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// BindQuery maps the query string parameters of the request into the fields of
+// the struct pointed by destination, and then validates it as DecodeJSON does.
+//
+// Parameter names are taken from the `query` tag of the fields, or from their
+// names if they don't have one, and fields tagged with `query:"-"` are ignored.
+// Fields of embedded structs are mapped as if they were fields of the outer struct.
+//
+//	type ListFilter struct {
+//		Status []string   `query:"status"`
+//		Limit  int        `query:"limit" default:"50"`
+//		Since  *time.Time `query:"since" layout:"2006-01-02"`
+//	}
+//
+// Supported field types are string, bool, the integer and float types, time.Duration,
+// time.Time, pointers to them and slices of them. Absent parameters take the value of
+// the `default` tag, comma separated for slices, and otherwise leave the field untouched,
+// so pointer fields stay nil. Slices take every value of repeated parameters, and
+// time.Time fields are parsed with the layout of the `layout` tag, time.RFC3339 by default.
+//
+// Values which can't be parsed into their fields result in a BadRequestError(400).
+func BindQuery(r *http.Request, destination any) error {
+	v := reflect.ValueOf(destination)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return InternalServerErrorf("invalid query binding destination: %T", destination)
+	}
+
+	if err := bindValues(r.URL.Query(), v.Elem(), "query"); err != nil {
+		return err
+	}
+
+	if err := _validate.StructCtx(r.Context(), destination); err != nil {
+		return handleValidateErr(err)
+	}
+
+	return nil
+}
+
+// bindValues maps values into the fields of the struct v, naming them after the
+// given tag, as described by BindQuery.
+func bindValues(values url.Values, v reflect.Value, tag string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := bindValues(values, v.Field(i), tag); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name, ok := field.Tag.Lookup(tag)
+		if name == "-" || !field.IsExported() || isUploadedFileType(field.Type) {
+			continue
+		}
+		if !ok || name == "" {
+			name = field.Name
+		}
+
+		fieldValues, ok := values[name]
+		if !ok || len(fieldValues) == 0 {
+			def, ok := field.Tag.Lookup("default")
+			if !ok {
+				continue
+			}
+
+			fieldValues = []string{def}
+			if field.Type.Kind() == reflect.Slice {
+				fieldValues = strings.Split(def, ",")
+			}
+		}
+
+		if err := setQueryField(v.Field(i), fieldValues, field.Tag.Get("layout")); err != nil {
+			return BadRequestErrorf("%s param %s is not a valid %s: %s", tag, name, field.Type, strings.Join(fieldValues, ","))
+		}
+	}
+
+	return nil
+}
+
+func setQueryField(v reflect.Value, values []string, layout string) error {
+	switch v.Kind() {
+	case reflect.Slice:
+		slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setQueryValue(slice.Index(i), value, layout); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+
+	case reflect.Pointer:
+		ptr := reflect.New(v.Type().Elem())
+		if err := setQueryValue(ptr.Elem(), values[0], layout); err != nil {
+			return err
+		}
+		v.Set(ptr)
+		return nil
+
+	default:
+		return setQueryValue(v, values[0], layout)
+	}
+}
+
+func setQueryValue(v reflect.Value, value, layout string) error {
+	switch v.Type() {
+	case _timeType:
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		t, err := time.Parse(layout, value)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+
+	case _durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+
+	return nil
+}
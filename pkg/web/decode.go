@@ -61,7 +61,7 @@ func handleDecodeErr(err error) error {
 	case *json.SyntaxError:
 		return NewErrorf(400, "syntax_error: offset=%v, error=%v", e.Offset, e)
 	default:
-		return NewErrorf(400, err.Error())
+		return NewError(400, err.Error())
 	}
 }
 
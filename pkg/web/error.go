@@ -5,21 +5,34 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+
+	"github.com/luizaranda/go-core/pkg/log"
 )
 
+// Error is an error answered to the client with the given HTTP Status. Code is a
+// machine-readable application error code, which by default is derived from the
+// status, as in "not_found", and Message a human-readable description.
+//
+// Cause is the underlying error, if any, accessible with errors.Is and errors.As
+// but never exposed to clients. Details are arbitrary key/value pairs which are
+// exposed to clients by the error encoder and logged along with the error.
 type Error struct {
-	Status  int    `json:"-"`
-	Code    string `json:"code"`
-	Message string `json:"message"`
+	Status  int            `json:"-"`
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+	Cause   error          `json:"-"`
+	Details map[string]any `json:"details,omitempty"`
 }
 
 func (e *Error) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
-		Code    string `json:"code"`
-		Message string `json:"message"`
+		Code    string         `json:"code"`
+		Message string         `json:"message"`
+		Details map[string]any `json:"details,omitempty"`
 	}{
 		Code:    e.Code,
 		Message: e.Message,
+		Details: e.Details,
 	})
 }
 
@@ -30,19 +43,80 @@ func (e *Error) StatusCode() int {
 
 // Error returns a string message of the error, implementing the error interface.
 func (e *Error) Error() string {
+	if e.Cause != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Cause)
+	}
 	return fmt.Sprintf("%s: %s", e.Code, e.Message)
 }
 
+// Unwrap returns the underlying cause of the error.
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// WithCode sets the application error code, returning the same error.
+func (e *Error) WithCode(code string) *Error {
+	e.Code = code
+	return e
+}
+
+// WithCause sets the underlying cause of the error, returning the same error.
+func (e *Error) WithCause(err error) *Error {
+	e.Cause = err
+	return e
+}
+
+// WithDetail adds a key/value pair to the details of the error, returning the
+// same error.
+func (e *Error) WithDetail(key string, value any) *Error {
+	if e.Details == nil {
+		e.Details = make(map[string]any)
+	}
+	e.Details[key] = value
+	return e
+}
+
+// LogFields returns the fields describing the error in logs: its status, code,
+// cause and details.
+func (e *Error) LogFields() []log.Field {
+	fields := []log.Field{
+		log.Int("status", e.Status),
+		log.String("error_code", e.Code),
+		log.String("error_message", e.Message),
+	}
+	if e.Cause != nil {
+		fields = append(fields, log.NamedErr("cause", e.Cause))
+	}
+	if len(e.Details) > 0 {
+		fields = append(fields, log.Any("details", e.Details))
+	}
+	return fields
+}
+
 // NewError creates a new error with the given status code and message.
 func NewError(statusCode int, message string) error {
-	return NewErrorf(statusCode, message)
+	return newError(statusCode, message)
 }
 
 // NewErrorf creates a new error with a formatted message.
 func NewErrorf(status int, format string, args ...interface{}) error {
+	return newError(status, fmt.Sprintf(format, args...))
+}
+
+// WrapError creates a new error with a formatted message, caused by the given
+// error. It returns an *Error so that its code and details can be set:
+//
+//	return web.WrapError(http.StatusNotFound, err, "user %d not found", id).
+//		WithCode("user_not_found").
+//		WithDetail("user_id", id)
+func WrapError(status int, cause error, format string, args ...interface{}) *Error {
+	return newError(status, fmt.Sprintf(format, args...)).WithCause(cause)
+}
+
+func newError(status int, message string) *Error {
 	return &Error{
 		Code:    strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_"),
-		Message: fmt.Sprintf(format, args...),
+		Message: message,
 		Status:  status,
 	}
 }
@@ -0,0 +1,118 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/luizaranda/go-core/pkg/log"
+)
+
+const _apiKeyHeader = "x-api-key"
+
+// ErrInvalidCredentials is returned by credential validators when the given
+// credentials are unknown or wrong, making the request fail with HTTP 401.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Principal is the authenticated identity a request is made on behalf of.
+type Principal struct {
+	// ID identifies the principal, such as a client or user name.
+	ID string
+
+	// Roles are the roles granted to the principal, if any.
+	Roles []string
+
+	// Metadata carries any other information about the principal.
+	Metadata map[string]string
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx which carries the given principal.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal carried by ctx, as set by the
+// APIKeyAuth and BasicAuth middlewares, and whether there is one.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// APIKeyLookupFunc returns the principal owning the given API key, or
+// ErrInvalidCredentials if there is none.
+type APIKeyLookupFunc func(ctx context.Context, key string) (Principal, error)
+
+// BasicAuthValidateFunc returns the principal with the given username and
+// password, or ErrInvalidCredentials if they are wrong. Implementations should
+// compare passwords in constant time, with crypto/subtle for instance.
+type BasicAuthValidateFunc func(ctx context.Context, username, password string) (Principal, error)
+
+// APIKeyAuth produces a Middleware which authenticates requests by the API key
+// in their x-api-key header, storing the principal returned by lookup in the
+// request context, accessible with PrincipalFromContext.
+//
+// Requests without a key, or whose key lookup fails with ErrInvalidCredentials,
+// are answered with HTTP 401. Any other lookup error is answered with HTTP 500.
+func APIKeyAuth(lookup APIKeyLookupFunc) Middleware {
+	return func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(_apiKeyHeader)
+			if key == "" {
+				_ = EncodeJSON(w, UnauthorizedError("missing api key"), http.StatusUnauthorized)
+				return
+			}
+
+			p, err := lookup(r.Context(), key)
+			if err != nil {
+				authFailed(w, r, err)
+				return
+			}
+
+			handler(w, r.WithContext(WithPrincipal(r.Context(), p)))
+		}
+	}
+}
+
+// BasicAuth produces a Middleware which authenticates requests by their HTTP
+// basic authentication credentials, storing the principal returned by validate
+// in the request context, accessible with PrincipalFromContext.
+//
+// Requests without credentials, or whose validation fails with
+// ErrInvalidCredentials, are answered with HTTP 401 and a WWW-Authenticate
+// header. Any other validation error is answered with HTTP 500.
+func BasicAuth(validate BasicAuthValidateFunc) Middleware {
+	return func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			username, password, ok := r.BasicAuth()
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Basic realm="restricted", charset="UTF-8"`)
+				_ = EncodeJSON(w, UnauthorizedError("missing credentials"), http.StatusUnauthorized)
+				return
+			}
+
+			p, err := validate(r.Context(), username, password)
+			if err != nil {
+				if errors.Is(err, ErrInvalidCredentials) {
+					w.Header().Set("WWW-Authenticate", `Basic realm="restricted", charset="UTF-8"`)
+				}
+				authFailed(w, r, err)
+				return
+			}
+
+			handler(w, r.WithContext(WithPrincipal(r.Context(), p)))
+		}
+	}
+}
+
+func authFailed(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, ErrInvalidCredentials) {
+		_ = EncodeJSON(w, UnauthorizedError(err.Error()), http.StatusUnauthorized)
+		return
+	}
+
+	log.Error(r.Context(), "authentication failed", log.Err(err))
+	notifyErr(r.Context(), err)
+	_ = EncodeJSON(w, InternalServerError("authentication failed"), http.StatusInternalServerError)
+}
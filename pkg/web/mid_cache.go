@@ -0,0 +1,274 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/luizaranda/go-core/pkg/telemetry"
+)
+
+const (
+	_surrogateKeyHeader = "Surrogate-Key"
+	_cacheStatusHeader  = "X-Cache"
+
+	_responseCacheKeyPrefix  = "web-cache:"
+	_surrogateCacheKeyPrefix = "web-cache-surrogate:"
+)
+
+// CacheStore stores the responses cached by the Cache middleware. It's
+// satisfied by the stores of the httpclient package, like the one returned by
+// httpclient.NewLocalCache, and may be backed by a distributed cache.
+type CacheStore interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte)
+	Delete(key string)
+}
+
+// CacheKeyFunc returns the key under which the response to a request is
+// cached. Requests with an empty key are not cached.
+type CacheKeyFunc func(r *http.Request) string
+
+// DefaultCacheKey keys responses by the request host, path and query string.
+func DefaultCacheKey(r *http.Request) string {
+	return r.Host + r.URL.RequestURI()
+}
+
+type cacheConfig struct {
+	credentials bool
+}
+
+// CacheOption configures the Cache middleware.
+type CacheOption func(*cacheConfig)
+
+// WithCacheCredentials makes Cache also cache the responses to requests with
+// credentials, the Authorization or Cookie headers, which are otherwise not
+// cached. It's meant for key funcs which key those requests by user, so that
+// their responses are not shared with other users.
+func WithCacheCredentials() CacheOption {
+	return func(cfg *cacheConfig) {
+		cfg.credentials = true
+	}
+}
+
+type cachedResponse struct {
+	Status     int               `json:"status"`
+	Header     http.Header       `json:"header"`
+	Body       []byte            `json:"body"`
+	Expires    time.Time         `json:"expires"`
+	Surrogates map[string]string `json:"surrogates,omitempty"`
+}
+
+// Cache produces a Middleware which caches full responses, status, headers and
+// body, to GET requests for the given ttl, keyed by keyFunc or DefaultCacheKey
+// if nil. It's meant to protect expensive idempotent routes, such as
+// aggregations, and not as an HTTP cache: only 200 OK responses without
+// `Cache-Control: no-store` or `private` are cached, regardless of the request
+// caching headers.
+//
+// Since cached responses are replayed to every client, responses setting
+// cookies or with `Vary: *` are not cached, nor are the responses to requests
+// with the Authorization or Cookie headers, unless WithCacheCredentials is
+// given along with a keyFunc keying them by user.
+//
+// Responses can be tagged with surrogate keys with SetSurrogateKeys, so that
+// all the responses tagged with a key are invalidated at once by
+// PurgeSurrogateKeys, when the underlying data changes.
+//
+// Responses have the X-Cache header set to HIT or MISS, and requests record
+// the toolkit.http.server.cache.hit or toolkit.http.server.cache.miss metrics.
+func Cache(store CacheStore, ttl time.Duration, keyFunc CacheKeyFunc, opts ...CacheOption) Middleware {
+	var cfg cacheConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if keyFunc == nil {
+		keyFunc = DefaultCacheKey
+	}
+
+	return func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			var key string
+			if r.Method == http.MethodGet && (cfg.credentials || !hasCredentials(r)) {
+				key = keyFunc(r)
+			}
+
+			if key == "" {
+				handler(w, r)
+				return
+			}
+			key = _responseCacheKeyPrefix + key
+
+			tags := []string{
+				"handler:" + telemetry.SanitizeMetricTagValue(chi.RouteContext(r.Context()).RoutePattern()),
+			}
+
+			if res, ok := getCachedResponse(store, key); ok {
+				telemetry.Incr(r.Context(), "toolkit.http.server.cache.hit", tags)
+				for k, v := range res.Header {
+					w.Header()[k] = v
+				}
+				w.Header().Set(_cacheStatusHeader, "HIT")
+				w.WriteHeader(res.Status)
+				_, _ = w.Write(res.Body)
+				return
+			}
+
+			telemetry.Incr(r.Context(), "toolkit.http.server.cache.miss", tags)
+			w.Header().Set(_cacheStatusHeader, "MISS")
+
+			var body bytes.Buffer
+			hw := &headerWriter{ResponseWriter: w}
+			w2 := middleware.NewWrapResponseWriter(hw, r.ProtoMajor)
+			w2.Tee(&body)
+
+			handler(w2, r)
+
+			header := hw.written()
+			if w2.Status() != http.StatusOK || !cacheable(header) {
+				return
+			}
+
+			header.Del(_cacheStatusHeader)
+			setCachedResponse(store, key, cachedResponse{
+				Status:     http.StatusOK,
+				Header:     header,
+				Body:       body.Bytes(),
+				Expires:    time.Now().Add(ttl),
+				Surrogates: surrogateGenerations(store, strings.Fields(header.Get(_surrogateKeyHeader))),
+			})
+		}
+	}
+}
+
+// SetSurrogateKeys tags the response being written to w with the given
+// surrogate keys, so that PurgeSurrogateKeys invalidates it once cached by the
+// Cache middleware. It must be called before writing the response.
+func SetSurrogateKeys(w http.ResponseWriter, keys ...string) {
+	keys = append(strings.Fields(w.Header().Get(_surrogateKeyHeader)), keys...)
+	w.Header().Set(_surrogateKeyHeader, strings.Join(keys, " "))
+}
+
+// PurgeSurrogateKeys invalidates every response cached in store by the Cache
+// middleware which was tagged with any of the given surrogate keys.
+func PurgeSurrogateKeys(store CacheStore, keys ...string) {
+	generation := strconv.FormatInt(time.Now().UnixNano(), 10)
+	for _, key := range keys {
+		store.Set(_surrogateCacheKeyPrefix+key, []byte(generation))
+	}
+}
+
+// surrogateGenerations returns the current generation of every surrogate key.
+// Cached responses are stale once the generation of any of their keys changes.
+func surrogateGenerations(store CacheStore, keys []string) map[string]string {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	generations := make(map[string]string, len(keys))
+	for _, key := range keys {
+		generation, _ := store.Get(_surrogateCacheKeyPrefix + key)
+		generations[key] = string(generation)
+	}
+
+	return generations
+}
+
+func getCachedResponse(store CacheStore, key string) (cachedResponse, bool) {
+	b, ok := store.Get(key)
+	if !ok {
+		return cachedResponse{}, false
+	}
+
+	var res cachedResponse
+	if err := json.Unmarshal(b, &res); err != nil || time.Now().After(res.Expires) {
+		return cachedResponse{}, false
+	}
+
+	for key, generation := range res.Surrogates {
+		current, _ := store.Get(_surrogateCacheKeyPrefix + key)
+		if string(current) != generation {
+			return cachedResponse{}, false
+		}
+	}
+
+	return res, true
+}
+
+func setCachedResponse(store CacheStore, key string, res cachedResponse) {
+	b, err := json.Marshal(res)
+	if err != nil {
+		return
+	}
+
+	store.Set(key, b)
+}
+
+// headerWriter records the header of a response as the handler writes it, so
+// that the headers added to the same map afterwards by outer middlewares, such
+// as the Content-Encoding of Compress, aren't stored along with the body the
+// handler wrote.
+type headerWriter struct {
+	http.ResponseWriter
+	header http.Header
+}
+
+func (w *headerWriter) WriteHeader(code int) {
+	if code >= http.StatusOK {
+		w.record()
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *headerWriter) Write(b []byte) (int, error) {
+	w.record()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *headerWriter) Flush() {
+	w.record()
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *headerWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *headerWriter) record() {
+	if w.header == nil {
+		w.header = w.ResponseWriter.Header().Clone()
+	}
+}
+
+// written returns a copy of the header written by the handler, or of the
+// current one if it wrote nothing.
+func (w *headerWriter) written() http.Header {
+	if w.header == nil {
+		return w.ResponseWriter.Header().Clone()
+	}
+	return w.header.Clone()
+}
+
+func hasCredentials(r *http.Request) bool {
+	return r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != ""
+}
+
+func cacheable(header http.Header) bool {
+	if header.Get("Set-Cookie") != "" || headerContainsToken(header, "Vary", "*") {
+		return false
+	}
+
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		switch strings.ToLower(strings.TrimSpace(directive)) {
+		case "no-store", "private":
+			return false
+		}
+	}
+	return true
+}
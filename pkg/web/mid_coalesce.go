@@ -0,0 +1,157 @@
+package web
+
+import (
+	"bytes"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/luizaranda/go-core/pkg/telemetry"
+)
+
+// DefaultCoalesceVaryHeaders are the request headers which, besides the path
+// and query string, must match for GET requests to be coalesced by default.
+// They include the credentials of the request, so that responses are never
+// shared between different users.
+var DefaultCoalesceVaryHeaders = []string{
+	"Accept",
+	"Accept-Encoding",
+	"Accept-Language",
+	"Authorization",
+	"Cookie",
+}
+
+type coalesceConfig struct {
+	varyHeaders []string
+}
+
+// CoalesceOption configures the Coalesce middleware.
+type CoalesceOption func(*coalesceConfig)
+
+// WithCoalesceVaryHeaders sets the request headers which must match for GET
+// requests to be coalesced, replacing DefaultCoalesceVaryHeaders. Applications
+// identifying users through other headers, such as x-api-key, must include
+// them.
+func WithCoalesceVaryHeaders(headers ...string) CoalesceOption {
+	return func(cfg *coalesceConfig) {
+		cfg.varyHeaders = headers
+	}
+}
+
+// coalescedCall is the execution of a handler shared by identical requests.
+type coalescedCall struct {
+	done chan struct{}
+
+	// Fields written before closing done.
+	ok     bool
+	status int
+	header http.Header
+	body   []byte
+}
+
+// Coalesce produces a Middleware which collapses concurrent identical GET
+// requests, the ones with the same path, query string and vary headers, into a
+// single execution of the handler. The first request is handled as usual, while
+// the ones arriving before it finishes wait for its response, which is buffered
+// and replayed to them, protecting expensive read routes during traffic spikes.
+//
+// Waiting requests are recorded in the toolkit.http.server.coalesced metric. If
+// the first request is canceled or its handler panics, waiting requests are
+// handled on their own.
+func Coalesce(opts ...CoalesceOption) Middleware {
+	cfg := coalesceConfig{varyHeaders: DefaultCoalesceVaryHeaders}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var mu sync.Mutex // guards calls
+	calls := make(map[string]*coalescedCall)
+
+	return func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				handler(w, r)
+				return
+			}
+
+			key := coalesceKey(r, cfg.varyHeaders)
+
+			mu.Lock()
+			if call, ok := calls[key]; ok {
+				mu.Unlock()
+				waitCoalescedCall(w, r, call, handler)
+				return
+			}
+
+			call := &coalescedCall{done: make(chan struct{})}
+			calls[key] = call
+			mu.Unlock()
+
+			defer func() {
+				mu.Lock()
+				delete(calls, key)
+				mu.Unlock()
+				close(call.done)
+			}()
+
+			var body bytes.Buffer
+			hw := &headerWriter{ResponseWriter: w}
+			w2 := middleware.NewWrapResponseWriter(hw, r.ProtoMajor)
+			w2.Tee(&body)
+
+			handler(w2, r)
+
+			if r.Context().Err() != nil {
+				return
+			}
+
+			call.ok = true
+			call.status = w2.Status()
+			if call.status == 0 {
+				call.status = http.StatusOK
+			}
+			call.header = hw.written()
+			call.body = body.Bytes()
+		}
+	}
+}
+
+func waitCoalescedCall(w http.ResponseWriter, r *http.Request, call *coalescedCall, handler http.HandlerFunc) {
+	select {
+	case <-call.done:
+	case <-r.Context().Done():
+		return
+	}
+
+	if !call.ok {
+		handler(w, r)
+		return
+	}
+
+	tags := []string{
+		"handler:" + telemetry.SanitizeMetricTagValue(chi.RouteContext(r.Context()).RoutePattern()),
+	}
+	telemetry.Incr(r.Context(), "toolkit.http.server.coalesced", tags)
+
+	for k, v := range call.header {
+		w.Header()[k] = slices.Clone(v)
+	}
+	w.WriteHeader(call.status)
+	_, _ = w.Write(call.body)
+}
+
+func coalesceKey(r *http.Request, varyHeaders []string) string {
+	var b strings.Builder
+	b.WriteString(r.Host)
+	b.WriteString(r.URL.RequestURI())
+	for _, name := range varyHeaders {
+		b.WriteByte('\n')
+		b.WriteString(name)
+		b.WriteByte(':')
+		b.WriteString(strings.Join(r.Header.Values(name), ","))
+	}
+	return b.String()
+}
@@ -0,0 +1,332 @@
+package web
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Content codings supported by the Compress middleware.
+const (
+	EncodingGzip   = "gzip"
+	EncodingBrotli = "br"
+	EncodingZstd   = "zstd"
+)
+
+// DefaultCompressMinSize is the minimum size in bytes a response must have in
+// order to be compressed by the Compress middleware.
+const DefaultCompressMinSize = 1024
+
+// DefaultCompressContentTypes are the content types compressed by default by
+// the Compress middleware.
+var DefaultCompressContentTypes = []string{
+	"text/*",
+	"application/json",
+	"application/x-ndjson",
+	"application/javascript",
+	"application/xml",
+	"application/problem+json",
+	"image/svg+xml",
+}
+
+// CompressConfig configures the Compress middleware.
+type CompressConfig struct {
+	// Encodings are the content codings used, in order of preference when a
+	// client accepts many of them with the same q-value. By default brotli,
+	// zstd and gzip are used, in that order.
+	Encodings []string
+
+	// Levels are the compression levels of each encoding, as defined by their
+	// libraries. Encodings without a level, or with level zero, use their
+	// default one.
+	Levels map[string]int
+
+	// MinSize is the minimum size in bytes a response must have in order to be
+	// compressed, DefaultCompressMinSize by default.
+	MinSize int
+
+	// ContentTypes are the content types compressed, which may end in "/*" to
+	// match every subtype. DefaultCompressContentTypes by default.
+	ContentTypes []string
+}
+
+// Compress produces a Middleware which compresses response bodies using the
+// content coding preferred by the client Accept-Encoding header, honoring its
+// q-values, among the configured ones.
+//
+// Only responses of the configured content types and of at least the minimum
+// size are compressed, so responses are buffered until reaching it, unless
+// flushed by the handler. Responses which already have a Content-Encoding,
+// responses to HEAD requests and protocol upgrades are left untouched.
+// Compressors are pooled and reused across responses.
+//
+// This function will panic if any of the encodings is not supported.
+func Compress(cfg CompressConfig) Middleware {
+	if len(cfg.Encodings) == 0 {
+		cfg.Encodings = []string{EncodingBrotli, EncodingZstd, EncodingGzip}
+	}
+	if cfg.MinSize == 0 {
+		cfg.MinSize = DefaultCompressMinSize
+	}
+	if len(cfg.ContentTypes) == 0 {
+		cfg.ContentTypes = DefaultCompressContentTypes
+	}
+
+	pools := make(map[string]*sync.Pool, len(cfg.Encodings))
+	for _, encoding := range cfg.Encodings {
+		pools[encoding] = newEncoderPool(encoding, cfg.Levels[encoding])
+	}
+
+	return func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"), cfg.Encodings)
+			if encoding == "" || r.Method == http.MethodHead || r.Header.Get("Upgrade") != "" {
+				handler(w, r)
+				return
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: w,
+				encoding:       encoding,
+				pool:           pools[encoding],
+				minSize:        cfg.MinSize,
+				contentTypes:   cfg.ContentTypes,
+			}
+			defer cw.close()
+
+			handler(cw, r)
+		}
+	}
+}
+
+// encoder is implemented by the writers of every supported content coding.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+func newEncoderPool(encoding string, level int) *sync.Pool {
+	var newEncoder func() encoder
+	switch encoding {
+	case EncodingGzip:
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		if _, err := gzip.NewWriterLevel(io.Discard, level); err != nil {
+			panic(fmt.Sprintf("web: invalid gzip compression level %d", level))
+		}
+		newEncoder = func() encoder {
+			w, _ := gzip.NewWriterLevel(io.Discard, level)
+			return w
+		}
+
+	case EncodingBrotli:
+		if level == 0 {
+			level = brotli.DefaultCompression
+		}
+		newEncoder = func() encoder { return brotli.NewWriterLevel(io.Discard, level) }
+
+	case EncodingZstd:
+		opts := []zstd.EOption{zstd.WithEncoderConcurrency(1)}
+		if level != 0 {
+			opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+		}
+		newEncoder = func() encoder {
+			w, _ := zstd.NewWriter(io.Discard, opts...)
+			return w
+		}
+
+	default:
+		panic(fmt.Sprintf("web: unsupported content coding %q", encoding))
+	}
+
+	return &sync.Pool{New: func() any { return newEncoder() }}
+}
+
+// negotiateEncoding returns the encoding with the highest q-value in the
+// Accept-Encoding header among the supported ones, which are sorted by server
+// preference, or an empty string if none is acceptable.
+func negotiateEncoding(acceptEncoding string, supported []string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+
+	qvalues := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(key, "q") {
+				if v, err := strconv.ParseFloat(value, 64); err == nil {
+					q = v
+				}
+			}
+		}
+		qvalues[name] = q
+	}
+
+	var best string
+	var bestQ float64
+	for _, encoding := range supported {
+		q, ok := qvalues[encoding]
+		if !ok {
+			q, ok = qvalues["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+
+	return best
+}
+
+// compressWriter buffers a response until it reaches minSize bytes, and then
+// decides whether to compress it.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding     string
+	pool         *sync.Pool
+	minSize      int
+	contentTypes []string
+
+	status  int
+	buf     []byte
+	decided bool
+	encoder encoder
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = code
+
+	// Informational responses are sent as they are.
+	if code < http.StatusOK {
+		w.status = 0
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if !w.decided {
+		if len(w.buf)+len(b) < w.minSize {
+			w.buf = append(w.buf, b...)
+			return len(b), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+
+	if w.encoder != nil {
+		return w.encoder.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *compressWriter) Flush() {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if !w.decided {
+		// Flushed responses are streams, so they're compressed regardless of
+		// their size.
+		_ = w.decide(true)
+	}
+
+	if w.encoder != nil {
+		_ = w.encoder.Flush()
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// decide writes the response header, setting up compression if the response
+// is eligible and sized, and then writes the buffered body.
+func (w *compressWriter) decide(sized bool) error {
+	w.decided = true
+
+	h := w.Header()
+	if w.eligible() {
+		h.Add("Vary", "Accept-Encoding")
+
+		if sized {
+			h.Set("Content-Encoding", w.encoding)
+			h.Del("Content-Length")
+
+			w.encoder = w.pool.Get().(encoder)
+			w.encoder.Reset(w.ResponseWriter)
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+
+	var err error
+	if w.encoder != nil {
+		_, err = w.encoder.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+func (w *compressWriter) eligible() bool {
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" || w.status == http.StatusNoContent || w.status == http.StatusNotModified {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+
+	return slices.ContainsFunc(w.contentTypes, func(ct string) bool {
+		if prefix, ok := strings.CutSuffix(ct, "/*"); ok {
+			return strings.HasPrefix(mediaType, prefix+"/")
+		}
+		return mediaType == ct
+	})
+}
+
+// close finishes the response, writing it as is if it never reached minSize.
+func (w *compressWriter) close() {
+	if !w.decided && w.status != 0 {
+		_ = w.decide(false)
+	}
+
+	if w.encoder != nil {
+		_ = w.encoder.Close()
+		w.encoder.Reset(io.Discard)
+		w.pool.Put(w.encoder)
+		w.encoder = nil
+	}
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,53 @@
+package web
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/luizaranda/go-core/pkg/telemetry"
+)
+
+// Deprecated produces a route Middleware which flags the route as deprecated to
+// its consumers, adding to its responses the headers:
+//   - Deprecation, set to true.
+//   - Sunset, with the date after which the route may stop working, unless
+//     sunset is zero.
+//   - Link, with the URL of the migration guide or the route replacing it as the
+//     "deprecation" relation, unless link is empty.
+//
+// Requests to the route are recorded in the toolkit.http.server.deprecated
+// metric, so that its remaining usage can be tracked before removing it.
+//
+//	router.Get("/v1/users/{id}", getUser, web.Deprecated(sunset, "https://api.example.com/docs/v2-migration"))
+func Deprecated(sunset time.Time, link string) Middleware {
+	var sunsetValue string
+	if !sunset.IsZero() {
+		sunsetValue = sunset.UTC().Format(http.TimeFormat)
+	}
+
+	var linkValue string
+	if link != "" {
+		linkValue = "<" + link + `>; rel="deprecation"`
+	}
+
+	return func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "true")
+			if sunsetValue != "" {
+				w.Header().Set("Sunset", sunsetValue)
+			}
+			if linkValue != "" {
+				w.Header().Add("Link", linkValue)
+			}
+
+			tags := []string{
+				"method:" + r.Method,
+				"handler:" + telemetry.SanitizeMetricTagValue(chi.RouteContext(r.Context()).RoutePattern()),
+			}
+			telemetry.Incr(r.Context(), "toolkit.http.server.deprecated", tags)
+
+			handler(w, r)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package web
+
+import (
+	"bytes"
+	"encoding/hex"
+	"hash/fnv"
+	"net/http"
+	"strings"
+)
+
+// DefaultETagMaxSize is the maximum size of the responses ETag buffers.
+const DefaultETagMaxSize = 64 << 10
+
+type etagConfig struct {
+	weak    bool
+	maxSize int
+}
+
+// ETagOption configures the ETag middleware.
+type ETagOption func(*etagConfig)
+
+// WithWeakETag makes the ETag middleware generate weak ETags, which only claim
+// semantic equivalence of the responses, instead of strong ones.
+func WithWeakETag() ETagOption {
+	return func(c *etagConfig) {
+		c.weak = true
+	}
+}
+
+// WithETagMaxSize sets the maximum size of the responses the ETag middleware
+// buffers, DefaultETagMaxSize by default.
+func WithETagMaxSize(size int) ETagOption {
+	return func(c *etagConfig) {
+		c.maxSize = size
+	}
+}
+
+// ETag produces a Middleware which buffers 200 OK responses to GET and HEAD
+// requests, computes their ETag from the body, and answers requests whose
+// If-None-Match header matches it with 304 Not Modified and no body. Responses
+// which already have an ETag header are only checked against If-None-Match.
+//
+// Responses larger than the maximum size, or flushed by the handler, are
+// streamed to the client without an ETag.
+//
+// ETags are computed from the uncompressed body, since the compression
+// middleware is the outermost one. As the compressed representation is not
+// the same byte per byte, applications with compression enabled should use
+// WithWeakETag.
+func ETag(opts ...ETagOption) Middleware {
+	cfg := etagConfig{maxSize: DefaultETagMaxSize}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				handler(w, r)
+				return
+			}
+
+			ew := &etagWriter{ResponseWriter: w, maxSize: cfg.maxSize}
+			handler(ew, r)
+
+			if ew.passthrough {
+				return
+			}
+
+			etag := w.Header().Get("ETag")
+			if etag == "" {
+				etag = computeETag(ew.buf.Bytes(), cfg.weak)
+				w.Header().Set("ETag", etag)
+			}
+
+			if etagMatch(r.Header.Get("If-None-Match"), etag) {
+				w.Header().Del("Content-Length")
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(ew.buf.Bytes())
+		}
+	}
+}
+
+func computeETag(body []byte, weak bool) string {
+	h := fnv.New64a()
+	_, _ = h.Write(body)
+	etag := `"` + hex.EncodeToString(h.Sum(nil)) + `"`
+	if weak {
+		etag = "W/" + etag
+	}
+	return etag
+}
+
+// etagMatch compares the ETag with every entity tag of the If-None-Match
+// header using the weak comparison, as required by RFC 7232 for the header.
+func etagMatch(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// etagWriter buffers a 200 OK response up to maxSize, passing any other
+// response through.
+type etagWriter struct {
+	http.ResponseWriter
+	maxSize int
+
+	buf         bytes.Buffer
+	wroteHeader bool
+	passthrough bool
+}
+
+func (w *etagWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	if code != http.StatusOK {
+		w.passthrough = true
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *etagWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.passthrough {
+		return w.ResponseWriter.Write(b)
+	}
+
+	if w.buf.Len()+len(b) > w.maxSize {
+		w.flushBuffer()
+		return w.ResponseWriter.Write(b)
+	}
+
+	return w.buf.Write(b)
+}
+
+func (w *etagWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if !w.passthrough {
+		w.flushBuffer()
+	}
+
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// flushBuffer switches to passing the response through, writing what was
+// buffered so far.
+func (w *etagWriter) flushBuffer() {
+	w.passthrough = true
+	w.ResponseWriter.WriteHeader(http.StatusOK)
+	_, _ = w.ResponseWriter.Write(w.buf.Bytes())
+	w.buf.Reset()
+}
+
+func (w *etagWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
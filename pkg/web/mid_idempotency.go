@@ -0,0 +1,376 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/luizaranda/go-core/pkg/log"
+	"github.com/luizaranda/go-core/pkg/telemetry"
+)
+
+const (
+	_idempotencyKeyHeader      = "Idempotency-Key"
+	_idempotentReplayedHeader  = "Idempotent-Replayed"
+	_idempotencyStoreKeyPrefix = "web-idempotency:"
+
+	// _maxIdempotencyKeyLength is the maximum length of the keys given by
+	// clients, which are usually UUIDs.
+	_maxIdempotencyKeyLength = 255
+)
+
+// IdempotencyStore keeps the responses recorded by the Idempotency middleware.
+// The in-memory NewMemoryIdempotencyStore implementation is limited to each
+// instance of the application, while a distributed implementation, backed by
+// Redis for instance, makes keys effective across instances.
+type IdempotencyStore interface {
+	// Add stores value under key for ttl only if key is not stored yet,
+	// returning whether it did. It must be atomic, since it's used to lock
+	// keys while their request is in flight.
+	Add(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+
+	// Get returns the value stored under key, if any.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+
+	// Set stores value under key for ttl, replacing the stored one.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+
+	// Delete removes the value stored under key, if any.
+	Delete(ctx context.Context, key string) error
+}
+
+// IdempotencyConfig configures the Idempotency middleware.
+type IdempotencyConfig struct {
+	// TTL is how long responses are replayed for. By default it's 24 hours.
+	TTL time.Duration
+
+	// LockTimeout is how long a key is locked by a request in flight, after
+	// which it's released even if the request didn't finish, as happens when
+	// the application crashes. By default it's one minute.
+	LockTimeout time.Duration
+
+	// Required makes POST and PATCH requests without an Idempotency-Key header
+	// be rejected with HTTP 400. By default they're handled as usual.
+	Required bool
+
+	// Scope returns the namespace of the keys of a request, such as the client
+	// of an API key, so that clients can't collide with each other keys. By
+	// default keys are shared by all clients.
+	Scope func(r *http.Request) string
+
+	// Store keeps the recorded responses. By default an in-memory store is used.
+	Store IdempotencyStore
+}
+
+// idempotencyRecord is the state of an idempotency key, either locked by a
+// request in flight or holding its response.
+type idempotencyRecord struct {
+	InFlight    bool        `json:"in_flight,omitempty"`
+	Fingerprint string      `json:"fingerprint"`
+	Status      int         `json:"status,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+}
+
+// Idempotency produces a Middleware which makes POST and PATCH requests with an
+// Idempotency-Key header safe to retry, as payment-style APIs require. The
+// response to the first request with a key is recorded, as configured by cfg,
+// and replayed to the later requests with the same key, with the
+// Idempotent-Replayed header set and recorded in the
+// toolkit.http.server.idempotency.replayed metric. Server error responses are
+// not recorded, so that requests failing with them can be retried.
+//
+// Requests are rejected, and recorded in the
+// toolkit.http.server.idempotency.rejected metric, with:
+//   - HTTP 409 and a Retry-After header, if a request with the same key is in
+//     flight.
+//   - HTTP 422, if the key was used for a request with a different method, path
+//     or body.
+//   - HTTP 503, if the store fails, since handling them could apply their
+//     changes twice.
+func Idempotency(cfg IdempotencyConfig) Middleware {
+	if cfg.TTL <= 0 {
+		cfg.TTL = 24 * time.Hour
+	}
+
+	if cfg.LockTimeout <= 0 {
+		cfg.LockTimeout = time.Minute
+	}
+
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryIdempotencyStore()
+	}
+
+	return func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost && r.Method != http.MethodPatch {
+				handler(w, r)
+				return
+			}
+
+			idempotencyKey := r.Header.Get(_idempotencyKeyHeader)
+			switch {
+			case idempotencyKey == "" && !cfg.Required:
+				handler(w, r)
+				return
+			case idempotencyKey == "":
+				_ = EncodeJSON(w, BadRequestErrorf("missing %s header", _idempotencyKeyHeader), http.StatusBadRequest)
+				return
+			case len(idempotencyKey) > _maxIdempotencyKeyLength:
+				_ = EncodeJSON(w, BadRequestErrorf("%s header longer than %d characters", _idempotencyKeyHeader, _maxIdempotencyKeyLength), http.StatusBadRequest)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				_ = EncodeJSON(w, BadRequestErrorf("reading request body: %v", err), http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			key := _idempotencyStoreKeyPrefix + idempotencyKey
+			if cfg.Scope != nil {
+				key += "|" + cfg.Scope(r)
+			}
+			fingerprint := idempotencyFingerprint(r, body)
+
+			locked, err := addIdempotencyRecord(r.Context(), cfg.Store, key, idempotencyRecord{InFlight: true, Fingerprint: fingerprint}, cfg.LockTimeout)
+			if err != nil {
+				rejectIdempotentRequest(w, r, err, "store_error")
+				return
+			}
+
+			if !locked {
+				replayIdempotentRequest(w, r, cfg.Store, key, fingerprint)
+				return
+			}
+
+			recordIdempotentRequest(w, r, handler, cfg, key, fingerprint)
+		}
+	}
+}
+
+func recordIdempotentRequest(w http.ResponseWriter, r *http.Request, handler http.HandlerFunc, cfg IdempotencyConfig, key, fingerprint string) {
+	ctx := r.Context()
+
+	recorded := false
+	defer func() {
+		if recorded {
+			return
+		}
+		// Release the key, also when the handler panics, so that the
+		// request can be retried. The request context may be canceled.
+		if err := cfg.Store.Delete(context.WithoutCancel(ctx), key); err != nil {
+			log.Warn(ctx, "idempotency store failed to release key", log.Err(err))
+		}
+	}()
+
+	var body bytes.Buffer
+	hw := &headerWriter{ResponseWriter: w}
+	w2 := middleware.NewWrapResponseWriter(hw, r.ProtoMajor)
+	w2.Tee(&body)
+
+	handler(w2, r)
+
+	status := w2.Status()
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if status >= http.StatusInternalServerError {
+		return
+	}
+
+	record := idempotencyRecord{
+		Fingerprint: fingerprint,
+		Status:      status,
+		Header:      hw.written(),
+		Body:        body.Bytes(),
+	}
+
+	b, err := json.Marshal(record)
+	if err == nil {
+		err = cfg.Store.Set(context.WithoutCancel(ctx), key, b, cfg.TTL)
+	}
+	if err != nil {
+		log.Warn(ctx, "idempotency store failed to record response", log.Err(err))
+		return
+	}
+	recorded = true
+}
+
+func replayIdempotentRequest(w http.ResponseWriter, r *http.Request, store IdempotencyStore, key, fingerprint string) {
+	b, ok, err := store.Get(r.Context(), key)
+	if err != nil {
+		rejectIdempotentRequest(w, r, err, "store_error")
+		return
+	}
+
+	var record idempotencyRecord
+	if ok {
+		if err := json.Unmarshal(b, &record); err != nil {
+			rejectIdempotentRequest(w, r, err, "store_error")
+			return
+		}
+	} else {
+		// The key was released by the request holding it after being locked,
+		// which only happens if it failed, so it's treated as in flight.
+		record.InFlight = true
+		record.Fingerprint = fingerprint
+	}
+
+	switch {
+	case record.Fingerprint != fingerprint:
+		rejectIdempotentRequest(w, r, nil, "mismatch")
+	case record.InFlight:
+		rejectIdempotentRequest(w, r, nil, "in_flight")
+	default:
+		telemetry.Incr(r.Context(), "toolkit.http.server.idempotency.replayed", idempotencyTags(r))
+
+		for k, v := range record.Header {
+			w.Header()[k] = slices.Clone(v)
+		}
+		w.Header().Set(_idempotentReplayedHeader, "true")
+		w.WriteHeader(record.Status)
+		_, _ = w.Write(record.Body)
+	}
+}
+
+func rejectIdempotentRequest(w http.ResponseWriter, r *http.Request, err error, reason string) {
+	telemetry.Incr(r.Context(), "toolkit.http.server.idempotency.rejected", append(idempotencyTags(r), "reason:"+reason))
+
+	switch reason {
+	case "mismatch":
+		_ = EncodeJSON(w, NewErrorf(http.StatusUnprocessableEntity, "%s already used for a different request", _idempotencyKeyHeader), http.StatusUnprocessableEntity)
+	case "in_flight":
+		w.Header().Set("Retry-After", "1")
+		_ = EncodeJSON(w, NewErrorf(http.StatusConflict, "a request with the same %s is in progress", _idempotencyKeyHeader), http.StatusConflict)
+	default:
+		log.Error(r.Context(), "idempotency store failed, rejecting request", log.Err(err))
+		_ = EncodeJSON(w, NewErrorf(http.StatusServiceUnavailable, "idempotency check unavailable"), http.StatusServiceUnavailable)
+	}
+}
+
+func idempotencyTags(r *http.Request) []string {
+	return []string{
+		"method:" + r.Method,
+		"handler:" + telemetry.SanitizeMetricTagValue(chi.RouteContext(r.Context()).RoutePattern()),
+	}
+}
+
+// idempotencyFingerprint identifies the request a key was used for, so that
+// keys reused for other requests are detected.
+func idempotencyFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	_, _ = io.WriteString(h, r.Method+" "+r.URL.Path+"\n")
+	_, _ = h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func addIdempotencyRecord(ctx context.Context, store IdempotencyStore, key string, record idempotencyRecord, ttl time.Duration) (bool, error) {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return false, err
+	}
+
+	return store.Add(ctx, key, b, ttl)
+}
+
+// MemoryIdempotencyStore is an in-memory IdempotencyStore. It is safe to use
+// concurrently by multiple goroutines.
+type MemoryIdempotencyStore struct {
+	mu        sync.Mutex // guards the fields below
+	values    map[string]idempotencyValue
+	lastSweep time.Time
+}
+
+type idempotencyValue struct {
+	value   []byte
+	expires time.Time
+}
+
+// _idempotencySweepInterval is how often the in-memory store drops the expired
+// values.
+const _idempotencySweepInterval = time.Minute
+
+// NewMemoryIdempotencyStore returns a new in-memory IdempotencyStore.
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		values:    make(map[string]idempotencyValue),
+		lastSweep: time.Now(),
+	}
+}
+
+// Add implements IdempotencyStore.
+func (s *MemoryIdempotencyStore) Add(_ context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	if v, ok := s.values[key]; ok && now.Before(v.expires) {
+		return false, nil
+	}
+
+	s.values[key] = idempotencyValue{value: value, expires: now.Add(ttl)}
+	return true, nil
+}
+
+// Get implements IdempotencyStore.
+func (s *MemoryIdempotencyStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.values[key]
+	if !ok || time.Now().After(v.expires) {
+		return nil, false, nil
+	}
+
+	return v.value, true, nil
+}
+
+// Set implements IdempotencyStore.
+func (s *MemoryIdempotencyStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+	s.values[key] = idempotencyValue{value: value, expires: now.Add(ttl)}
+	return nil
+}
+
+// Delete implements IdempotencyStore.
+func (s *MemoryIdempotencyStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.values, key)
+	return nil
+}
+
+// sweep drops the expired values, at most once per _idempotencySweepInterval.
+// It must be called with s.mu held.
+func (s *MemoryIdempotencyStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < _idempotencySweepInterval {
+		return
+	}
+
+	for k, v := range s.values {
+		if now.After(v.expires) {
+			delete(s.values, k)
+		}
+	}
+	s.lastSweep = now
+}
@@ -0,0 +1,154 @@
+package web
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/luizaranda/go-core/pkg/telemetry"
+)
+
+type ipFilterConfig struct {
+	trustedProxies []netip.Prefix
+}
+
+// IPFilterOption configures the IPFilter middleware.
+type IPFilterOption func(*ipFilterConfig)
+
+// WithTrustedProxies sets the IPs or CIDR ranges of the proxies in front of the
+// application, such as load balancers. The client IP of requests coming from
+// them is taken from the X-Forwarded-For header, as the rightmost address
+// which is not a trusted proxy, or is the one of the proxy if the header is
+// missing. Without trusted proxies, the client IP is always the one of the
+// connection.
+//
+// It panics if any of the proxies is not a valid IP or CIDR range.
+func WithTrustedProxies(proxies ...string) IPFilterOption {
+	prefixes := mustParsePrefixes(proxies)
+	return func(cfg *ipFilterConfig) {
+		cfg.trustedProxies = prefixes
+	}
+}
+
+// IPFilter produces a Middleware which only allows requests from clients whose
+// IP is in the allow list, if not empty, and not in the deny list, which are
+// made of IPs and CIDR ranges, such as "10.0.0.0/8". Rejected requests are
+// answered with HTTP 403 and recorded in the
+// toolkit.http.server.ip_filter.rejected metric.
+//
+//	router.Group("/internal", web.IPFilter([]string{"10.0.0.0/8"}, nil, web.WithTrustedProxies("10.0.0.1")))
+//
+// This function will panic if any of the IPs or CIDR ranges is not valid.
+func IPFilter(allow, deny []string, opts ...IPFilterOption) Middleware {
+	var cfg ipFilterConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	allowed := mustParsePrefixes(allow)
+	denied := mustParsePrefixes(deny)
+
+	return func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			ip, ok := clientIP(r, cfg.trustedProxies)
+
+			switch {
+			case ok && prefixesContain(denied, ip):
+			case len(allowed) == 0 && (ok || len(denied) == 0):
+				handler(w, r)
+				return
+			case ok && prefixesContain(allowed, ip):
+				handler(w, r)
+				return
+			}
+
+			tags := []string{
+				"method:" + r.Method,
+				"handler:" + telemetry.SanitizeMetricTagValue(chi.RouteContext(r.Context()).RoutePattern()),
+			}
+			telemetry.Incr(r.Context(), "toolkit.http.server.ip_filter.rejected", tags)
+
+			_ = EncodeJSON(w, NewErrorf(http.StatusForbidden, "client address not allowed"), http.StatusForbidden)
+		}
+	}
+}
+
+// clientIP returns the IP of the client which made the request, skipping the
+// trusted proxies it went through.
+func clientIP(r *http.Request, trustedProxies []netip.Prefix) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	ip = ip.Unmap()
+
+	if !prefixesContain(trustedProxies, ip) {
+		return ip, true
+	}
+
+	// Requests made by the proxy itself, such as the health checks of load
+	// balancers, have no forwarded addresses.
+	header := strings.Join(r.Header.Values("X-Forwarded-For"), ",")
+	if strings.TrimSpace(header) == "" {
+		return ip, true
+	}
+
+	// Every proxy appends the address it received the request from, so the
+	// client is the rightmost one which is not a trusted proxy.
+	forwarded := strings.Split(header, ",")
+	for _, addr := range slices.Backward(forwarded) {
+		forwardedIP, err := netip.ParseAddr(strings.TrimSpace(addr))
+		if err != nil {
+			return netip.Addr{}, false
+		}
+
+		ip = forwardedIP.Unmap()
+		if !prefixesContain(trustedProxies, ip) {
+			return ip, true
+		}
+	}
+
+	// Every address is a trusted proxy, so the leftmost one is the client.
+	return ip, true
+}
+
+func prefixesContain(prefixes []netip.Prefix, ip netip.Addr) bool {
+	return slices.ContainsFunc(prefixes, func(p netip.Prefix) bool {
+		return p.Contains(ip)
+	})
+}
+
+// mustParsePrefixes parses IPs and CIDR ranges, IPs being ranges of a single
+// address.
+func mustParsePrefixes(values []string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+
+		if strings.Contains(v, "/") {
+			p, err := netip.ParsePrefix(v)
+			if err != nil {
+				panic(fmt.Sprintf("web: invalid CIDR range %q: %v", v, err))
+			}
+			prefixes = append(prefixes, p.Masked())
+			continue
+		}
+
+		ip, err := netip.ParseAddr(v)
+		if err != nil {
+			panic(fmt.Sprintf("web: invalid IP %q: %v", v, err))
+		}
+		ip = ip.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(ip, ip.BitLen()))
+	}
+	return prefixes
+}
@@ -0,0 +1,68 @@
+package web
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/text/language"
+)
+
+type localeKey struct{}
+
+// Locale produces a Middleware which negotiates the language of every request,
+// the best match between the ones of its Accept-Language header and the
+// supported ones, so that handlers can localize their responses, error messages
+// included. The first supported language is used when none matches or the
+// header is missing.
+//
+// The negotiated language is:
+//   - stored in the request context, accessible with LocaleFromContext.
+//   - added as the locale attribute of the NewRelic transaction and
+//     OpenTelemetry span of the request.
+//
+// For example:
+//
+//	router.Use(web.Locale(language.English, language.Spanish, language.BrazilianPortuguese))
+//
+// This function will panic if no supported language is given.
+func Locale(supported ...language.Tag) Middleware {
+	if len(supported) == 0 {
+		panic("web: Locale requires at least one supported language")
+	}
+
+	matcher := language.NewMatcher(supported)
+
+	return func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			// Invalid headers yield no tags, which match the default language.
+			tags, _, _ := language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+
+			// The index is used instead of the matched tag, which may carry
+			// extensions of the requested ones.
+			_, index, _ := matcher.Match(tags...)
+			locale := supported[index]
+
+			w.Header().Add("Vary", "Accept-Language")
+
+			ctx := r.Context()
+			if txn := newrelic.FromContext(ctx); txn != nil {
+				txn.AddAttribute("locale", locale.String())
+			}
+			trace.SpanFromContext(ctx).SetAttributes(attribute.String("locale", locale.String()))
+
+			handler(w, r.WithContext(context.WithValue(ctx, localeKey{}, locale)))
+		}
+	}
+}
+
+// LocaleFromContext returns the language negotiated by the Locale middleware for
+// the request of ctx, or language.Und if there is none.
+func LocaleFromContext(ctx context.Context) language.Tag {
+	if locale, ok := ctx.Value(localeKey{}).(language.Tag); ok {
+		return locale
+	}
+	return language.Und
+}
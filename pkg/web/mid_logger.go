@@ -1,8 +1,10 @@
 package web
 
 import (
-	"github.com/luizaranda/go-core/pkg/log"
+	"context"
 	"net/http"
+
+	"github.com/luizaranda/go-core/pkg/log"
 )
 
 const (
@@ -27,11 +29,13 @@ func Logger(logger log.Logger) Middleware {
 				l = l.WithLevel(log.DebugLevel)
 			}
 
+			ctx := r.Context()
 			if reqID := r.Header.Get(_requestIDHeader); reqID != "" {
 				l = l.With(log.String("request_id", reqID))
+				ctx = context.WithValue(ctx, loggedRequestIDKey{}, true)
 			}
 
-			ctx := log.Context(r.Context(), l)
+			ctx = log.Context(ctx, l)
 			r2 := r.WithContext(ctx)
 
 			handler(w, r2)
@@ -0,0 +1,242 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/go-chi/chi/v5"
+	"github.com/luizaranda/go-core/pkg/telemetry"
+)
+
+// OpenAPIViolation describes why a request does not conform to the OpenAPI
+// document. In is where the offending value is: "path", "query", "header",
+// "cookie" or "body", and Field its name, or its JSON pointer within the body.
+type OpenAPIViolation struct {
+	In      string `json:"in"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+type openAPIConfig struct {
+	authenticate  openapi3filter.AuthenticationFunc
+	multiError    bool
+	rejectUnknown bool
+}
+
+// OpenAPIOption configures the OpenAPIValidator middleware.
+type OpenAPIOption func(*openAPIConfig)
+
+// WithOpenAPIAuthentication sets the function validating the security
+// requirements of operations. By default security requirements are not
+// validated, as authentication is left to other middlewares like APIKeyAuth.
+// Requests failing them are answered with HTTP 401.
+func WithOpenAPIAuthentication(fn openapi3filter.AuthenticationFunc) OpenAPIOption {
+	return func(cfg *openAPIConfig) {
+		cfg.authenticate = fn
+	}
+}
+
+// WithOpenAPIAllErrors reports every violation of a request instead of only
+// the first one found.
+func WithOpenAPIAllErrors() OpenAPIOption {
+	return func(cfg *openAPIConfig) {
+		cfg.multiError = true
+	}
+}
+
+// WithOpenAPIRejectUndocumented answers requests whose path or method are not
+// documented with HTTP 404 and 405 respectively, instead of handing them over
+// to the router.
+func WithOpenAPIRejectUndocumented() OpenAPIOption {
+	return func(cfg *openAPIConfig) {
+		cfg.rejectUnknown = true
+	}
+}
+
+// OpenAPIValidator produces a Middleware that validates requests against the
+// operations of the given OpenAPI 3 document, in JSON or YAML: their path,
+// query, header and cookie parameters, their content type and their body.
+// Invalid requests are answered with HTTP 400 and an "invalid_request" error
+// whose "violations" detail lists OpenAPIViolation values, and recorded in the
+// toolkit.http.server.openapi.rejected metric.
+//
+//	//go:embed openapi.yaml
+//	var spec []byte
+//
+//	router.Use(web.OpenAPIValidator(spec))
+//
+// Operations are matched by path, ignoring the host of the document servers
+// but not their base path. Requests to undocumented operations are handed over
+// to the router, unless WithOpenAPIRejectUndocumented is given.
+//
+// This function will panic if the document can't be loaded or is invalid.
+func OpenAPIValidator(spec []byte, opts ...OpenAPIOption) Middleware {
+	cfg := openAPIConfig{authenticate: openapi3filter.NoopAuthenticationFunc}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromData(spec)
+	if err != nil {
+		panic(fmt.Sprintf("web: loading OpenAPI document: %v", err))
+	}
+	if err := doc.Validate(loader.Context); err != nil {
+		panic(fmt.Sprintf("web: invalid OpenAPI document: %v", err))
+	}
+
+	// Servers are matched against the request host, which is rarely the one
+	// in the document, so only their paths are kept.
+	for _, server := range doc.Servers {
+		if u, err := url.Parse(server.URL); err == nil && u.Host != "" {
+			server.URL = u.Path
+		}
+	}
+
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		panic(fmt.Sprintf("web: routing OpenAPI document: %v", err))
+	}
+
+	options := &openapi3filter.Options{
+		AuthenticationFunc:  cfg.authenticate,
+		MultiError:          cfg.multiError,
+		SkipSettingDefaults: true,
+	}
+
+	return func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			route, pathParams, err := router.FindRoute(r)
+			if err != nil {
+				if cfg.rejectUnknown {
+					status := http.StatusNotFound
+					if errors.Is(err, routers.ErrMethodNotAllowed) {
+						status = http.StatusMethodNotAllowed
+					}
+					_ = EncodeJSON(w, NewErrorf(status, "%s %s is not documented", r.Method, r.URL.Path), status)
+					return
+				}
+
+				handler(w, r)
+				return
+			}
+
+			err = openapi3filter.ValidateRequest(r.Context(), &openapi3filter.RequestValidationInput{
+				Request:    r,
+				PathParams: pathParams,
+				Route:      route,
+				Options:    options,
+			})
+			if err == nil {
+				handler(w, r)
+				return
+			}
+
+			recordOpenAPIRejection(r)
+
+			status := http.StatusBadRequest
+			var securityErr *openapi3filter.SecurityRequirementsError
+			if errors.As(err, &securityErr) {
+				status = http.StatusUnauthorized
+			}
+
+			webErr := WrapError(status, err, "request does not conform to the API specification").
+				WithCode("invalid_request").
+				WithDetail("violations", openAPIViolations(err))
+			_ = EncodeJSON(w, webErr, status)
+		}
+	}
+}
+
+func recordOpenAPIRejection(r *http.Request) {
+	var routePattern string
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		routePattern = rctx.RoutePattern()
+	}
+
+	tags := []string{
+		"method:" + r.Method,
+		"handler:" + telemetry.SanitizeMetricTagValue(routePattern),
+	}
+	telemetry.Incr(r.Context(), "toolkit.http.server.openapi.rejected", tags)
+}
+
+// openAPIViolations flattens the errors returned by request validation.
+func openAPIViolations(err error) []OpenAPIViolation {
+	switch e := err.(type) {
+	case openapi3.MultiError:
+		var violations []OpenAPIViolation
+		for _, err := range e {
+			violations = append(violations, openAPIViolations(err)...)
+		}
+		return violations
+
+	case *openapi3filter.SecurityRequirementsError:
+		return []OpenAPIViolation{{In: "security", Message: "security requirements failed"}}
+
+	case *openapi3filter.RequestError:
+		v := OpenAPIViolation{In: "request"}
+		switch {
+		case e.Parameter != nil:
+			v.In, v.Field = e.Parameter.In, e.Parameter.Name
+		case e.RequestBody != nil:
+			v.In = "body"
+		}
+
+		// The cause may hold many schema errors, one per invalid field.
+		var violations []OpenAPIViolation
+		for _, cause := range flattenOpenAPIErrors(e.Err) {
+			violations = append(violations, openAPIViolation(v, cause, e.Reason))
+		}
+		return violations
+
+	default:
+		return []OpenAPIViolation{openAPIViolation(OpenAPIViolation{In: "request"}, err, "")}
+	}
+}
+
+func flattenOpenAPIErrors(err error) []error {
+	multi, ok := err.(openapi3.MultiError)
+	if !ok {
+		return []error{err}
+	}
+
+	var errs []error
+	for _, e := range multi {
+		errs = append(errs, flattenOpenAPIErrors(e)...)
+	}
+	return errs
+}
+
+// openAPIViolation completes v with the field and message of the given cause.
+// Messages never include the offending value, unlike the Error method of
+// schema errors.
+func openAPIViolation(v OpenAPIViolation, cause error, reason string) OpenAPIViolation {
+	var schemaErr *openapi3.SchemaError
+	var parseErr *openapi3filter.ParseError
+
+	switch {
+	case errors.As(cause, &schemaErr):
+		v.Message = schemaErr.Reason
+		if pointer := schemaErr.JSONPointer(); v.In == "body" && len(pointer) > 0 {
+			v.Field = "/" + strings.Join(pointer, "/")
+		}
+	case errors.As(cause, &parseErr):
+		v.Message = parseErr.Reason
+	case reason != "":
+		v.Message = reason
+	case cause != nil:
+		v.Message = cause.Error()
+	default:
+		v.Message = "invalid value"
+	}
+
+	return v
+}
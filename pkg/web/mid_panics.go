@@ -1,18 +1,87 @@
 package web
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/luizaranda/go-core/pkg/log"
 	"github.com/luizaranda/go-core/pkg/telemetry"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// DefaultPanicStackDepth is the maximum number of stack frames logged for a panic.
+const DefaultPanicStackDepth = 32
+
+// PanicHandler answers a request whose handler panicked with the recovered
+// error. The default one responds with a status code 500.
+type PanicHandler func(w http.ResponseWriter, r *http.Request, err error)
+
+type panicsConfig struct {
+	handler        PanicHandler
+	stackDepth     int
+	sanitizeStack  func(frame string) string
+	repanicOnAbort bool
+}
+
+// PanicsOption configures the Panics middleware.
+type PanicsOption func(*panicsConfig)
+
+// WithPanicHandler sets the handler answering requests whose handler panicked.
+func WithPanicHandler(h PanicHandler) PanicsOption {
+	return func(c *panicsConfig) {
+		c.handler = h
+	}
+}
+
+// WithPanicStackDepth sets the maximum number of stack frames logged for a
+// panic, DefaultPanicStackDepth by default. Zero disables logging the stack.
+func WithPanicStackDepth(depth int) PanicsOption {
+	return func(c *panicsConfig) {
+		c.stackDepth = depth
+	}
+}
+
+// WithPanicStackSanitizer sets a function which rewrites every logged stack
+// frame, formatted as "function file:line", in order to remove sensitive or
+// noisy information such as build paths. Frames rewritten to an empty string
+// are dropped.
+func WithPanicStackSanitizer(fn func(frame string) string) PanicsOption {
+	return func(c *panicsConfig) {
+		c.sanitizeStack = fn
+	}
+}
+
+// RepanicOnAbort makes the middleware panic again with http.ErrAbortHandler
+// when recovering it, so that the http.Server aborts the response as intended
+// by handlers panicking with it, instead of answering it.
+func RepanicOnAbort() PanicsOption {
+	return func(c *panicsConfig) {
+		c.repanicOnAbort = true
+	}
+}
+
 // Panics handles any panic that may occur by notifying the error to an external system such as DataDOG or NewRelic
 // and responding to the client with a status code 500.
 // For this middleware to log, it requires the context to have a log.Logger.
-func Panics() Middleware {
+//
+// Panics are logged along with their stack trace, recorded on the request span, and counted per route in the
+// toolkit.http.server.panic_recovered metric. The response, stack trace and handling of http.ErrAbortHandler can be
+// configured with opts.
+func Panics(opts ...PanicsOption) Middleware {
+	cfg := panicsConfig{
+		handler:    func(w http.ResponseWriter, _ *http.Request, _ error) { w.WriteHeader(http.StatusInternalServerError) },
+		stackDepth: DefaultPanicStackDepth,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
 	return func(handler http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
 			defer func() {
@@ -22,7 +91,15 @@ func Panics() Middleware {
 						err = fmt.Errorf("%v", rvr)
 					}
 
-					log.Error(r.Context(), "panic recover", log.Err(err))
+					if cfg.repanicOnAbort && errors.Is(err, http.ErrAbortHandler) {
+						panic(rvr)
+					}
+
+					fields := []log.Field{log.Err(err)}
+					if cfg.stackDepth > 0 {
+						fields = append(fields, log.Strings("stack", panicStack(cfg.stackDepth, cfg.sanitizeStack)))
+					}
+					log.Error(r.Context(), "panic recover", fields...)
 
 					routePattern := chi.RouteContext(r.Context()).RoutePattern()
 					tags := []string{
@@ -31,8 +108,12 @@ func Panics() Middleware {
 					}
 					telemetry.Incr(r.Context(), "toolkit.http.server.panic_recovered", tags)
 
+					span := trace.SpanFromContext(r.Context())
+					span.RecordError(err)
+					span.SetStatus(codes.Error, "panic recovered")
+
 					notifyErr(r.Context(), err)
-					w.WriteHeader(http.StatusInternalServerError)
+					cfg.handler(w, r, err)
 				}
 			}()
 
@@ -40,3 +121,32 @@ func Panics() Middleware {
 		}
 	}
 }
+
+// panicStack returns up to depth frames of the stack of the panicking
+// goroutine, skipping the runtime and this package frames.
+func panicStack(depth int, sanitize func(string) string) []string {
+	pcs := make([]uintptr, depth)
+	// Skip runtime.Callers, panicStack and the deferred function.
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	stack := make([]string, 0, n)
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "runtime.") {
+			s := frame.Function + " " + frame.File + ":" + strconv.Itoa(frame.Line)
+			if sanitize != nil {
+				s = sanitize(s)
+			}
+			if s != "" {
+				stack = append(stack, s)
+			}
+		}
+
+		if !more {
+			break
+		}
+	}
+
+	return stack
+}
@@ -0,0 +1,215 @@
+package web
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/luizaranda/go-core/pkg/log"
+	"github.com/luizaranda/go-core/pkg/telemetry"
+)
+
+const _quotaStoreKeyPrefix = "web-quota:"
+
+// QuotaStore keeps the request counters used by the Quota middleware. The
+// in-memory NewMemoryQuotaStore implementation counts the requests of each
+// instance of the application on its own, while a distributed implementation,
+// backed by Redis for instance, allows sharing the quotas across instances.
+type QuotaStore interface {
+	// Increment adds one to the counter identified by key, which expires at
+	// expiration, returning its new value. Missing or expired counters start
+	// from zero.
+	Increment(ctx context.Context, key string, expiration time.Time) (int64, error)
+}
+
+// QuotaLimit is the number of requests allowed per window, such as 1000 per
+// hour. Windows are aligned to multiples of their duration since the Unix
+// epoch, so daily ones reset at midnight UTC.
+type QuotaLimit struct {
+	Limit  int64
+	Window time.Duration
+}
+
+// QuotaConfig configures the Quota middleware.
+type QuotaConfig struct {
+	// Limits are the quotas enforced on every client, such as an hourly and a
+	// daily one.
+	Limits []QuotaLimit
+
+	// KeyFunc identifies the client of requests, such as RateLimitByHeader
+	// with the API key header. Requests with an empty key are not limited.
+	KeyFunc RateLimitKeyFunc
+
+	// Store keeps the request counters. By default an in-memory store is used.
+	Store QuotaStore
+}
+
+// Quota produces a Middleware that enforces request quotas per client, such as
+// an API key or principal, as configured by cfg. Every request counts towards
+// the quotas of its client, including the rejected ones.
+//
+// Responses have the X-RateLimit-Limit, X-RateLimit-Remaining and
+// X-RateLimit-Reset headers set, the latter in seconds, for the quota with the
+// fewest remaining requests. Requests exceeding any quota are answered with
+// HTTP 429 and a Retry-After header, and recorded in the
+// toolkit.http.server.quota.rejected metric, while the rest are recorded in
+// the toolkit.http.server.quota.consumed one, both tagged by window. If the
+// store fails, requests are allowed and the error is logged.
+//
+//	api.Use(web.Quota(web.QuotaConfig{
+//		Limits: []web.QuotaLimit{
+//			{Limit: 1000, Window: time.Hour},
+//			{Limit: 10000, Window: 24 * time.Hour},
+//		},
+//		KeyFunc: web.RateLimitByHeader("X-Api-Key"),
+//	}))
+//
+// This function will panic if cfg has no limits or KeyFunc, or any limit or
+// window is not positive.
+func Quota(cfg QuotaConfig) Middleware {
+	if len(cfg.Limits) == 0 || cfg.KeyFunc == nil {
+		panic("web: quota limits and key func are required")
+	}
+
+	for _, l := range cfg.Limits {
+		if l.Limit <= 0 || l.Window <= 0 {
+			panic("web: quota limit and window must be positive")
+		}
+	}
+
+	store := cfg.Store
+	if store == nil {
+		store = NewMemoryQuotaStore()
+	}
+
+	return func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			key := cfg.KeyFunc(r)
+			if key == "" {
+				handler(w, r)
+				return
+			}
+
+			now := time.Now()
+			routePattern := chi.RouteContext(r.Context()).RoutePattern()
+
+			var (
+				header    QuotaLimit
+				remaining int64 = math.MaxInt64
+				reset     time.Duration
+				exceeded  []QuotaLimit
+				retry     time.Duration
+			)
+
+			for _, l := range cfg.Limits {
+				// time.Time.Truncate aligns to the zero time rather than the
+				// Unix epoch.
+				elapsed := now.UnixNano() % int64(l.Window)
+				start := now.Add(-time.Duration(elapsed))
+				end := start.Add(l.Window)
+
+				counterKey := _quotaStoreKeyPrefix + l.Window.String() + ":" + strconv.FormatInt(start.Unix(), 10) + ":" + key
+				count, err := store.Increment(r.Context(), counterKey, end)
+				if err != nil {
+					log.Warn(r.Context(), "quota store failed, allowing request", log.Err(err))
+					continue
+				}
+
+				// On ties, the quota taking longer to reset is the binding one.
+				left := max(l.Limit-count, 0)
+				if left < remaining || (left == remaining && end.Sub(now) > reset) {
+					header, remaining, reset = l, left, end.Sub(now)
+				}
+
+				if count > l.Limit {
+					exceeded = append(exceeded, l)
+					retry = max(retry, end.Sub(now))
+				}
+			}
+
+			if header.Limit > 0 {
+				w.Header().Set("X-RateLimit-Limit", strconv.FormatInt(header.Limit, 10))
+				w.Header().Set("X-RateLimit-Remaining", strconv.FormatInt(remaining, 10))
+				w.Header().Set("X-RateLimit-Reset", strconv.Itoa(int(math.Ceil(reset.Seconds()))))
+			}
+
+			if len(exceeded) == 0 {
+				for _, l := range cfg.Limits {
+					telemetry.Incr(r.Context(), "toolkit.http.server.quota.consumed", quotaTags(r.Method, routePattern, l))
+				}
+				handler(w, r)
+				return
+			}
+
+			for _, l := range exceeded {
+				telemetry.Incr(r.Context(), "toolkit.http.server.quota.rejected", quotaTags(r.Method, routePattern, l))
+			}
+
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+			_ = EncodeJSON(w, NewErrorf(http.StatusTooManyRequests, "quota exceeded"), http.StatusTooManyRequests)
+		}
+	}
+}
+
+func quotaTags(method, routePattern string, l QuotaLimit) []string {
+	return []string{
+		"method:" + method,
+		"handler:" + telemetry.SanitizeMetricTagValue(routePattern),
+		"window:" + l.Window.String(),
+	}
+}
+
+// _quotaSweepInterval is how often the in-memory store drops the expired
+// counters.
+const _quotaSweepInterval = time.Minute
+
+// MemoryQuotaStore is an in-memory QuotaStore. It is safe to use concurrently
+// by multiple goroutines.
+type MemoryQuotaStore struct {
+	mu        sync.Mutex // guards the fields below
+	counters  map[string]*quotaCounter
+	lastSweep time.Time
+}
+
+type quotaCounter struct {
+	count      int64
+	expiration time.Time
+}
+
+// NewMemoryQuotaStore returns a new in-memory QuotaStore.
+func NewMemoryQuotaStore() *MemoryQuotaStore {
+	return &MemoryQuotaStore{
+		counters:  make(map[string]*quotaCounter),
+		lastSweep: time.Now(),
+	}
+}
+
+// Increment implements QuotaStore.
+func (s *MemoryQuotaStore) Increment(_ context.Context, key string, expiration time.Time) (int64, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) > _quotaSweepInterval {
+		for k, c := range s.counters {
+			if !now.Before(c.expiration) {
+				delete(s.counters, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	c, ok := s.counters[key]
+	if !ok || !now.Before(c.expiration) {
+		c = &quotaCounter{expiration: expiration}
+		s.counters[key] = c
+	}
+
+	c.count++
+	return c.count, nil
+}
@@ -0,0 +1,201 @@
+package web
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/luizaranda/go-core/pkg/log"
+	"github.com/luizaranda/go-core/pkg/telemetry"
+)
+
+// RateLimitStore keeps the token buckets used by the RateLimit middleware. The
+// in-memory NewMemoryRateLimitStore implementation limits each instance of the
+// application on its own, while a distributed implementation, backed by Redis
+// for instance, allows sharing the limits across instances.
+type RateLimitStore interface {
+	// Take takes a token from the bucket identified by key, which holds up to
+	// burst tokens and is refilled at rate tokens per second. If the bucket is
+	// empty it returns false and the time until a token is available.
+	Take(ctx context.Context, key string, rate float64, burst int) (bool, time.Duration, error)
+}
+
+// RateLimitKeyFunc returns the key of the bucket a request takes its token
+// from. Requests with an empty key are not limited.
+type RateLimitKeyFunc func(r *http.Request) string
+
+// RateLimitByIP limits requests per client IP, taken from the request
+// RemoteAddr. Applications behind proxies should set RemoteAddr from the
+// forwarding headers, with chi's RealIP middleware for instance.
+func RateLimitByIP() RateLimitKeyFunc {
+	return func(r *http.Request) string {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			return r.RemoteAddr
+		}
+		return host
+	}
+}
+
+// RateLimitByHeader limits requests per value of the given header, such as an
+// API key. Requests without the header are not limited.
+func RateLimitByHeader(name string) RateLimitKeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// RateLimitByRoute limits requests per route pattern and method, so that every
+// route has its own bucket shared by all clients.
+func RateLimitByRoute() RateLimitKeyFunc {
+	return func(r *http.Request) string {
+		return r.Method + " " + chi.RouteContext(r.Context()).RoutePattern()
+	}
+}
+
+// RateLimitConfig configures the RateLimit middleware.
+type RateLimitConfig struct {
+	// Rate is the number of requests per second allowed for each key.
+	Rate float64
+
+	// Burst is the number of requests allowed at once for each key.
+	Burst int
+
+	// KeyFuncs are the functions computing the bucket key of requests. Keys
+	// are combined, so RateLimitByRoute and RateLimitByIP limit each client
+	// per route. By default requests are limited by RateLimitByIP.
+	KeyFuncs []RateLimitKeyFunc
+
+	// Store keeps the token buckets. By default an in-memory store is used.
+	Store RateLimitStore
+}
+
+// RateLimit produces a Middleware that limits requests with token buckets, as
+// configured by cfg. Requests exceeding the limit are answered with HTTP 429
+// and a Retry-After header, and recorded in the
+// toolkit.http.server.rate_limit.rejected metric. If the store fails, requests
+// are allowed and the error is logged.
+//
+// This function will panic if cfg.Rate or cfg.Burst are not positive.
+func RateLimit(cfg RateLimitConfig) Middleware {
+	if cfg.Rate <= 0 || cfg.Burst <= 0 {
+		panic("web: rate limit rate and burst must be positive")
+	}
+
+	keyFuncs := cfg.KeyFuncs
+	if len(keyFuncs) == 0 {
+		keyFuncs = []RateLimitKeyFunc{RateLimitByIP()}
+	}
+
+	store := cfg.Store
+	if store == nil {
+		store = NewMemoryRateLimitStore()
+	}
+
+	return func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			var key string
+			for _, fn := range keyFuncs {
+				k := fn(r)
+				if k == "" {
+					handler(w, r)
+					return
+				}
+				key += k + "|"
+			}
+
+			allowed, retryAfter, err := store.Take(r.Context(), key, cfg.Rate, cfg.Burst)
+			if err != nil {
+				log.Warn(r.Context(), "rate limit store failed, allowing request", log.Err(err))
+				allowed = true
+			}
+
+			if allowed {
+				handler(w, r)
+				return
+			}
+
+			routePattern := chi.RouteContext(r.Context()).RoutePattern()
+			tags := []string{
+				"method:" + r.Method,
+				"handler:" + telemetry.SanitizeMetricTagValue(routePattern),
+			}
+			telemetry.Incr(r.Context(), "toolkit.http.server.rate_limit.rejected", tags)
+
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			_ = EncodeJSON(w, NewErrorf(http.StatusTooManyRequests, "rate limit exceeded"), http.StatusTooManyRequests)
+		}
+	}
+}
+
+// _rateLimitSweepInterval is how often the in-memory store drops the buckets
+// that were refilled, which are the same as missing ones.
+const _rateLimitSweepInterval = time.Minute
+
+// MemoryRateLimitStore is an in-memory RateLimitStore. It is safe to use
+// concurrently by multiple goroutines.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex // guards the fields below
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	rate   float64
+	burst  int
+}
+
+// NewMemoryRateLimitStore returns a new in-memory RateLimitStore.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets:   make(map[string]*tokenBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Take implements RateLimitStore.
+func (s *MemoryRateLimitStore) Take(_ context.Context, key string, rate float64, burst int) (bool, time.Duration, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) > _rateLimitSweepInterval {
+		for k, b := range s.buckets {
+			if b.refill(now) >= float64(b.burst) {
+				delete(s.buckets, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(burst), last: now}
+		s.buckets[key] = b
+	}
+	b.rate, b.burst = rate, burst
+
+	if b.refill(now) < 1 {
+		wait := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+		return false, wait, nil
+	}
+
+	b.tokens--
+	return true, 0, nil
+}
+
+// refill adds the tokens accumulated since the last refill, returning the
+// number of available tokens.
+func (b *tokenBucket) refill(now time.Time) float64 {
+	b.tokens = math.Min(float64(b.burst), b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	return b.tokens
+}
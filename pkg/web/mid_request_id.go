@@ -0,0 +1,47 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/luizaranda/go-core/pkg/log"
+	"github.com/luizaranda/go-core/pkg/telemetry/tracing"
+)
+
+// loggedRequestIDKey marks the contexts whose logger has the request_id field
+// already, added by Logger.
+type loggedRequestIDKey struct{}
+
+// RequestID makes every request have a request id, taken from its
+// x-request-id header, or the one already in the request context, such as the
+// one generated by HeaderForwarder, or generated if missing, which is:
+//   - stored in the request context, accessible with tracing.RequestID, and
+//     forwarded in outgoing requests by httpclient.ForwardTracingHeadersRequestHook.
+//   - added to the x-request-id header of the response.
+//   - added as the request_id field of every log written with the request context.
+//
+// Generated ids are also set in the request x-request-id header, so that the
+// HeaderForwarder and Logger middlewares chained after this one use them as well.
+func RequestID() Middleware {
+	return func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			reqID := r.Header.Get(_requestIDHeader)
+			if reqID == "" {
+				reqID = tracing.RequestID(r.Context())
+			}
+
+			ctx := tracing.WithRequestID(r.Context(), reqID)
+			reqID = tracing.RequestID(ctx)
+
+			r.Header.Set(_requestIDHeader, reqID)
+			w.Header().Set(_requestIDHeader, reqID)
+
+			// Logger already adds the request_id field for requests with the
+			// header, when chained before this one.
+			if logged, _ := ctx.Value(loggedRequestIDKey{}).(bool); !logged {
+				ctx = log.With(ctx, log.String("request_id", reqID))
+			}
+
+			handler(w, r.WithContext(ctx))
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package web
 
 import (
+	"context"
 	"fmt"
 	"github.com/luizaranda/go-core/pkg/telemetry"
 	"net/http"
@@ -9,6 +10,8 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Telemetry middleware simplifies tracing of incoming web requests by
@@ -16,6 +19,9 @@ import (
 // It also records different metrics such as:
 // - Count of requests per handler by {method,status}
 // - Timing of response per handler by {method,status}.
+//
+// Routes can opt out of it with WithoutTelemetry, or be reported under a
+// different transaction name with WithTransactionName.
 func Telemetry(tracer telemetry.Client) Middleware {
 	return func(handler http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
@@ -35,7 +41,10 @@ func Telemetry(tracer telemetry.Client) Middleware {
 				w = spanWriter
 			}
 
-			r2 := r.WithContext(ctx)
+			// Route middlewares run after this one, so they flag routes
+			// excluded from telemetry through this shared state.
+			state := &routeTelemetry{}
+			r2 := r.WithContext(context.WithValue(ctx, routeTelemetryKey{}, state))
 
 			// Wrap the http.ResponseWriter with a proxy for later response
 			// inspection.
@@ -43,6 +52,9 @@ func Telemetry(tracer telemetry.Client) Middleware {
 
 			start := time.Now()
 			handler(w2, r2)
+			if state.ignored {
+				return
+			}
 			recordRequest(tracer, w2.Status(), time.Since(start), r.Method, routePattern)
 		}
 	}
@@ -65,3 +77,56 @@ func recordRequest(tracer telemetry.Client, status int, delta time.Duration, met
 	tracer.Incr("toolkit.http.server.request", tags)
 	tracer.Timing("toolkit.http.server.request.time", delta, tags)
 }
+
+type routeTelemetryKey struct{}
+
+// routeTelemetry is the telemetry state of a request shared between the
+// Telemetry middleware and route middlewares.
+type routeTelemetry struct {
+	ignored bool
+}
+
+// WithoutTelemetry produces a route Middleware which excludes the requests of
+// the route from telemetry: their NewRelic transaction is ignored and the
+// toolkit.http.server.request metrics are not recorded. It is meant for high
+// volume internal endpoints, such as polling ones, which would otherwise
+// flood APM with noise.
+//
+//	router.Get("/jobs/{id}/status", jobStatus, web.WithoutTelemetry())
+//
+// OpenTelemetry spans can't be discarded once started, so they're still
+// recorded.
+func WithoutTelemetry() Middleware {
+	return func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			if txn := newrelic.FromContext(ctx); txn != nil {
+				txn.Ignore()
+			}
+			if state, ok := ctx.Value(routeTelemetryKey{}).(*routeTelemetry); ok {
+				state.ignored = true
+			}
+
+			handler(w, r)
+		}
+	}
+}
+
+// WithTransactionName produces a route Middleware which renames the NewRelic
+// transaction and the OpenTelemetry span of the requests of the route, which
+// are named after the route pattern and method by default.
+//
+//	router.Get("/{tenant}/feed", feed, web.WithTransactionName("feed (GET)"))
+func WithTransactionName(name string) Middleware {
+	return func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			if txn := newrelic.FromContext(ctx); txn != nil {
+				txn.SetName(name)
+			}
+			trace.SpanFromContext(ctx).SetName(name)
+
+			handler(w, r)
+		}
+	}
+}
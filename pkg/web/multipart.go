@@ -0,0 +1,323 @@
+package web
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultMultipartMaxSize is the size limit of every file bound by
+	// BindMultipart which doesn't set its own with the `maxsize` tag.
+	DefaultMultipartMaxSize = 32 << 20
+
+	// _multipartMaxMemory is how much of a multipart form is kept in memory,
+	// the remaining files being streamed to temporary files.
+	_multipartMaxMemory = 8 << 20
+
+	// _multipartOverhead is the allowance for the form fields and part
+	// headers on top of the size of the files of a multipart body.
+	_multipartOverhead = 1 << 20
+
+	// _sniffLen is the number of bytes http.DetectContentType considers.
+	_sniffLen = 512
+)
+
+var (
+	_uploadedFileType      = reflect.TypeOf((*UploadedFile)(nil))
+	_uploadedFileSliceType = reflect.TypeOf([]*UploadedFile(nil))
+)
+
+// UploadedFile is a file of a multipart form. Its content is kept in memory or
+// in a temporary file, and can be read with Open. Temporary files are removed
+// once the handler returns when it's routed by a Router, and otherwise must be
+// removed with the RemoveAll method of the MultipartForm of the request.
+type UploadedFile struct {
+	*multipart.FileHeader
+
+	// ContentType is the MIME type of the file sniffed from its content with
+	// http.DetectContentType, as the one declared by the client can't be
+	// trusted.
+	ContentType string
+}
+
+// FormFile returns the first file uploaded in the given field of a
+// multipart/form-data request, whose size must not exceed maxSize bytes. If
+// allowedTypes are given, the MIME type sniffed from the file content must be
+// one of them, or match one of them ending in "/*", such as "image/*".
+//
+//	file, err := web.FormFile(r, "avatar", 2<<20, "image/png", "image/jpeg")
+//	if err != nil {
+//		return err
+//	}
+//
+//	f, err := file.Open()
+//
+// The errors returned are *Error: UnsupportedMediaType(415) if the request is
+// not multipart or the file type is not allowed, RequestEntityTooLarge(413) if
+// the file or the request are too large, and BadRequestError(400) if the form
+// is malformed or the file is missing.
+func FormFile(r *http.Request, field string, maxSize int64, allowedTypes ...string) (*UploadedFile, error) {
+	if err := parseMultipart(r, maxSize+_multipartOverhead); err != nil {
+		return nil, err
+	}
+
+	headers := r.MultipartForm.File[field]
+	if len(headers) == 0 {
+		return nil, BadRequestErrorf("missing file %s", field)
+	}
+
+	return newUploadedFile(field, headers[0], maxSize, allowedTypes)
+}
+
+// BindMultipart maps the fields and files of a multipart/form-data request into
+// the fields of the struct pointed by destination, and then validates it as
+// DecodeJSON does.
+//
+// Form fields are mapped as BindQuery maps query parameters, naming them after
+// the `form` tag instead. Files are mapped into *UploadedFile fields, or
+// []*UploadedFile ones to take every file of the field, and checked as FormFile
+// does: their size limit is taken from the `maxsize` tag, in bytes or with a KB,
+// MB or GB suffix, DefaultMultipartMaxSize by default, and their allowed MIME
+// types from the comma separated `accept` tag.
+//
+//	type ProfileForm struct {
+//		Name        string              `form:"name" validate:"required"`
+//		Avatar      *web.UploadedFile   `form:"avatar" maxsize:"2MB" accept:"image/png,image/jpeg"`
+//		Attachments []*web.UploadedFile `form:"attachments" maxsize:"20MB" accept:"application/pdf"`
+//	}
+//
+// The request body is limited to the sum of the size limits of the file fields,
+// so the limit of slice fields applies to all their files together. Missing
+// files leave their fields untouched; use the validate tag to require them. The
+// errors returned are the ones of FormFile.
+func BindMultipart(r *http.Request, destination any) error {
+	v := reflect.ValueOf(destination)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return InternalServerErrorf("invalid multipart binding destination: %T", destination)
+	}
+
+	limit, err := multipartBodyLimit(v.Elem().Type())
+	if err != nil {
+		return err
+	}
+
+	if err := parseMultipart(r, limit); err != nil {
+		return err
+	}
+
+	if err := bindValues(r.MultipartForm.Value, v.Elem(), "form"); err != nil {
+		return err
+	}
+
+	if err := bindFiles(r.MultipartForm.File, v.Elem()); err != nil {
+		return err
+	}
+
+	if err := _validate.StructCtx(r.Context(), destination); err != nil {
+		return handleValidateErr(err)
+	}
+
+	return nil
+}
+
+// parseMultipart parses the multipart form of the request, unless already
+// parsed, limiting the size of its body.
+func parseMultipart(r *http.Request, limit int64) error {
+	if r.MultipartForm != nil {
+		return nil
+	}
+
+	ct, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || ct != "multipart/form-data" {
+		return NewErrorf(http.StatusUnsupportedMediaType, "unsupported media type: %s", r.Header.Get("Content-Type"))
+	}
+
+	r.Body = http.MaxBytesReader(nil, r.Body, limit)
+	if err := r.ParseMultipartForm(_multipartMaxMemory); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return NewErrorf(http.StatusRequestEntityTooLarge, "request body exceeds %d bytes", maxBytesErr.Limit)
+		}
+		return BadRequestErrorf("malformed multipart form: %v", err)
+	}
+
+	return nil
+}
+
+// removeMultipartForm removes the temporary files of the multipart form parsed
+// from r, if any. The server only removes the ones of the requests it creates,
+// not of their copies handed to handlers, such as the ones made by
+// http.Request.WithContext.
+func removeMultipartForm(r *http.Request) {
+	if r.MultipartForm != nil {
+		_ = r.MultipartForm.RemoveAll()
+	}
+}
+
+func newUploadedFile(field string, header *multipart.FileHeader, maxSize int64, allowedTypes []string) (*UploadedFile, error) {
+	if header.Size > maxSize {
+		return nil, NewErrorf(http.StatusRequestEntityTooLarge, "file %s exceeds %d bytes", field, maxSize)
+	}
+
+	f, err := header.Open()
+	if err != nil {
+		return nil, InternalServerErrorf("opening file %s: %v", field, err)
+	}
+	defer f.Close()
+
+	buf := make([]byte, _sniffLen)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, InternalServerErrorf("reading file %s: %v", field, err)
+	}
+
+	contentType := http.DetectContentType(buf[:n])
+	if len(allowedTypes) > 0 && !mediaTypeAllowed(contentType, allowedTypes) {
+		return nil, NewErrorf(http.StatusUnsupportedMediaType, "file %s has unsupported type %s", field, contentType)
+	}
+
+	return &UploadedFile{FileHeader: header, ContentType: contentType}, nil
+}
+
+func mediaTypeAllowed(contentType string, allowed []string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return slices.ContainsFunc(allowed, func(t string) bool {
+		t = strings.TrimSpace(t)
+		if prefix, ok := strings.CutSuffix(t, "/*"); ok {
+			return strings.HasPrefix(mediaType, prefix+"/")
+		}
+		return mediaType == t
+	})
+}
+
+func isUploadedFileType(t reflect.Type) bool {
+	return t == _uploadedFileType || t == _uploadedFileSliceType
+}
+
+// multipartBodyLimit returns the size limit of a multipart body bound into a
+// struct of type t: the sum of the limits of its file fields.
+func multipartBodyLimit(t reflect.Type) (int64, error) {
+	limit := int64(_multipartOverhead)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			n, err := multipartBodyLimit(field.Type)
+			if err != nil {
+				return 0, err
+			}
+			limit += n - _multipartOverhead
+			continue
+		}
+
+		if !isUploadedFileType(field.Type) || field.Tag.Get("form") == "-" {
+			continue
+		}
+
+		maxSize, err := fileMaxSize(field)
+		if err != nil {
+			return 0, err
+		}
+
+		// The number of files of slice fields is unknown, so their limit
+		// applies to all their files together.
+		limit += maxSize
+	}
+
+	return limit, nil
+}
+
+func fileMaxSize(field reflect.StructField) (int64, error) {
+	tag, ok := field.Tag.Lookup("maxsize")
+	if !ok {
+		return DefaultMultipartMaxSize, nil
+	}
+
+	size, err := parseByteSize(tag)
+	if err != nil {
+		return 0, InternalServerErrorf("invalid maxsize tag of field %s: %s", field.Name, tag)
+	}
+	return size, nil
+}
+
+// parseByteSize parses sizes such as "512", "64KB", "2MB" or "1GB".
+func parseByteSize(s string) (int64, error) {
+	multiplier := int64(1)
+	for suffix, m := range map[string]int64{"KB": 1 << 10, "MB": 1 << 20, "GB": 1 << 30} {
+		if n, ok := strings.CutSuffix(strings.ToUpper(s), suffix); ok {
+			s, multiplier = n, m
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil || n <= 0 {
+		return 0, errors.New("invalid byte size")
+	}
+	return n * multiplier, nil
+}
+
+func bindFiles(files map[string][]*multipart.FileHeader, v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := bindFiles(files, v.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name, ok := field.Tag.Lookup("form")
+		if name == "-" || !field.IsExported() || !isUploadedFileType(field.Type) {
+			continue
+		}
+		if !ok || name == "" {
+			name = field.Name
+		}
+
+		headers := files[name]
+		if len(headers) == 0 {
+			continue
+		}
+
+		maxSize, err := fileMaxSize(field)
+		if err != nil {
+			return err
+		}
+
+		var allowedTypes []string
+		if accept := field.Tag.Get("accept"); accept != "" {
+			allowedTypes = strings.Split(accept, ",")
+		}
+
+		uploaded := make([]*UploadedFile, 0, len(headers))
+		for _, header := range headers {
+			file, err := newUploadedFile(name, header, maxSize, allowedTypes)
+			if err != nil {
+				return err
+			}
+			uploaded = append(uploaded, file)
+		}
+
+		if field.Type == _uploadedFileType {
+			v.Field(i).Set(reflect.ValueOf(uploaded[0]))
+		} else {
+			v.Field(i).Set(reflect.ValueOf(uploaded))
+		}
+	}
+
+	return nil
+}
@@ -5,14 +5,16 @@ import (
 	"net/http"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid"
 )
 
 type uriParamsContextKey struct{}
 
 // URIParams contains the key-value combination of parameters from the URI.
-// Deprecated: use web.Param and web.ParamInt instead since this type will be removed in a future release.
+// Deprecated: use web.Param and its typed variants, such as web.ParamInt, instead since this type will be removed in a future release.
 type URIParams map[string]string
 
 // Params returns a map from the given request's context containing every URI parameter defined in the route.
@@ -118,7 +120,7 @@ func Param(r *http.Request, key string) string {
 
 // ParamInt returns the value of the URL parameter with the given key as an int.
 // If the parameter is not found, it returns 0.
-// If the parameter type value is a not an int, it returns an error.
+// If the parameter type value is a not an int, it returns BadRequestError(400).
 func ParamInt(r *http.Request, key string) (int, error) {
 	value := Param(r, key)
 	if value == "" {
@@ -126,7 +128,119 @@ func ParamInt(r *http.Request, key string) (int, error) {
 	}
 
 	intValue, err := strconv.Atoi(value)
-	return intValue, err
+	if err != nil {
+		return 0, paramError(key, "int", value, err)
+	}
+
+	return intValue, nil
+}
+
+// ParamInt64 returns the value of the URL parameter with the given key as an int64.
+// If the parameter is not found, it returns 0.
+// If the parameter type value is a not an int64, it returns BadRequestError(400).
+func ParamInt64(r *http.Request, key string) (int64, error) {
+	value := Param(r, key)
+	if value == "" {
+		return 0, nil
+	}
+
+	intValue, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, paramError(key, "int64", value, err)
+	}
+
+	return intValue, nil
+}
+
+// ParamUint returns the value of the URL parameter with the given key as an uint.
+// If the parameter is not found, it returns 0.
+// If the parameter type value is a not an uint, it returns BadRequestError(400).
+func ParamUint(r *http.Request, key string) (uint, error) {
+	value := Param(r, key)
+	if value == "" {
+		return 0, nil
+	}
+
+	uintValue, err := strconv.ParseUint(value, 10, 0)
+	if err != nil {
+		return 0, paramError(key, "uint", value, err)
+	}
+
+	return uint(uintValue), nil
+}
+
+// ParamFloat returns the value of the URL parameter with the given key as a float64.
+// If the parameter is not found, it returns 0.
+// If the parameter type value is a not a float, it returns BadRequestError(400).
+func ParamFloat(r *http.Request, key string) (float64, error) {
+	value := Param(r, key)
+	if value == "" {
+		return 0, nil
+	}
+
+	floatValue, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, paramError(key, "float", value, err)
+	}
+
+	return floatValue, nil
+}
+
+// ParamBool returns the value of the URL parameter with the given key as a bool,
+// accepting the values strconv.ParseBool does.
+// If the parameter is not found, it returns false.
+// If the parameter type value is a not a bool, it returns BadRequestError(400).
+func ParamBool(r *http.Request, key string) (bool, error) {
+	value := Param(r, key)
+	if value == "" {
+		return false, nil
+	}
+
+	boolValue, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, paramError(key, "bool", value, err)
+	}
+
+	return boolValue, nil
+}
+
+// ParamUUID returns the value of the URL parameter with the given key as an UUID.
+// If the parameter is not found, it returns uuid.Nil.
+// If the parameter type value is a not an UUID, it returns BadRequestError(400).
+func ParamUUID(r *http.Request, key string) (uuid.UUID, error) {
+	value := Param(r, key)
+	if value == "" {
+		return uuid.Nil, nil
+	}
+
+	uuidValue, err := uuid.FromString(value)
+	if err != nil {
+		return uuid.Nil, paramError(key, "uuid", value, err)
+	}
+
+	return uuidValue, nil
+}
+
+// ParamTime returns the value of the URL parameter with the given key as a time.Time
+// parsed with the given layout, such as time.DateOnly.
+// If the parameter is not found, it returns the zero time.
+// If the parameter type value is a not a time in the layout, it returns BadRequestError(400).
+func ParamTime(r *http.Request, key, layout string) (time.Time, error) {
+	value := Param(r, key)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	timeValue, err := time.Parse(layout, value)
+	if err != nil {
+		return time.Time{}, paramError(key, "time", value, err)
+	}
+
+	return timeValue, nil
+}
+
+func paramError(key, kind, value string, err error) error {
+	return WrapError(http.StatusBadRequest, err, "uri param %s is not a valid %s value: %s", key, kind, value)
 }
 
 // WithURLParams adds the given URL parameters to the request context.
@@ -171,3 +285,98 @@ func QueryParamInt(r *http.Request, key string) (int, error) {
 
 	return intValue, nil
 }
+
+// RequiredQuery returns the value of the query parameter with the given key.
+// If the parameter is not found or is empty, it returns BadRequestError(400).
+func RequiredQuery(r *http.Request, key string) (string, error) {
+	value := QueryParam(r, key)
+	if value == "" {
+		return "", BadRequestErrorf("missing query param %s", key)
+	}
+
+	return value, nil
+}
+
+// QueryInt returns the value of the query parameter with the given key as an int.
+// If the parameter is not found, it returns def.
+// If the parameter type value is a not an int, it returns BadRequestError(400).
+func QueryInt(r *http.Request, key string, def int) (int, error) {
+	value := QueryParam(r, key)
+	if value == "" {
+		return def, nil
+	}
+
+	intValue, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, queryError(key, "int", value, err)
+	}
+
+	return intValue, nil
+}
+
+// QueryBool returns the value of the query parameter with the given key as a bool,
+// accepting the values strconv.ParseBool does.
+// If the parameter is not found, it returns def.
+// If the parameter type value is a not a bool, it returns BadRequestError(400).
+func QueryBool(r *http.Request, key string, def bool) (bool, error) {
+	value := QueryParam(r, key)
+	if value == "" {
+		return def, nil
+	}
+
+	boolValue, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, queryError(key, "bool", value, err)
+	}
+
+	return boolValue, nil
+}
+
+// QueryTime returns the value of the query parameter with the given key as a time.Time
+// parsed with the given layout, such as time.RFC3339.
+// If the parameter is not found, it returns def.
+// If the parameter type value is a not a time in the layout, it returns BadRequestError(400).
+func QueryTime(r *http.Request, key, layout string, def time.Time) (time.Time, error) {
+	value := QueryParam(r, key)
+	if value == "" {
+		return def, nil
+	}
+
+	timeValue, err := time.Parse(layout, value)
+	if err != nil {
+		return time.Time{}, queryError(key, "time", value, err)
+	}
+
+	return timeValue, nil
+}
+
+// QueryStrings returns every value of the query parameter with the given key, as
+// in ?status=open&status=closed. It returns nil if the parameter is not found.
+func QueryStrings(r *http.Request, key string) []string {
+	return r.URL.Query()[key]
+}
+
+// QueryInts returns every value of the query parameter with the given key as ints.
+// It returns nil if the parameter is not found.
+// If any of the values is not an int, it returns BadRequestError(400).
+func QueryInts(r *http.Request, key string) ([]int, error) {
+	values := QueryStrings(r, key)
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	ints := make([]int, len(values))
+	for i, value := range values {
+		intValue, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, queryError(key, "int", value, err)
+		}
+		ints[i] = intValue
+	}
+
+	return ints, nil
+}
+
+func queryError(key, kind, value string, err error) error {
+	return WrapError(http.StatusBadRequest, err, "query param %s is not a valid %s value: %s", key, kind, value)
+}
@@ -0,0 +1,116 @@
+package web
+
+import (
+	"encoding/json"
+	"iter"
+	"net/http"
+)
+
+// _streamFlushEvery is the number of elements written between flushes.
+const _streamFlushEvery = 100
+
+// EncodeJSONStream serializes the elements of seq as a JSON array to the
+// ResponseWriter with a 200 status code, encoding them one at a time and
+// flushing the response periodically, instead of holding the whole array in
+// memory. It's meant for endpoints returning large collections, like exports.
+//
+// Since the status code is written before the first element, an error
+// returned once the stream started leaves a truncated array to the client.
+func EncodeJSONStream[T any](w http.ResponseWriter, seq iter.Seq[T]) error {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write([]byte("[")); err != nil {
+		return err
+	}
+
+	rc := http.NewResponseController(w)
+
+	i := 0
+	for v := range seq {
+		if i > 0 {
+			if _, err := w.Write([]byte(",")); err != nil {
+				return err
+			}
+		}
+
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+
+		i++
+		if i%_streamFlushEvery == 0 {
+			_ = rc.Flush()
+		}
+	}
+
+	_, err := w.Write([]byte("]"))
+	return err
+}
+
+// NDJSONWriter writes values as newline delimited JSON, one per line, to a
+// ResponseWriter, flushing the response periodically.
+type NDJSONWriter struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	enc     *json.Encoder
+	written int
+}
+
+// NewNDJSONWriter returns a NDJSONWriter writing to w. The response has the
+// application/x-ndjson content type and a 200 status code, written along with
+// the first value.
+func NewNDJSONWriter(w http.ResponseWriter) *NDJSONWriter {
+	return &NDJSONWriter{
+		w:   w,
+		rc:  http.NewResponseController(w),
+		enc: json.NewEncoder(w),
+	}
+}
+
+// Encode writes v as a JSON line.
+func (n *NDJSONWriter) Encode(v any) error {
+	if n.written == 0 {
+		n.w.Header().Set("Content-Type", "application/x-ndjson")
+		n.w.WriteHeader(http.StatusOK)
+	}
+
+	if err := n.enc.Encode(v); err != nil {
+		return err
+	}
+
+	n.written++
+	if n.written%_streamFlushEvery == 0 {
+		return n.Flush()
+	}
+
+	return nil
+}
+
+// Flush sends the lines written so far to the client.
+func (n *NDJSONWriter) Flush() error {
+	return n.rc.Flush()
+}
+
+// EncodeNDJSONStream serializes the elements of seq as newline delimited JSON
+// to the ResponseWriter. For more information check NDJSONWriter.
+func EncodeNDJSONStream[T any](w http.ResponseWriter, seq iter.Seq[T]) error {
+	n := NewNDJSONWriter(w)
+	for v := range seq {
+		if err := n.Encode(v); err != nil {
+			return err
+		}
+	}
+
+	if n.written == 0 {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+	}
+
+	return nil
+}
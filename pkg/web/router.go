@@ -3,11 +3,18 @@ package web
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"path"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/luizaranda/go-core/pkg/log"
 )
 
 // Handler is a type that handles a http request within our framework.
@@ -88,6 +95,9 @@ type Headerer interface {
 // ErrorHandler receives a transport error to be processed for diagnostic purposes.
 type ErrorHandler func(ctx context.Context, err error)
 
+// DefaultErrorHandler notifies server errors, those whose status code is 5xx, to
+// NewRelic. Server errors which are or wrap an *Error are also logged, along with
+// their code, cause and details.
 func DefaultErrorHandler(ctx context.Context, err error) {
 	code := http.StatusInternalServerError
 	if sc, ok := err.(StatusCoder); ok {
@@ -95,6 +105,11 @@ func DefaultErrorHandler(ctx context.Context, err error) {
 	}
 
 	if code >= 500 && code <= 599 {
+		var webErr *Error
+		if errors.As(err, &webErr) {
+			log.Error(ctx, "request failed", append(webErr.LogFields(), log.Err(err))...)
+		}
+
 		notifyErr(ctx, err)
 	}
 }
@@ -109,18 +124,86 @@ var DefaultNotFoundHandler = func(w http.ResponseWriter, r *http.Request) {
 // handler functions via configurable routes.
 type Router struct {
 	mux        *chi.Mux
+	config     routerConfig
 	mw         []Middleware
 	errEncoder ErrorEncoder
 	errHandler ErrorHandler
+	onRequest  []RequestHook
+	onResponse []ResponseHook
+}
+
+// RequestHook is called before handling every request, returning the context the
+// request is handled with, which is usually ctx itself or derived from it.
+type RequestHook func(ctx context.Context, r *http.Request) context.Context
+
+// ResponseHook is called after handling every request, with the context returned
+// by the request hooks, the response status and the time taken to handle it.
+type ResponseHook func(ctx context.Context, status int, duration time.Duration)
+
+// TrailingSlash defines how a Router handles requests whose path only differs
+// from the one of a route in a trailing slash, such as /users/ for /users.
+type TrailingSlash int
+
+const (
+	// TrailingSlashStrict answers HTTP 404 to such requests, which is the default.
+	TrailingSlashStrict TrailingSlash = iota
+	// TrailingSlashRedirect redirects such requests to the path of the route,
+	// with HTTP 301 for GET and HEAD requests and HTTP 308 for the rest, which
+	// keeps their method and body.
+	TrailingSlashRedirect
+	// TrailingSlashIgnore handles such requests with the route.
+	TrailingSlashIgnore
+)
+
+type routerConfig struct {
+	trailingSlash    TrailingSlash
+	caseInsensitive  bool
+	automaticOptions bool
+}
+
+// RouterOption configures a Router.
+type RouterOption func(*routerConfig)
+
+// WithTrailingSlash sets how requests whose path only differs from the one of a
+// route in a trailing slash are handled. Default is TrailingSlashStrict.
+func WithTrailingSlash(mode TrailingSlash) RouterOption {
+	return func(cfg *routerConfig) {
+		cfg.trailingSlash = mode
+	}
+}
+
+// WithCaseInsensitiveMatching makes requests not matching any route be handled
+// by the one matching their lowercased path, if any, so routes must be
+// registered in lowercase. Path parameters of such requests are taken from the
+// lowercased path.
+func WithCaseInsensitiveMatching() RouterOption {
+	return func(cfg *routerConfig) {
+		cfg.caseInsensitive = true
+	}
+}
+
+// WithAutomaticOptions makes OPTIONS requests to paths with routes, but none for
+// the OPTIONS method, be answered with HTTP 204 and an Allow header listing the
+// methods of those routes.
+func WithAutomaticOptions() RouterOption {
+	return func(cfg *routerConfig) {
+		cfg.automaticOptions = true
+	}
 }
 
 // New instantiates a `Router`.
-func New() *Router {
+func New(opts ...RouterOption) *Router {
+	var cfg routerConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
 	mux := chi.NewRouter()
 	mux.NotFound(DefaultNotFoundHandler)
 
 	return &Router{
 		mux:        mux,
+		config:     cfg,
 		errEncoder: DefaultErrorEncoder,
 		errHandler: DefaultErrorHandler,
 	}
@@ -131,6 +214,22 @@ func (r *Router) Use(middlewares ...Middleware) {
 	r.mw = append(r.mw, middlewares...)
 }
 
+// OnRequest registers hooks which are called, in order, before the middlewares
+// of every route. They are a lighter alternative to middlewares for subsystems
+// which only observe requests or enrich their context, like audit or quotas.
+//
+// Hooks must be registered before the Router starts serving requests, and are
+// not called for requests not matching any route.
+func (r *Router) OnRequest(hooks ...RequestHook) {
+	r.onRequest = append(r.onRequest, hooks...)
+}
+
+// OnResponse registers hooks which are called, in order, after every route
+// handler and its middlewares have returned. See OnRequest.
+func (r *Router) OnResponse(hooks ...ResponseHook) {
+	r.onResponse = append(r.onResponse, hooks...)
+}
+
 // NotFound sets a custom http.HandlerFunc for routing paths that could
 // not be found. The default 404 handler is `http.NotFound`.
 func (r *Router) NotFound(fn http.HandlerFunc) {
@@ -148,12 +247,20 @@ func (r *Router) ErrorHandler(fn ErrorHandler) {
 }
 
 // Group creates a new RouteGroup with the given p prefix and middlewares which are
-// chained after this Router's middlewares.
+// chained after this Router's middlewares. Routes added to the RouteGroup inherit
+// both, so sections of an API like /v1 or /internal share their middleware stack:
+//
+//	v1 := router.Group("/v1", auth)
+//	v1.Get("/users/{id}", getUser)
+//
+//	internal := router.Group("/internal", allowPrivateNetworks)
+//	internal.Use(audit)
+//	internal.Post("/reindex", reindex)
 func (r *Router) Group(p string, mw ...Middleware) *RouteGroup {
 	return &RouteGroup{
 		router: r,
 		path:   p,
-		mw:     mw,
+		mw:     slices.Clone(mw),
 	}
 }
 
@@ -168,8 +275,43 @@ func (r *Router) Any(pattern string, handler Handler, mw ...Middleware) {
 	r.mux.Handle(pattern, r.handle(handler, mw...))
 }
 
+// Mount attaches the handler h to the subtree of routes under prefix, wrapped by
+// this Router's middlewares and `mw`. The prefix is stripped from the path of
+// the requests given to h, so handlers built for their own mux, like embedded
+// admin UIs, can be mounted as they are:
+//
+//	router.Mount("/admin", adminUI)
+//
+// Requests to the subtree are reported by telemetry under the `prefix/*` route.
+func (r *Router) Mount(prefix string, h http.Handler, mw ...Middleware) {
+	prefix = strings.TrimSuffix(prefix, "/")
+
+	stripped := http.StripPrefix(prefix, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "" {
+			req.URL.Path = "/"
+		}
+		h.ServeHTTP(w, req)
+	}))
+
+	mounted := r.handle(func(w http.ResponseWriter, req *http.Request) error {
+		stripped.ServeHTTP(w, req)
+		return nil
+	}, mw...)
+
+	// Requests to the prefix itself match the `prefix` and `prefix/` routes,
+	// which are reported as the rest of the subtree.
+	r.mux.Mount(prefix, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if rctx := chi.RouteContext(req.Context()); rctx != nil && len(rctx.RoutePatterns) > 0 {
+			rctx.RoutePatterns[len(rctx.RoutePatterns)-1] = prefix + "/*"
+		}
+		mounted.ServeHTTP(w, req)
+	}))
+}
+
 func (r *Router) handle(handler Handler, mw ...Middleware) http.Handler {
 	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		defer removeMultipartForm(req)
+
 		err := handler(w, req)
 		if err == nil {
 			return
@@ -184,7 +326,42 @@ func (r *Router) handle(handler Handler, mw ...Middleware) http.Handler {
 	// Add the application's general middleware to the handler chain.
 	h = wrapMiddleware(h, r.mw)
 
-	return h
+	return r.withHooks(h)
+}
+
+// withHooks wraps h with the calls to the request and response hooks.
+func (r *Router) withHooks(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if len(r.onRequest) == 0 && len(r.onResponse) == 0 {
+			h(w, req)
+			return
+		}
+
+		ctx := req.Context()
+		for _, hook := range r.onRequest {
+			ctx = hook(ctx, req)
+		}
+		req = req.WithContext(ctx)
+
+		if len(r.onResponse) == 0 {
+			h(w, req)
+			return
+		}
+
+		ww := middleware.NewWrapResponseWriter(w, req.ProtoMajor)
+		start := time.Now()
+		h(ww, req)
+		duration := time.Since(start)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		for _, hook := range r.onResponse {
+			hook(ctx, status, duration)
+		}
+	}
 }
 
 // Get is a shortcut for r.Method(http.MethodGet, pattern, handle, mw).
@@ -229,9 +406,127 @@ func (r *Router) Trace(pattern string, handler Handler, mw ...Middleware) {
 
 // ServeHTTP conforms to the http.Handler interface.
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if r.config == (routerConfig{}) {
+		r.mux.ServeHTTP(w, req)
+		return
+	}
+
+	routePath := req.URL.RawPath
+	if routePath == "" {
+		routePath = req.URL.Path
+	}
+
+	if req.Method == http.MethodOptions && r.config.automaticOptions {
+		if allowed := r.allowedMethods(routePath); len(allowed) > 0 && !slices.Contains(allowed, http.MethodOptions) {
+			w.Header().Set("Allow", strings.Join(append(allowed, http.MethodOptions), ", "))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+
+	if r.matches(req.Method, routePath) {
+		r.mux.ServeHTTP(w, req)
+		return
+	}
+
+	for _, candidate := range r.candidatePaths(routePath) {
+		if !r.matches(req.Method, candidate.path) {
+			continue
+		}
+
+		if candidate.slashToggled && r.config.trailingSlash == TrailingSlashRedirect {
+			redirectToPath(w, req, candidate.path)
+			return
+		}
+
+		// Routing with the path of the candidate, as chi does with the ones
+		// of mounted routers.
+		rctx := chi.NewRouteContext()
+		rctx.Routes = r.mux
+		rctx.RoutePath = candidate.path
+		r.mux.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx)))
+		return
+	}
+
 	r.mux.ServeHTTP(w, req)
 }
 
+// candidatePath is an alternative path to route a request not matching any route.
+type candidatePath struct {
+	path         string
+	slashToggled bool
+}
+
+// candidatePaths returns the alternative paths to route a request with the given
+// path, according to the router options, in order of preference.
+func (r *Router) candidatePaths(routePath string) []candidatePath {
+	paths := []string{routePath}
+	if r.config.caseInsensitive {
+		if lower := strings.ToLower(routePath); lower != routePath {
+			paths = append(paths, lower)
+		}
+	}
+
+	var candidates []candidatePath
+	for _, p := range paths[1:] {
+		candidates = append(candidates, candidatePath{path: p})
+	}
+
+	if r.config.trailingSlash != TrailingSlashStrict && routePath != "/" {
+		for _, p := range paths {
+			toggled, ok := strings.CutSuffix(p, "/")
+			if !ok {
+				toggled = p + "/"
+			}
+			candidates = append(candidates, candidatePath{path: toggled, slashToggled: true})
+		}
+	}
+
+	return candidates
+}
+
+func (r *Router) matches(method, routePath string) bool {
+	return r.mux.Match(chi.NewRouteContext(), method, routePath)
+}
+
+// allowedMethods returns the methods of the routes matching routePath.
+func (r *Router) allowedMethods(routePath string) []string {
+	var allowed []string
+	for _, method := range []string{
+		http.MethodGet,
+		http.MethodHead,
+		http.MethodPost,
+		http.MethodPut,
+		http.MethodPatch,
+		http.MethodDelete,
+		http.MethodConnect,
+		http.MethodOptions,
+		http.MethodTrace,
+	} {
+		if r.matches(method, routePath) {
+			allowed = append(allowed, method)
+		}
+	}
+	return allowed
+}
+
+func redirectToPath(w http.ResponseWriter, req *http.Request, routePath string) {
+	code := http.StatusPermanentRedirect
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		code = http.StatusMovedPermanently
+	}
+
+	u := *req.URL
+	u.Path, u.RawPath = routePath, ""
+	if req.URL.RawPath != "" {
+		if unescaped, err := url.PathUnescape(routePath); err == nil {
+			u.Path, u.RawPath = unescaped, routePath
+		}
+	}
+
+	http.Redirect(w, req, u.RequestURI(), code)
+}
+
 // Route describes the details of a routing handler.
 type Route struct {
 	Method      string
@@ -284,6 +579,18 @@ func (g *RouteGroup) Any(pattern string, handler Handler, mw ...Middleware) {
 	g.router.Any(path.Join(g.path, pattern), handler, g.appendMiddlewares(mw)...)
 }
 
+// Mount attaches the handler h to the subtree of routes under prefix, relative to
+// the RouteGroup path, as Router.Mount does.
+func (g *RouteGroup) Mount(prefix string, h http.Handler, mw ...Middleware) {
+	g.router.Mount(path.Join(g.path, prefix), h, g.appendMiddlewares(mw)...)
+}
+
+// Use appends a middleware handler to the middleware stack of the RouteGroup. It
+// applies to routes and groups added to the RouteGroup afterwards.
+func (g *RouteGroup) Use(middlewares ...Middleware) {
+	g.mw = append(g.mw[:len(g.mw):len(g.mw)], middlewares...)
+}
+
 func (g *RouteGroup) appendMiddlewares(mw []Middleware) []Middleware {
 	var m []Middleware
 	m = append(m, g.mw...)
@@ -2,6 +2,7 @@ package web
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
 	"net/http"
@@ -52,12 +53,55 @@ type Timeouts struct {
 	// ShutdownTimeout is the maximum duration for the server
 	// to gracefully shutdown.
 	ShutdownTimeout time.Duration
+
+	// DrainDelay is the duration the server keeps serving requests
+	// once asked to stop, before closing its listeners and
+	// shutting down, so that load balancers stop routing requests
+	// to it in the meantime. A zero value means no delay.
+	DrainDelay time.Duration
+}
+
+// ServerOption configures the http.Server used by Run and RunWithContext.
+type ServerOption func(*http.Server)
+
+// WithH2C enables HTTP/2 over cleartext TCP connections, known as h2c, along with
+// HTTP/1. Clients must use HTTP/2 with prior knowledge, as gRPC ones and service
+// mesh proxies do, since upgrades from HTTP/1 are not supported.
+//
+// HTTP/2 connections honor the server Timeouts and are gracefully shut down,
+// letting the streams in flight finish.
+func WithH2C() ServerOption {
+	return func(server *http.Server) {
+		var protocols http.Protocols
+		protocols.SetHTTP1(true)
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+		server.Protocols = &protocols
+	}
+}
+
+// WithTLSConfig makes the server serve HTTPS, with HTTP/2 enabled, using the given
+// TLS configuration, which must provide the server certificates through its
+// Certificates or GetCertificate fields.
+func WithTLSConfig(cfg *tls.Config) ServerOption {
+	return func(server *http.Server) {
+		server.TLSConfig = cfg
+	}
+}
+
+// WithBaseContext sets the function returning the base context of the requests
+// accepted on each listener, which they inherit the values of. See
+// http.Server.BaseContext.
+func WithBaseContext(fn func(net.Listener) context.Context) ServerOption {
+	return func(server *http.Server) {
+		server.BaseContext = fn
+	}
 }
 
 // Run runs the handler h on the given net.Listener using a http.Server configured with the given
-// timeouts.
+// timeouts and options.
 // It blocks until SIGTERM o SIGINT is received by the running process.
-func Run(ln net.Listener, timeouts Timeouts, h http.Handler) error {
+func Run(ln net.Listener, timeouts Timeouts, h http.Handler, opts ...ServerOption) error {
 	server := http.Server{
 		ReadTimeout:       timeouts.ReadTimeout,
 		ReadHeaderTimeout: timeouts.ReadHeaderTimeout,
@@ -65,17 +109,29 @@ func Run(ln net.Listener, timeouts Timeouts, h http.Handler) error {
 		IdleTimeout:       timeouts.IdleTimeout,
 		Handler:           h,
 	}
+	for _, opt := range opts {
+		opt(&server)
+	}
 
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
-	return run(ctx, &server, timeouts.ShutdownTimeout, ln)
+	return run(ctx, &server, timeouts, ln)
 }
 
 // RunWithContext runs the handler h on the given net.Listener using a http.Server configured with the given
-// timeouts.
+// timeouts and options.
 // It blocks until the given context's Done channel is closed.
-func RunWithContext(ctx context.Context, ln net.Listener, timeouts Timeouts, h http.Handler) error {
+func RunWithContext(ctx context.Context, ln net.Listener, timeouts Timeouts, h http.Handler, opts ...ServerOption) error {
+	return RunListeners(ctx, []net.Listener{ln}, timeouts, h, opts...)
+}
+
+// RunListeners runs the handler h on every one of the given listeners, such as a
+// TCP and a Unix socket one, using a single http.Server configured with the
+// given timeouts and options.
+// It blocks until the given context's Done channel is closed, or serving on any
+// of the listeners fails, in which case the server is closed.
+func RunListeners(ctx context.Context, lns []net.Listener, timeouts Timeouts, h http.Handler, opts ...ServerOption) error {
 	server := http.Server{
 		ReadTimeout:       timeouts.ReadTimeout,
 		ReadHeaderTimeout: timeouts.ReadHeaderTimeout,
@@ -83,22 +139,47 @@ func RunWithContext(ctx context.Context, ln net.Listener, timeouts Timeouts, h h
 		IdleTimeout:       timeouts.IdleTimeout,
 		Handler:           h,
 	}
+	for _, opt := range opts {
+		opt(&server)
+	}
 
-	return run(ctx, &server, timeouts.ShutdownTimeout, ln)
+	return run(ctx, &server, timeouts, lns...)
 }
 
-func run(ctx context.Context, server *http.Server, shutdownTimeout time.Duration, ln net.Listener) error {
-	serverErrors := make(chan error, 1)
-	go func() {
-		serverErrors <- server.Serve(ln)
-	}()
+func run(ctx context.Context, server *http.Server, timeouts Timeouts, lns ...net.Listener) error {
+	// Serving sets up a TLSConfig for HTTP/2, so it must be checked beforehand.
+	useTLS := server.TLSConfig != nil
+
+	serverErrors := make(chan error, len(lns))
+	for _, ln := range lns {
+		go func() {
+			if useTLS {
+				// Certificates are taken from the TLSConfig.
+				serverErrors <- server.ServeTLS(ln, "", "")
+				return
+			}
+			serverErrors <- server.Serve(ln)
+		}()
+	}
 
 	select {
 	case err := <-serverErrors:
+		// Stop serving on the remaining listeners.
+		server.Close()
 		return fmt.Errorf("error in serve: %w", err)
 	case <-ctx.Done():
+		// Keep serving while load balancers stop routing requests here.
+		if timeouts.DrainDelay > 0 {
+			select {
+			case <-time.After(timeouts.DrainDelay):
+			case err := <-serverErrors:
+				server.Close()
+				return fmt.Errorf("error in serve: %w", err)
+			}
+		}
+
 		// Give outstanding requests a deadline for completion.
-		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		ctx, cancel := context.WithTimeout(context.Background(), timeouts.ShutdownTimeout)
 		defer cancel()
 
 		if err := server.Shutdown(ctx); err != nil {
@@ -0,0 +1,177 @@
+package web
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/luizaranda/go-core/pkg/telemetry"
+	"golang.org/x/net/websocket"
+)
+
+// WebSocketHandler handles a WebSocket connection upgraded from a request to
+// a route added with Router.WebSocket. The connection is closed once it
+// returns. The given context derives from the one of the upgraded request, and
+// is canceled when the server shuts down.
+type WebSocketHandler func(ctx context.Context, conn *websocket.Conn) error
+
+// WebSocket adds the route `pattern` that upgrades GET requests to WebSocket
+// connections handled by `handler`, wrapped by `mw` as any other route.
+//
+// Requests which are not WebSocket upgrades are answered with HTTP 426, and
+// requests from browsers whose Origin doesn't match the request host are
+// rejected with HTTP 403. Once upgraded, the read and write deadlines set by
+// the http.Server timeouts are cleared, since they are meant for requests and
+// not for long-lived connections, so handlers should set their own deadlines
+// on conn if they need them.
+//
+// Upgraded connections are not tracked by http.Server.Shutdown, so when the
+// server shuts down the context given to handler is canceled and the
+// connection is closed, which makes pending reads and writes fail.
+//
+// Errors returned by handler are sent to the Router ErrorHandler, as they
+// can't be written to the client. The number of open connections is recorded
+// in the toolkit.http.server.websocket.connections gauge, and the duration of
+// every connection in the toolkit.http.server.websocket.connection.time metric.
+func (r *Router) WebSocket(pattern string, handler WebSocketHandler, mw ...Middleware) {
+	var open atomic.Int64
+
+	h := func(w http.ResponseWriter, req *http.Request) error {
+		if !isWebSocketUpgrade(req) {
+			w.Header().Set("Upgrade", "websocket")
+			return NewErrorf(http.StatusUpgradeRequired, "websocket upgrade required")
+		}
+
+		if !sameOrigin(req) {
+			return NewErrorf(http.StatusForbidden, "cross origin websocket request")
+		}
+
+		ctx := req.Context()
+		tags := []string{
+			"handler:" + telemetry.SanitizeMetricTagValue(chi.RouteContext(ctx).RoutePattern()),
+		}
+
+		server := websocket.Server{
+			// Origin was already checked, and non-browser clients don't send it.
+			Handshake: func(*websocket.Config, *http.Request) error { return nil },
+			Handler: func(conn *websocket.Conn) {
+				_ = conn.SetDeadline(time.Time{})
+
+				ctx, cancel := context.WithCancel(ctx)
+				defer cancel()
+				stop := context.AfterFunc(serverShutdownContext(req), func() {
+					cancel()
+					_ = conn.Close()
+				})
+				defer stop()
+
+				telemetry.Gauge(ctx, "toolkit.http.server.websocket.connections", float64(open.Add(1)), tags)
+				start := time.Now()
+				defer func() {
+					telemetry.Gauge(ctx, "toolkit.http.server.websocket.connections", float64(open.Add(-1)), tags)
+					telemetry.Timing(ctx, "toolkit.http.server.websocket.connection.time", time.Since(start), tags)
+				}()
+
+				if err := handler(ctx, conn); err != nil {
+					r.errHandler(ctx, err)
+				}
+			},
+		}
+
+		server.ServeHTTP(hijackWriter{w}, req)
+		return nil
+	}
+
+	r.Get(pattern, h, mw...)
+}
+
+// WebSocket adds the route `pattern` that upgrades requests to WebSocket
+// connections. For more information check the Router.WebSocket method.
+func (g *RouteGroup) WebSocket(pattern string, handler WebSocketHandler, mw ...Middleware) {
+	g.router.WebSocket(path.Join(g.path, pattern), handler, g.appendMiddlewares(mw)...)
+}
+
+// _serverShutdowns holds the contexts canceled when the servers shut down, by
+// *http.Server.
+var _serverShutdowns sync.Map
+
+// serverShutdownContext returns a context canceled when the server which
+// received req shuts down. It's never canceled if req wasn't received by an
+// http.Server.
+func serverShutdownContext(req *http.Request) context.Context {
+	server, ok := req.Context().Value(http.ServerContextKey).(*http.Server)
+	if !ok {
+		return context.Background()
+	}
+
+	if ctx, ok := _serverShutdowns.Load(server); ok {
+		return ctx.(context.Context)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if actual, loaded := _serverShutdowns.LoadOrStore(server, ctx); loaded {
+		cancel()
+		return actual.(context.Context)
+	}
+
+	// The context is kept once canceled, so that the connections upgraded
+	// while shutting down are closed too.
+	server.RegisterOnShutdown(cancel)
+	return ctx
+}
+
+func isWebSocketUpgrade(req *http.Request) bool {
+	return req.Method == http.MethodGet &&
+		strings.EqualFold(req.Header.Get("Upgrade"), "websocket") &&
+		headerContainsToken(req.Header, "Connection", "upgrade")
+}
+
+func headerContainsToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sameOrigin tells whether the Origin header of the request, which is sent by
+// browsers, matches its host. Requests without it are allowed.
+func sameOrigin(req *http.Request) bool {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(u.Host, req.Host)
+}
+
+// hijackWriter makes the http.Hijacker of a ResponseWriter wrapped by
+// middlewares available to the websocket package, which asserts it directly.
+type hijackWriter struct {
+	http.ResponseWriter
+}
+
+func (w hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err != nil {
+		return nil, nil, fmt.Errorf("websocket: %w", err)
+	}
+	return conn, rw, nil
+}